@@ -0,0 +1,173 @@
+//Package cpix imports DASH-IF Content Protection Information Exchange (CPIX) documents, as
+//delivered by DRM key servers, and applies their content keys and DRM signalling to DASH MPDs
+//and HLS playlists.
+//
+//The content key of each track is selected by the usage rules of the document, the DRM
+//signalling of the key (pssh boxes, ContentProtection data and HLS key URIs) is then written
+//to the AdaptationSets of a MPD with ApplyDASH, or to the keys of HLS playlists with ApplyHLS.
+package cpix
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ingest/manifest/drm"
+)
+
+//CPIX is a CPIX document. Only the elements needed to signal content keys are modelled:
+//content keys, DRM system signalling and usage rules.
+type CPIX struct {
+	ID          string        `xml:"id,attr,omitempty"`
+	ContentID   string        `xml:"contentId,attr,omitempty"`
+	ContentKeys []*ContentKey `xml:"ContentKeyList>ContentKey"`
+	DRMSystems  []*DRMSystem  `xml:"DRMSystemList>DRMSystem"`
+	UsageRules  []*UsageRule  `xml:"ContentKeyUsageRuleList>ContentKeyUsageRule"`
+}
+
+//ContentKey is a content key, identified by its KID.
+type ContentKey struct {
+	KID                    string `xml:"kid,attr"`                              //Required. UUID.
+	CommonEncryptionScheme string `xml:"commonEncryptionScheme,attr,omitempty"` //Optional. Possible Values: cenc, cens, cbc1, cbcs.
+	ExplicitIV             string `xml:"explicitIV,attr,omitempty"`             //Optional. Base64 encoded 128-bit IV.
+	PlainValue             string `xml:"Data>Secret>PlainValue,omitempty"`      //Optional. Base64 encoded key.
+	EncryptedValue         string `xml:"Data>Secret>EncryptedValue>CipherData>CipherValue,omitempty"`
+}
+
+//DRMSystem is the signalling of a content key for a DRM system. Every field but KID and
+//SystemID is optional, and base64 encoded.
+type DRMSystem struct {
+	KID                   string              `xml:"kid,attr"`
+	SystemID              string              `xml:"systemId,attr"`
+	PSSH                  string              `xml:"PSSH,omitempty"`                  //pssh box.
+	ContentProtectionData string              `xml:"ContentProtectionData,omitempty"` //Children of the DASH ContentProtection element.
+	URIExtXKey            string              `xml:"URIExtXKey,omitempty"`            //URI of the HLS key.
+	HLSSignalingData      []*HLSSignalingData `xml:"HLSSignalingData,omitempty"`
+}
+
+//HLSSignalingData is a complete HLS key tag of a DRM system.
+type HLSSignalingData struct {
+	Playlist string `xml:"playlist,attr,omitempty"` //Optional. Possible Values: media, master. Default: media.
+	Value    string `xml:",chardata"`               //Base64 encoded #EXT-X-KEY or #EXT-X-SESSION-KEY tag.
+}
+
+//ErrEncryptedKey is returned for content keys encrypted with a document key, which aren't supported.
+var ErrEncryptedKey = errors.New("encrypted content keys aren't supported")
+
+//AES128 is the system ID that signals HLS AES-128 full segment encryption in CPIX documents.
+var AES128 = drm.SystemID{0x3e, 0xa8, 0x77, 0x8f, 0x77, 0x42, 0x4b, 0xf9, 0xb1, 0x8b, 0xe8, 0x34, 0xb2, 0xac, 0xbd, 0x47}
+
+//Parse reads a CPIX document.
+func (c *CPIX) Parse(reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(c); err != nil {
+		return fmt.Errorf("invalid CPIX document: %v", err)
+	}
+	return c.validate()
+}
+
+func (c *CPIX) validate() error {
+	keys := make(map[drm.KID]bool, len(c.ContentKeys))
+	for _, k := range c.ContentKeys {
+		kid, err := k.ID()
+		if err != nil {
+			return err
+		}
+		keys[kid] = true
+	}
+	for _, s := range c.DRMSystems {
+		kid, err := drm.ParseKID(s.KID)
+		if err != nil {
+			return err
+		}
+		if !keys[kid] {
+			return fmt.Errorf("DRMSystem KID %s isn't in the ContentKeyList", kid)
+		}
+		if _, err := drm.ParseSystemID(s.SystemID); err != nil {
+			return err
+		}
+	}
+	for _, r := range c.UsageRules {
+		kid, err := drm.ParseKID(r.KID)
+		if err != nil {
+			return err
+		}
+		if !keys[kid] {
+			return fmt.Errorf("ContentKeyUsageRule KID %s isn't in the ContentKeyList", kid)
+		}
+	}
+	return nil
+}
+
+//ContentKey returns the content key identified by kid, or nil if the document doesn't have it.
+func (c *CPIX) ContentKey(kid drm.KID) *ContentKey {
+	for _, k := range c.ContentKeys {
+		if id, err := k.ID(); err == nil && id == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+//Systems returns the DRM system signalling of the content key identified by kid, in document order.
+func (c *CPIX) Systems(kid drm.KID) []*DRMSystem {
+	var systems []*DRMSystem
+	for _, s := range c.DRMSystems {
+		if id, err := drm.ParseKID(s.KID); err == nil && id == kid {
+			systems = append(systems, s)
+		}
+	}
+	return systems
+}
+
+//ID returns the KID of the content key.
+func (k *ContentKey) ID() (drm.KID, error) {
+	return drm.ParseKID(k.KID)
+}
+
+//Key returns the value of the content key.
+func (k *ContentKey) Key() ([]byte, error) {
+	if k.PlainValue == "" {
+		if k.EncryptedValue != "" {
+			return nil, ErrEncryptedKey
+		}
+		return nil, fmt.Errorf("content key %s has no value", k.KID)
+	}
+	return decodeBase64(k.PlainValue)
+}
+
+//IV returns the explicit IV of the content key, or nil if it doesn't have one.
+func (k *ContentKey) IV() ([]byte, error) {
+	if k.ExplicitIV == "" {
+		return nil, nil
+	}
+	iv, err := decodeBase64(k.ExplicitIV)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != 16 {
+		return nil, fmt.Errorf("explicit IV of content key %s isn't 16 bytes long", k.KID)
+	}
+	return iv, nil
+}
+
+//System returns the ID of the DRM system.
+func (s *DRMSystem) System() (drm.SystemID, error) {
+	return drm.ParseSystemID(s.SystemID)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 value: %v", err)
+	}
+	return b, nil
+}
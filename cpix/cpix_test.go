@@ -0,0 +1,228 @@
+package cpix
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/drm"
+	"github.com/ingest/manifest/hls"
+)
+
+const (
+	sdKID    = "9a4a7b4e-1c8b-4b5e-8a1c-2f0b7a7d5c01"
+	hdKID    = "9a4a7b4e-1c8b-4b5e-8a1c-2f0b7a7d5c02"
+	audioKID = "9a4a7b4e-1c8b-4b5e-8a1c-2f0b7a7d5c03"
+)
+
+func parseTestdata(t *testing.T) *CPIX {
+	f, err := os.Open("./testdata/cpix.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c := &CPIX{}
+	if err := c.Parse(f); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestParse(t *testing.T) {
+	c := parseTestdata(t)
+	if len(c.ContentKeys) != 3 || len(c.DRMSystems) != 6 || len(c.UsageRules) != 3 {
+		t.Fatalf("Expected 3 keys, 6 DRM systems and 3 usage rules, but got %d, %d and %d",
+			len(c.ContentKeys), len(c.DRMSystems), len(c.UsageRules))
+	}
+
+	key := c.ContentKeys[0]
+	if v, err := key.Key(); err != nil || len(v) != 16 {
+		t.Errorf("Expected 16 byte key, but got %x (%v)", v, err)
+	}
+	if iv, err := key.IV(); err != nil || len(iv) != 16 {
+		t.Errorf("Expected 16 byte IV, but got %x (%v)", iv, err)
+	}
+	if key.CommonEncryptionScheme != "cbcs" {
+		t.Errorf("Expected scheme cbcs, but got %s", key.CommonEncryptionScheme)
+	}
+	if s := c.DRMSystems[5]; len(s.HLSSignalingData) != 2 || s.HLSSignalingData[1].Playlist != "master" {
+		t.Errorf("Expected media and master HLSSignalingData, but got %v", s.HLSSignalingData)
+	}
+
+	invalid := `<CPIX><ContentKeyList><ContentKey kid="` + sdKID + `"/></ContentKeyList>
+	<DRMSystemList><DRMSystem kid="` + hdKID + `" systemId="edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"/></DRMSystemList></CPIX>`
+	if err := (&CPIX{}).Parse(strings.NewReader(invalid)); err == nil {
+		t.Error("Expected error for DRMSystem of unknown KID")
+	}
+}
+
+func TestKeyFor(t *testing.T) {
+	c := parseTestdata(t)
+
+	tests := []struct {
+		track Track
+		kid   string
+	}{
+		{Track{Type: Video, Width: 640, Height: 360}, sdKID},
+		{Track{Type: Video, Width: 1024, Height: 576}, sdKID},
+		{Track{Type: Video, Width: 1280, Height: 720}, hdKID},
+		{Track{Type: Audio, Channels: 2}, audioKID},
+		{Track{Type: Text}, ""},
+	}
+	for _, tt := range tests {
+		key, err := c.KeyFor(tt.track)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kid string
+		if key != nil {
+			kid = key.KID
+		}
+		if kid != tt.kid {
+			t.Errorf("Expected key %q for %v, but got %q", tt.kid, tt.track, kid)
+		}
+	}
+
+	//a video track of unknown resolution matches both the SD and HD rules
+	if _, err := c.KeyFor(Track{Type: Video}); err == nil {
+		t.Error("Expected error for track matching the rules of two keys")
+	}
+
+	c.UsageRules[2].KeyPeriodFilters = []*KeyPeriodFilter{{PeriodID: "keyPeriod_1"}}
+	if key, _ := c.KeyFor(Track{Type: Audio}); key != nil {
+		t.Errorf("Expected no key outside the key period, but got %s", key.KID)
+	}
+	if key, _ := c.KeyFor(Track{Type: Audio, KeyPeriod: "keyPeriod_1"}); key == nil || key.KID != audioKID {
+		t.Errorf("Expected key %s in the key period, but got %v", audioKID, key)
+	}
+}
+
+func TestApplyDASH(t *testing.T) {
+	c := parseTestdata(t)
+	m := &dash.MPD{Profiles: "urn:mpeg:dash:profile:isoff-live:2011", Type: "static",
+		MinBufferTime: &dash.CustomDuration{Duration: 2 * time.Second}, MediaPresDuration: &dash.CustomDuration{Duration: time.Minute}}
+	m.Periods = dash.Periods{&dash.Period{AdaptationSets: dash.AdaptationSets{
+		&dash.AdaptationSet{ID: 1, MimeType: "video/mp4", Representations: dash.Representations{
+			&dash.Representation{ID: "360p", Bandwidth: 800000, Width: 640, Height: 360},
+			&dash.Representation{ID: "576p", Bandwidth: 1500000, Width: 1024, Height: 576},
+		}},
+		&dash.AdaptationSet{ID: 2, MimeType: "video/mp4", Representations: dash.Representations{
+			&dash.Representation{ID: "720p", Bandwidth: 3000000, Width: 1280, Height: 720},
+		}},
+		&dash.AdaptationSet{ID: 3, ContentType: "audio", Representations: dash.Representations{
+			&dash.Representation{ID: "aac", Bandwidth: 128000, MimeType: "audio/mp4"},
+		}},
+		&dash.AdaptationSet{ID: 4, ContentType: "text", Representations: dash.Representations{
+			&dash.Representation{ID: "en", Bandwidth: 1000, MimeType: "application/mp4"},
+		}},
+	}}}
+	if err := c.ApplyDASH(m); err != nil {
+		t.Fatal(err)
+	}
+
+	sets := m.Periods[0].AdaptationSets
+	for i, kid := range []string{sdKID, hdKID, audioKID} {
+		cps := sets[i].CENCContentProtections
+		if got, ok := drm.DefaultKID(cps); !ok || got.String() != kid {
+			t.Errorf("Expected default KID %s in AdaptationSet %d, but got %v", kid, sets[i].ID, got)
+		}
+		if cps[0].Value != "cbcs" {
+			t.Errorf("Expected cbcs mp4protection, but got %s", cps[0].Value)
+		}
+	}
+
+	sd := sets[0].CENCContentProtections
+	if len(sd) != 3 || sd[1].Pssh == nil || sd[2].Value != "MSPR 2.0" || sd[2].Pro == nil {
+		t.Errorf("Expected mp4protection, Widevine and PlayReady ContentProtection, but got %v", sd)
+	}
+	if hd := sets[1].CENCContentProtections; len(hd) != 2 {
+		t.Errorf("Expected FairPlay to have no ContentProtection, but got %d elements", len(hd))
+	}
+	audio := sets[2].CENCContentProtections
+	if len(audio) != 2 || audio[1].Pssh == nil || len(audio[1].ExtraElements) != 1 || audio[1].ExtraElements[0].XMLName.Local != "laurl" {
+		t.Errorf("Expected ContentProtection from ContentProtectionData, but got %v", audio)
+	}
+	if len(sets[3].CENCContentProtections) != 0 {
+		t.Error("Expected text AdaptationSet to stay clear")
+	}
+
+	if _, err := m.Encode(); err != nil {
+		t.Errorf("Expected MPD to encode, but got %v", err)
+	}
+
+	mixed := &dash.MPD{Periods: dash.Periods{&dash.Period{AdaptationSets: dash.AdaptationSets{
+		&dash.AdaptationSet{ID: 1, MimeType: "video/mp4", Representations: dash.Representations{
+			&dash.Representation{ID: "360p", Bandwidth: 800000, Width: 640, Height: 360},
+			&dash.Representation{ID: "720p", Bandwidth: 3000000, Width: 1280, Height: 720},
+		}},
+	}}}}
+	if err := c.ApplyDASH(mixed); err == nil {
+		t.Error("Expected error for AdaptationSet using two keys")
+	}
+}
+
+func TestApplyHLS(t *testing.T) {
+	c := parseTestdata(t)
+	p := &hls.MasterPlaylist{
+		M3U:     true,
+		Version: 7,
+		Variants: []*hls.Variant{
+			{URI: "360p.m3u8", Bandwidth: 800000, Resolution: "640x360", Codecs: "avc1.4d401e,mp4a.40.2", Audio: "aac"},
+			{URI: "720p.m3u8", Bandwidth: 3000000, Resolution: "1280x720", Codecs: "avc1.4d401f,mp4a.40.2", Audio: "aac"},
+		},
+		Renditions: []*hls.Rendition{
+			{Type: "AUDIO", GroupID: "aac", Name: "English", URI: "audio.m3u8"},
+		},
+	}
+	media := map[string]*hls.MediaPlaylist{}
+	for _, uri := range []string{"360p.m3u8", "720p.m3u8", "audio.m3u8"} {
+		media[uri] = &hls.MediaPlaylist{Version: 7, TargetDuration: 6, EndList: true, Segments: hls.Segments{
+			&hls.Segment{ID: 0, URI: "0.mp4", Inf: &hls.Inf{Duration: 6}},
+			&hls.Segment{ID: 1, URI: "1.mp4", Inf: &hls.Inf{Duration: 6}},
+		}}
+	}
+	if err := c.ApplyHLS(p, media); err != nil {
+		t.Fatal(err)
+	}
+
+	sd := media["360p.m3u8"].Segments[0].Keys
+	if len(sd) != 3 || sd[2].Keyformat != drm.KeyFormatFairPlay || sd[2].URI != "skd://"+sdKID {
+		t.Fatalf("Expected Widevine, PlayReady and FairPlay keys, but got %v", sd)
+	}
+	for _, k := range sd {
+		if k.Method != "SAMPLE-AES" || k.IV != "0xFDC31676A02F34592DFECEC25EAFD2D8" {
+			t.Errorf("Expected SAMPLE-AES key with explicit IV, but got %v", k)
+		}
+	}
+	if hd := media["720p.m3u8"].Segments[1].Keys; len(hd) != 2 || hd[0].IV != "" {
+		t.Errorf("Expected Widevine and FairPlay keys without IV, but got %v", hd)
+	}
+	audio := media["audio.m3u8"].Segments[0].Keys
+	if len(audio) != 1 || audio[0].IsSession || !strings.HasPrefix(audio[0].URI, "data:text/plain;base64,") {
+		t.Errorf("Expected key from HLSSignalingData, but got %v", audio)
+	}
+	if len(p.SessionKeys) != 6 {
+		t.Errorf("Expected 6 session keys, but got %d", len(p.SessionKeys))
+	}
+
+	r, err := media["360p.m3u8"].Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(r)
+	if n := strings.Count(string(out), "#EXT-X-KEY:"); n != 3 {
+		t.Errorf("Expected keys written once, but got %d #EXT-X-KEY tags:\n%s", n, out)
+	}
+	r, err = p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ = ioutil.ReadAll(r)
+	if !strings.Contains(string(out), `#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI="skd://`+hdKID+`"`) {
+		t.Errorf("Expected FairPlay session key, but got:\n%s", out)
+	}
+}
@@ -0,0 +1,198 @@
+package cpix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/drm"
+)
+
+//contentProtectionData wraps the ContentProtectionData of a DRM system, which holds the children
+//of its ContentProtection element with their cenc and mspr prefixes but not the namespace declarations.
+const contentProtectionData = `<ContentProtection xmlns:cenc="urn:mpeg:cenc:2013" xmlns:mspr="urn:microsoft:playready" schemeIdUri="%s" value="%s">%s</ContentProtection>`
+
+//ApplyDASH sets the ContentProtection elements of every AdaptationSet of m whose Representations
+//match a usage rule: the mp4protection element with the default KID, followed by the element of
+//each DRM system of the key, replacing any ContentProtection elements the AdaptationSet had.
+//All Representations of an AdaptationSet must use the same key. Key period filters aren't
+//matched, as Periods aren't key periods.
+func (c *CPIX) ApplyDASH(m *dash.MPD) error {
+	for _, p := range m.Periods {
+		for _, as := range p.AdaptationSets {
+			key, err := c.adaptationSetKey(as)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				continue
+			}
+			cps, err := c.contentProtections(key)
+			if err != nil {
+				return err
+			}
+			as.CENCContentProtections = cps
+		}
+	}
+	return nil
+}
+
+func (c *CPIX) adaptationSetKey(as *dash.AdaptationSet) (*ContentKey, error) {
+	if len(as.Representations) == 0 {
+		return c.KeyFor(adaptationSetTrack(as))
+	}
+
+	var key *ContentKey
+	for i, r := range as.Representations {
+		k, err := c.KeyFor(representationTrack(as, r))
+		if err != nil {
+			return nil, fmt.Errorf("Representation %s: %v", r.ID, err)
+		}
+		if i > 0 && k != key {
+			return nil, fmt.Errorf("Representations of AdaptationSet %d use different keys", as.ID)
+		}
+		key = k
+	}
+	return key, nil
+}
+
+func (c *CPIX) contentProtections(key *ContentKey) (dash.CENCContentProtections, error) {
+	kid, err := key.ID()
+	if err != nil {
+		return nil, err
+	}
+	scheme := key.CommonEncryptionScheme
+	if scheme == "" {
+		scheme = "cenc"
+	}
+	cps, err := drm.ContentProtections(scheme, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range c.Systems(kid) {
+		cp, err := s.contentProtection()
+		if err != nil {
+			return nil, fmt.Errorf("DRMSystem %s: %v", s.SystemID, err)
+		}
+		if cp != nil {
+			cps = append(cps, cp)
+		}
+	}
+	return cps, nil
+}
+
+//contentProtection returns the ContentProtection element of the DRM system, or nil if the system
+//has no DASH signalling, such as FairPlay.
+func (s *DRMSystem) contentProtection() (*dash.CENCContentProtection, error) {
+	system, err := s.System()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(s.PSSH) == "" && strings.TrimSpace(s.ContentProtectionData) == "" {
+		return nil, nil
+	}
+
+	cp := dash.NewContentProtection(system.URN(), "", "", "", "")
+	if strings.TrimSpace(s.PSSH) != "" {
+		box, err := drm.ParsePSSHBase64(s.PSSH)
+		if err != nil {
+			return nil, err
+		}
+		if box.SystemID != system {
+			return nil, fmt.Errorf("pssh box of system %s doesn't match DRMSystem", box.SystemID)
+		}
+		if cp, err = box.ContentProtection(); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(s.ContentProtectionData) == "" {
+		return cp, nil
+	}
+
+	data, err := decodeBase64(s.ContentProtectionData)
+	if err != nil {
+		return nil, err
+	}
+	cpd := &dash.CENCContentProtection{}
+	if err := xml.Unmarshal([]byte(fmt.Sprintf(contentProtectionData, cp.SchemeIDURI, cp.Value, data)), cpd); err != nil {
+		return nil, fmt.Errorf("invalid ContentProtectionData: %v", err)
+	}
+	return cpd, nil
+}
+
+func adaptationSetTrack(as *dash.AdaptationSet) Track {
+	return Track{
+		Type:      trackType(as.ContentType, as.MimeType),
+		Width:     as.Width,
+		Height:    as.Height,
+		FrameRate: parseFrameRate(as.FrameRate),
+		Channels:  channels(as.AudioChannelConfig),
+	}
+}
+
+//representationTrack returns the track of r, with the properties it inherits from as.
+func representationTrack(as *dash.AdaptationSet, r *dash.Representation) Track {
+	t := adaptationSetTrack(as)
+	if r.MimeType != "" {
+		t.Type = trackType(as.ContentType, r.MimeType)
+	}
+	if r.Width > 0 {
+		t.Width = r.Width
+	}
+	if r.Height > 0 {
+		t.Height = r.Height
+	}
+	if r.FrameRate != "" {
+		t.FrameRate = parseFrameRate(r.FrameRate)
+	}
+	if len(r.AudioChannelConfig) > 0 {
+		t.Channels = channels(r.AudioChannelConfig)
+	}
+	t.Bitrate = r.Bandwidth
+	return t
+}
+
+func trackType(contentType, mimeType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	switch {
+	case strings.HasPrefix(mimeType, "video/"):
+		return Video
+	case strings.HasPrefix(mimeType, "audio/"):
+		return Audio
+	case strings.HasPrefix(mimeType, "text/"), strings.HasPrefix(mimeType, "application/"):
+		return Text
+	}
+	return ""
+}
+
+//parseFrameRate parses a FrameRateType, an int or a fraction such as 30000/1001.
+func parseFrameRate(s string) float64 {
+	parts := strings.SplitN(s, "/", 2)
+	n, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0
+	}
+	if len(parts) == 2 {
+		d, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || d == 0 {
+			return 0
+		}
+		n /= d
+	}
+	return n
+}
+
+//channels returns the channel count of the first AudioChannelConfiguration that has one.
+func channels(configs []*dash.Descriptor) int {
+	for _, c := range configs {
+		if n, err := strconv.Atoi(c.Value); err == nil {
+			return n
+		}
+	}
+	return 0
+}
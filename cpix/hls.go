@@ -0,0 +1,203 @@
+package cpix
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ingest/manifest/drm"
+	"github.com/ingest/manifest/hls"
+)
+
+//videoCodecs are the RFC6381 prefixes of video codecs, which tell video Variants from audio only ones.
+var videoCodecs = []string{"avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "av01", "vp09"}
+
+//ApplyHLS sets the keys of the Media Playlists of every Variant and Rendition of p that matches
+//a usage rule, and adds the session keys of those keys to p. media holds the Media Playlists
+//by their URI in p; Variants and Renditions whose playlist isn't in media only get session keys.
+//The keys of each DRM system come from its HLSSignalingData if it has any, else from its
+//URIExtXKey or pssh box.
+func (c *CPIX) ApplyHLS(p *hls.MasterPlaylist, media map[string]*hls.MediaPlaylist) error {
+	type track struct {
+		uri string
+		Track
+	}
+	var tracks []track
+	for _, v := range p.Variants {
+		tracks = append(tracks, track{v.URI, variantTrack(v)})
+	}
+	for _, r := range p.Renditions {
+		if r.URI != "" {
+			tracks = append(tracks, track{r.URI, renditionTrack(r)})
+		}
+	}
+
+	for _, t := range tracks {
+		key, err := c.KeyFor(t.Track)
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.uri, err)
+		}
+		if key == nil {
+			continue
+		}
+
+		keys, err := c.hlsKeys(key, false)
+		if err != nil {
+			return err
+		}
+		if mp, ok := media[t.uri]; ok {
+			for _, s := range mp.Segments {
+				s.Keys = keys
+			}
+		}
+
+		sessionKeys, err := c.hlsKeys(key, true)
+		if err != nil {
+			return err
+		}
+		for _, k := range sessionKeys {
+			if !hasKey(p.SessionKeys, k) {
+				p.SessionKeys = append(p.SessionKeys, k)
+			}
+		}
+	}
+	return nil
+}
+
+//hlsKeys returns the EXT-X-KEYs of key, or its EXT-X-SESSION-KEYs if session is true, one per DRM system.
+func (c *CPIX) hlsKeys(key *ContentKey, session bool) ([]*hls.Key, error) {
+	kid, err := key.ID()
+	if err != nil {
+		return nil, err
+	}
+	iv, err := key.IV()
+	if err != nil {
+		return nil, err
+	}
+	method := "SAMPLE-AES"
+	if key.CommonEncryptionScheme == "cenc" {
+		method = "SAMPLE-AES-CTR"
+	}
+
+	var keys []*hls.Key
+	for _, s := range c.Systems(kid) {
+		k, err := s.hlsKey(method, session)
+		if err != nil {
+			return nil, fmt.Errorf("DRMSystem %s: %v", s.SystemID, err)
+		}
+		if k == nil {
+			continue
+		}
+		if iv != nil && k.IV == "" {
+			k.IV = "0x" + strings.ToUpper(hex.EncodeToString(iv))
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+//hlsKey returns the key of the DRM system, or nil if the system has no HLS signalling.
+func (s *DRMSystem) hlsKey(method string, session bool) (*hls.Key, error) {
+	system, err := s.System()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.HLSSignalingData) > 0 {
+		for _, d := range s.HLSSignalingData {
+			if (d.Playlist == "master") != session {
+				continue
+			}
+			tag, err := decodeBase64(d.Value)
+			if err != nil {
+				return nil, err
+			}
+			k, err := hls.ParseKey(string(tag))
+			if err != nil {
+				return nil, err
+			}
+			k.IsSession = session
+			return k, nil
+		}
+		return nil, nil
+	}
+
+	var k *hls.Key
+	switch {
+	case strings.TrimSpace(s.URIExtXKey) != "":
+		uri, err := decodeBase64(s.URIExtXKey)
+		if err != nil {
+			return nil, err
+		}
+		switch system {
+		case AES128:
+			k = &hls.Key{Method: "AES-128", URI: string(uri)}
+		case drm.FairPlay:
+			k = drm.FairPlayKey(string(uri))
+		default:
+			k = &hls.Key{Method: method, URI: string(uri), Keyformat: system.KeyFormat(), Keyformatversions: "1"}
+		}
+	case strings.TrimSpace(s.PSSH) != "":
+		box, err := drm.ParsePSSHBase64(s.PSSH)
+		if err != nil {
+			return nil, err
+		}
+		if k, err = box.Key(method); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	k.IsSession = session
+	return k, nil
+}
+
+func variantTrack(v *hls.Variant) Track {
+	t := Track{Bitrate: v.Bandwidth, FrameRate: v.FrameRate}
+	if res := strings.SplitN(v.Resolution, "x", 2); len(res) == 2 {
+		t.Width, _ = strconv.Atoi(res[0])
+		t.Height, _ = strconv.Atoi(res[1])
+	}
+	switch {
+	case v.Resolution != "" || hasVideoCodec(v.Codecs):
+		t.Type = Video
+	case v.Codecs != "":
+		t.Type = Audio
+	}
+	return t
+}
+
+func renditionTrack(r *hls.Rendition) Track {
+	t := Track{Label: r.Name}
+	switch r.Type {
+	case "VIDEO":
+		t.Type = Video
+	case "AUDIO":
+		t.Type = Audio
+	case "SUBTITLES":
+		t.Type = Text
+	}
+	return t
+}
+
+func hasVideoCodec(codecs string) bool {
+	for _, codec := range strings.Split(codecs, ",") {
+		codec = strings.TrimSpace(codec)
+		for _, prefix := range videoCodecs {
+			if strings.HasPrefix(codec, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasKey(keys []*hls.Key, k *hls.Key) bool {
+	for _, key := range keys {
+		if key.Equal(k) {
+			return true
+		}
+	}
+	return false
+}
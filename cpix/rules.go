@@ -0,0 +1,181 @@
+package cpix
+
+import (
+	"fmt"
+
+	"github.com/ingest/manifest/drm"
+)
+
+//Track types matched by the usage rule filters.
+const (
+	Video = "video"
+	Audio = "audio"
+	Text  = "text"
+)
+
+//UsageRule maps the content key KID to the tracks that match its filters. Filters of different
+//kinds must all match, while any of several filters of the same kind may match.
+type UsageRule struct {
+	KID               string             `xml:"kid,attr"`
+	IntendedTrackType string             `xml:"intendedTrackType,attr,omitempty"` //Optional. Label of the tracks, such as SD, HD or AUDIO.
+	VideoFilters      []*VideoFilter     `xml:"VideoFilter,omitempty"`
+	AudioFilters      []*AudioFilter     `xml:"AudioFilter,omitempty"`
+	BitrateFilters    []*BitrateFilter   `xml:"BitrateFilter,omitempty"`
+	LabelFilters      []*LabelFilter     `xml:"LabelFilter,omitempty"`
+	KeyPeriodFilters  []*KeyPeriodFilter `xml:"KeyPeriodFilter,omitempty"`
+}
+
+//VideoFilter matches video tracks. Limits that are zero don't apply.
+type VideoFilter struct {
+	MinPixels int     `xml:"minPixels,attr,omitempty"` //Width times height.
+	MaxPixels int     `xml:"maxPixels,attr,omitempty"`
+	MinFPS    float64 `xml:"minFps,attr,omitempty"`
+	MaxFPS    float64 `xml:"maxFps,attr,omitempty"`
+}
+
+//AudioFilter matches audio tracks. Limits that are zero don't apply.
+type AudioFilter struct {
+	MinChannels int `xml:"minChannels,attr,omitempty"`
+	MaxChannels int `xml:"maxChannels,attr,omitempty"`
+}
+
+//BitrateFilter matches tracks by bitrate, in bits per second. Limits that are zero don't apply.
+type BitrateFilter struct {
+	MinBitrate int64 `xml:"minBitrate,attr,omitempty"`
+	MaxBitrate int64 `xml:"maxBitrate,attr,omitempty"`
+}
+
+//LabelFilter matches tracks by a label agreed with the key server.
+type LabelFilter struct {
+	Label string `xml:"label,attr"`
+}
+
+//KeyPeriodFilter matches tracks in the key period PeriodID, for key rotation.
+type KeyPeriodFilter struct {
+	PeriodID string `xml:"periodId,attr"`
+}
+
+//Track describes a track for matching usage rules. Fields that are zero are unknown, and
+//don't stop a filter from matching.
+type Track struct {
+	Type      string //Possible Values: video, audio, text.
+	Width     int
+	Height    int
+	FrameRate float64
+	Channels  int
+	Bitrate   int64
+	Label     string //HLS Rendition NAME. DASH tracks have no label.
+	KeyPeriod string
+}
+
+//KeyFor returns the content key of the track, selected by the usage rules. It returns nil if
+//no rule matches, which leaves the track in the clear, and an error if rules of different keys match.
+func (c *CPIX) KeyFor(t Track) (*ContentKey, error) {
+	var key *ContentKey
+	for _, r := range c.UsageRules {
+		if !r.matches(t) {
+			continue
+		}
+		kid, err := drm.ParseKID(r.KID)
+		if err != nil {
+			return nil, err
+		}
+		k := c.ContentKey(kid)
+		if k == nil {
+			return nil, fmt.Errorf("ContentKeyUsageRule KID %s isn't in the ContentKeyList", kid)
+		}
+		if key != nil && key != k {
+			return nil, fmt.Errorf("usage rules of keys %s and %s both match %s track", key.KID, k.KID, t.Type)
+		}
+		key = k
+	}
+	return key, nil
+}
+
+func (r *UsageRule) matches(t Track) bool {
+	if len(r.VideoFilters) > 0 {
+		matched := false
+		for _, f := range r.VideoFilters {
+			matched = matched || f.matches(t)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.AudioFilters) > 0 {
+		matched := false
+		for _, f := range r.AudioFilters {
+			matched = matched || f.matches(t)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.BitrateFilters) > 0 {
+		matched := false
+		for _, f := range r.BitrateFilters {
+			matched = matched || f.matches(t)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.LabelFilters) > 0 {
+		matched := false
+		for _, f := range r.LabelFilters {
+			matched = matched || f.Label == t.Label
+		}
+		if !matched {
+			return false
+		}
+	}
+	//keys of a rule with key periods are only used for the tracks of those periods
+	if len(r.KeyPeriodFilters) > 0 {
+		matched := false
+		for _, f := range r.KeyPeriodFilters {
+			matched = matched || f.PeriodID == t.KeyPeriod
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *VideoFilter) matches(t Track) bool {
+	if t.Type != Video {
+		return false
+	}
+	if pixels := t.Width * t.Height; pixels > 0 {
+		if (f.MinPixels > 0 && pixels < f.MinPixels) || (f.MaxPixels > 0 && pixels > f.MaxPixels) {
+			return false
+		}
+	}
+	if t.FrameRate > 0 {
+		if (f.MinFPS > 0 && t.FrameRate < f.MinFPS) || (f.MaxFPS > 0 && t.FrameRate > f.MaxFPS) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *AudioFilter) matches(t Track) bool {
+	if t.Type != Audio {
+		return false
+	}
+	if t.Channels > 0 {
+		if (f.MinChannels > 0 && t.Channels < f.MinChannels) || (f.MaxChannels > 0 && t.Channels > f.MaxChannels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BitrateFilter) matches(t Track) bool {
+	if t.Bitrate > 0 {
+		if (f.MinBitrate > 0 && t.Bitrate < f.MinBitrate) || (f.MaxBitrate > 0 && t.Bitrate > f.MaxBitrate) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package dash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//Clip returns a copy of a static MPD with only the part of the presentation from start to end,
+//relative to the start of the presentation. The Periods containing start and end are split
+//with Split, so the presentationTimeOffset and startNumber of the first Period skip the media
+//before start for a frame-accurate start, and the Periods outside the clip are removed. Period
+//start times are rebased on start. end is capped at the end of the presentation.
+//
+//As with Split, Representations using SegmentTemplate@duration or SegmentList@duration must be
+//clipped on segment boundaries.
+func Clip(m *MPD, start, end time.Duration) (*MPD, error) {
+	if m == nil {
+		return nil, errors.New("MPD must not be nil")
+	}
+	if m.Type == "dynamic" {
+		return nil, errors.New("only static MPDs can be clipped")
+	}
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("clip from %v to %v must start at or after 0 and end after its start", start, end)
+	}
+
+	starts, durations, err := m.periodTimes()
+	if err != nil {
+		return nil, err
+	}
+	last := len(m.Periods) - 1
+	if total := starts[last] + durations[last]; end > total {
+		end = total
+	}
+	if start >= end {
+		return nil, fmt.Errorf("clip start %v is outside the presentation", start)
+	}
+
+	out := m.clone()
+	for _, at := range []time.Duration{start, end} {
+		if out, err = splitInside(out, at); err != nil {
+			return nil, err
+		}
+	}
+
+	if starts, durations, err = out.periodTimes(); err != nil {
+		return nil, err
+	}
+	var periods Periods
+	kept := make(map[string]bool)
+	for i, p := range out.Periods {
+		if starts[i] < start || starts[i]+durations[i] > end {
+			continue
+		}
+		p.Start = &CustomDuration{Duration: starts[i] - start}
+		p.Duration = &CustomDuration{Duration: durations[i]}
+		periods = append(periods, p)
+		kept[p.ID] = true
+	}
+
+	//Periods continued by the Periods of the clip may have been removed, or split
+	for i, p := range periods {
+		linked := false
+		for _, as := range p.AdaptationSets {
+			var descriptors []*Descriptor
+			for _, d := range as.SupplementalProperty {
+				if d.SchemeIDURI != PeriodContinuityScheme {
+					descriptors = append(descriptors, d)
+					continue
+				}
+				linked = true
+				if kept[d.Value] {
+					descriptors = append(descriptors, d)
+				}
+			}
+			as.SupplementalProperty = descriptors
+		}
+		if linked && i > 0 && continuous(periods[i-1], p) {
+			markContinuity(periods[i-1], p)
+		}
+	}
+
+	out.Periods = periods
+	out.MediaPresDuration = &CustomDuration{Duration: end - start}
+	return out, nil
+}
+
+//splitInside splits m at at with Split, unless at is on a Period boundary or outside the presentation.
+func splitInside(m *MPD, at time.Duration) (*MPD, error) {
+	starts, durations, err := m.periodTimes()
+	if err != nil {
+		return nil, err
+	}
+	for i := range m.Periods {
+		if at > starts[i] && at < starts[i]+durations[i] {
+			return Split(m, at)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,129 @@
+package dash
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClip(t *testing.T) {
+	mpd := getMultiPeriodMPD()
+
+	clip, err := Clip(mpd, 4*time.Second, 16*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mpd.Periods) != 1 || mpd.Periods[0].Duration != nil {
+		t.Error("Expected Clip to not modify the original MPD")
+	}
+	if len(clip.Periods) != 1 {
+		t.Fatalf("Expected 1 Period, but got %d", len(clip.Periods))
+	}
+
+	p := clip.Periods[0]
+	if p.Start.Duration != 0 || p.Duration.Duration != 12*time.Second || clip.MediaPresDuration.Duration != 12*time.Second {
+		t.Errorf("Expected a 12s Period starting at 0, but got %v starting at %v", p.Duration, p.Start)
+	}
+
+	video := p.AdaptationSets[0].Representations[0].SegmentTemplate
+	if startNumber(video.StartNumber) != 3 || video.PresTimeOffset != 1024+49152 {
+		t.Errorf("Expected startNumber 3 and presentationTimeOffset 50176, but got %d and %d", startNumber(video.StartNumber), video.PresTimeOffset)
+	}
+
+	//the audio segments from 3.968s to 17.856s overlap the clip
+	audio := p.AdaptationSets[1].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 190464, D: 95232, R: 6}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) || audio.PresTimeOffset != 192000 {
+		t.Errorf("Expected audio timeline %v with presentationTimeOffset 192000, but got %v and %d", expect, audio.SegmentTimeline.Segments, audio.PresTimeOffset)
+	}
+
+	for _, as := range p.AdaptationSets {
+		if len(as.SupplementalProperty) != 0 {
+			t.Errorf("Expected AdaptationSet %d not to continue a removed Period", as.ID)
+		}
+	}
+	if events := p.EventStream[0].Event; len(events) != 1 || events[0].PresTime != 10 {
+		t.Errorf("Expected the event at 14s to be kept at 10s, but got %v", events)
+	}
+
+	if _, err := clip.Encode(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClipMultiPeriod(t *testing.T) {
+	split, err := Split(getMultiPeriodMPD(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clip, err := Clip(split, 4*time.Second, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clip.Periods) != 2 || clip.MediaPresDuration.Duration != 16*time.Second {
+		t.Fatalf("Expected 2 Periods for 16s, but got %d for %v", len(clip.Periods), clip.MediaPresDuration)
+	}
+	first, second := clip.Periods[0], clip.Periods[1]
+	if first.Start.Duration != 0 || first.Duration.Duration != 6*time.Second || second.Start.Duration != 6*time.Second || second.Duration.Duration != 10*time.Second {
+		t.Errorf("Expected Periods of 6s and 10s, but got %v at %v and %v at %v", first.Duration, first.Start, second.Duration, second.Start)
+	}
+	if len(second.AdaptationSets[0].SupplementalProperty) != 1 || second.AdaptationSets[0].SupplementalProperty[0].Value != first.ID {
+		t.Errorf("Expected the second Period to continue the first one")
+	}
+}
+
+func TestClipBroadcastTimescale(t *testing.T) {
+	mpd := getMultiPeriodMPD()
+	mpd.MediaPresDuration = &CustomDuration{Duration: 3 * time.Hour}
+	video := mpd.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	video.Timescale, video.PresTimeOffset, video.Duration = 10000000, 0, 20000000
+	audio := mpd.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate
+	audio.Timescale = 90000
+	audio.SegmentTimeline.Segments = Segments{&S{T: 0, D: 180000, R: 5399}}
+
+	clip, err := Clip(mpd, time.Hour, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clip.Periods) != 1 || clip.MediaPresDuration.Duration != time.Hour {
+		t.Fatalf("Expected 1 Period for 1h, but got %d for %v", len(clip.Periods), clip.MediaPresDuration)
+	}
+
+	p := clip.Periods[0]
+	video = p.AdaptationSets[0].Representations[0].SegmentTemplate
+	if startNumber(video.StartNumber) != 1801 || video.PresTimeOffset != 36000000000 {
+		t.Errorf("Expected startNumber 1801 and presentationTimeOffset 36000000000, but got %d and %d", startNumber(video.StartNumber), video.PresTimeOffset)
+	}
+	audio = p.AdaptationSets[1].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 324000000, D: 180000, R: 1799}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) || audio.PresTimeOffset != 324000000 {
+		t.Errorf("Expected audio timeline %v with presentationTimeOffset 324000000, but got %v and %d", expect, audio.SegmentTimeline.Segments, audio.PresTimeOffset)
+	}
+	if err := clip.ValidateTimelines(); err != nil {
+		t.Errorf("Expected the clip to be covered by its timelines, but got %s", err)
+	}
+}
+
+func TestClipErrors(t *testing.T) {
+	mpd := getMultiPeriodMPD()
+	tests := []struct {
+		name       string
+		start, end time.Duration
+	}{
+		{"negative start", -time.Second, 4 * time.Second},
+		{"empty", 4 * time.Second, 4 * time.Second},
+		{"outside", 20 * time.Second, 30 * time.Second},
+		{"off a segment boundary", 3 * time.Second, 8 * time.Second},
+	}
+	for _, tt := range tests {
+		if _, err := Clip(mpd, tt.start, tt.end); err == nil {
+			t.Errorf("Expected error for a clip %s", tt.name)
+		}
+	}
+
+	mpd.Type = "dynamic"
+	if _, err := Clip(mpd, 0, 4*time.Second); err == nil {
+		t.Error("Expected error clipping a dynamic MPD")
+	}
+}
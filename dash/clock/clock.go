@@ -0,0 +1,222 @@
+//Package clock synchronises a local wall clock with the time source advertised
+//by the UTCTiming elements of a DASH MPD.
+package clock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+)
+
+//UTCTiming schemes defined by ISO/IEC 23009-1 and the DASH-IF interoperability guidelines.
+const (
+	SchemeHTTPXSDate = "urn:mpeg:dash:utc:http-xsdate:2014"
+	SchemeHTTPISO    = "urn:mpeg:dash:utc:http-iso:2014"
+	SchemeHTTPHead   = "urn:mpeg:dash:utc:http-head:2014"
+	SchemeHTTPNTP    = "urn:mpeg:dash:utc:http-ntp:2014"
+	SchemeDirect     = "urn:mpeg:dash:utc:direct:2014"
+)
+
+//ErrUnsupportedScheme is returned when a UTCTiming scheme can't be used to synchronise the clock.
+var ErrUnsupportedScheme = errors.New("unsupported UTCTiming scheme")
+
+//legacySchemes maps the 2012 scheme identifiers, still used by some packagers, to their 2014 equivalent.
+var legacySchemes = map[string]string{
+	"urn:mpeg:dash:utc:http-xsdate:2012": SchemeHTTPXSDate,
+	"urn:mpeg:dash:utc:http-iso:2012":    SchemeHTTPISO,
+	"urn:mpeg:dash:utc:http-head:2012":   SchemeHTTPHead,
+	"urn:mpeg:dash:utc:http-ntp:2012":    SchemeHTTPNTP,
+	"urn:mpeg:dash:utc:direct:2012":      SchemeDirect,
+}
+
+//dateLayouts are the xs:dateTime and ISO 8601 layouts accepted from a time server.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z0700",
+	time.RFC1123,
+}
+
+//Clock is a wall clock that applies the offset measured against a UTCTiming source.
+//The zero value is not synchronised and reports local time until Sync succeeds.
+type Clock struct {
+	Client *http.Client
+
+	mu     sync.RWMutex
+	offset time.Duration
+	synced bool
+	now    func() time.Time
+}
+
+//New returns a Clock that uses c to reach HTTP time sources.
+//By default uses the http.DefaultClient if a nil pointer is passed.
+func New(c *http.Client) *Clock {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &Clock{
+		Client: c,
+		now:    time.Now,
+	}
+}
+
+//Sync tries each UTCTiming descriptor in order and keeps the offset of the first one
+//that succeeds. Descriptors with unsupported schemes are skipped.
+func (c *Clock) Sync(ctx context.Context, timings []*dash.Descriptor) (time.Duration, error) {
+	if len(timings) == 0 {
+		return 0, errors.New("no UTCTiming elements to synchronise with")
+	}
+
+	var lastErr error
+	for _, timing := range timings {
+		offset, err := c.Measure(ctx, timing)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.offset = offset
+		c.synced = true
+		c.mu.Unlock()
+		return offset, nil
+	}
+
+	return 0, lastErr
+}
+
+//Measure returns the offset between the server described by timing and the local clock,
+//without updating the Clock. A positive offset means the server is ahead of local time.
+func (c *Clock) Measure(ctx context.Context, timing *dash.Descriptor) (time.Duration, error) {
+	if timing == nil {
+		return 0, errors.New("UTCTiming element is nil")
+	}
+
+	scheme := timing.SchemeIDURI
+	if s, ok := legacySchemes[scheme]; ok {
+		scheme = s
+	}
+
+	switch scheme {
+	case SchemeDirect:
+		server, err := parseDate(timing.Value)
+		if err != nil {
+			return 0, err
+		}
+		return server.Sub(c.localNow()), nil
+
+	case SchemeHTTPXSDate, SchemeHTTPISO, SchemeHTTPHead:
+		var lastErr error
+		//Value may hold a whitespace separated list of servers, try them in order
+		for _, uri := range strings.Fields(timing.Value) {
+			offset, err := c.measureHTTP(ctx, scheme, uri)
+			if err == nil {
+				return offset, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("UTCTiming %s has no server url", timing.SchemeIDURI)
+		}
+		return 0, lastErr
+
+	case SchemeHTTPNTP:
+		//TODO: decode the 64 bit NTP timestamp returned by http-ntp servers.
+		return 0, ErrUnsupportedScheme
+	}
+
+	return 0, ErrUnsupportedScheme
+}
+
+//measureHTTP requests the time from uri and compensates for half the round trip.
+func (c *Clock) measureHTTP(ctx context.Context, scheme string, uri string) (time.Duration, error) {
+	method := "GET"
+	if scheme == SchemeHTTPHead {
+		method = "HEAD"
+	}
+
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to construct request: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	sent := c.localNow()
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	received := c.localNow()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return 0, fmt.Errorf("time server %s responded with status %d", uri, res.StatusCode)
+	}
+
+	var server time.Time
+	if scheme == SchemeHTTPHead {
+		server, err = http.ParseTime(res.Header.Get("Date"))
+	} else {
+		var body []byte
+		body, err = ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		if err == nil {
+			server, err = parseDate(string(body))
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read time from %s: %v", uri, err)
+	}
+
+	midpoint := sent.Add(received.Sub(sent) / 2)
+	return server.Sub(midpoint), nil
+}
+
+//Offset returns the offset applied to the local clock by the last successful Sync.
+func (c *Clock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+//Synced reports whether Sync has succeeded at least once.
+func (c *Clock) Synced() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.synced
+}
+
+//Now returns the local time corrected by the synchronised offset.
+func (c *Clock) Now() time.Time {
+	return c.localNow().Add(c.Offset())
+}
+
+func (c *Clock) localNow() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q", value)
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+)
+
+func fixedClock(local time.Time) *Clock {
+	c := New(nil)
+	c.now = func() time.Time { return local }
+	return c
+}
+
+func TestMeasureSchemes(t *testing.T) {
+	local := time.Date(2016, 7, 13, 17, 22, 23, 0, time.UTC)
+	server := local.Add(90 * time.Second)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", server.Format(http.TimeFormat))
+		if r.Method == "HEAD" {
+			return
+		}
+		w.Write([]byte(server.Format("2006-01-02T15:04:05.000Z")))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		Case   string
+		Timing *dash.Descriptor
+	}{
+		{"xsdate", &dash.Descriptor{SchemeIDURI: SchemeHTTPXSDate, Value: ts.URL}},
+		{"iso", &dash.Descriptor{SchemeIDURI: SchemeHTTPISO, Value: ts.URL}},
+		{"head", &dash.Descriptor{SchemeIDURI: SchemeHTTPHead, Value: ts.URL}},
+		{"legacy xsdate", &dash.Descriptor{SchemeIDURI: "urn:mpeg:dash:utc:http-xsdate:2012", Value: ts.URL}},
+		{"fallback server", &dash.Descriptor{SchemeIDURI: SchemeHTTPXSDate, Value: "http://127.0.0.1:0/ " + ts.URL}},
+		{"direct", &dash.Descriptor{SchemeIDURI: SchemeDirect, Value: server.Format(time.RFC3339)}},
+	}
+
+	for _, tt := range tests {
+		c := fixedClock(local)
+		offset, err := c.Measure(context.Background(), tt.Timing)
+		if err != nil {
+			t.Errorf("%s - unexpected error: %v", tt.Case, err)
+			continue
+		}
+		if offset != 90*time.Second {
+			t.Errorf("%s - expected offset of 90s, but got %v", tt.Case, offset)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	local := time.Date(2016, 7, 13, 17, 22, 23, 0, time.UTC)
+	c := fixedClock(local)
+
+	if c.Synced() {
+		t.Fatal("Expected new clock to not be synced")
+	}
+
+	timings := []*dash.Descriptor{
+		&dash.Descriptor{SchemeIDURI: SchemeHTTPNTP, Value: "http://time.example.com"},
+		&dash.Descriptor{SchemeIDURI: SchemeDirect, Value: "2016-07-13T17:22:13Z"},
+	}
+	offset, err := c.Sync(context.Background(), timings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != -10*time.Second || c.Offset() != offset {
+		t.Errorf("Expected offset of -10s, but got %v", c.Offset())
+	}
+	if !c.Synced() {
+		t.Error("Expected clock to be synced")
+	}
+	if !c.Now().Equal(local.Add(-10 * time.Second)) {
+		t.Errorf("Expected Now to be %v, but got %v", local.Add(-10*time.Second), c.Now())
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := New(nil)
+	if _, err := c.Sync(context.Background(), nil); err == nil {
+		t.Error("Expected error when there are no UTCTiming elements")
+	}
+
+	timings := []*dash.Descriptor{&dash.Descriptor{SchemeIDURI: SchemeHTTPNTP, Value: ts.URL}}
+	if _, err := c.Sync(context.Background(), timings); err != ErrUnsupportedScheme {
+		t.Errorf("Expected ErrUnsupportedScheme, but got %v", err)
+	}
+
+	timings = []*dash.Descriptor{&dash.Descriptor{SchemeIDURI: SchemeHTTPISO, Value: ts.URL}}
+	if _, err := c.Sync(context.Background(), timings); err == nil {
+		t.Error("Expected error on 404 response")
+	}
+	if c.Synced() {
+		t.Error("Expected clock to not be synced after failures")
+	}
+}
@@ -1,17 +1,24 @@
 package dash
 
 import (
+	"bytes"
 	"encoding/xml"
 	"io"
+	"io/ioutil"
 )
 
 //Parse decodes a MPD file into an MPD element.
 //It validates MPD according to specs and returns an error if validation fails.
 func (m *MPD) Parse(reader io.Reader) error {
-	err := xml.NewDecoder(reader).Decode(&m)
+	data, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return err
 	}
+	err = xml.NewDecoder(bytes.NewReader(data)).Decode(&m)
+	if err != nil {
+		return err
+	}
+	m.scanExtensions(data)
 
 	return m.validate()
 }
@@ -70,6 +70,22 @@ func TestDynamicParse(t *testing.T) {
 	if len(mpd.Periods[0].AdaptationSets[1].Representations[0].AudioChannelConfig) != 1 {
 		t.Errorf("Expecting 1 AudioChannelConfig, but got %d", len(mpd.Periods[0].AdaptationSets[1].Representations[0].AudioChannelConfig))
 	}
+
+	if len(mpd.UTCTiming) != 2 {
+		t.Fatalf("Expecting 2 UTCTiming elements, but got %d", len(mpd.UTCTiming))
+	}
+
+	if mpd.UTCTiming[1].SchemeIDURI != "urn:mpeg:dash:utc:direct:2014" {
+		t.Errorf("Expecting UTCTiming scheme urn:mpeg:dash:utc:direct:2014, but got %s", mpd.UTCTiming[1].SchemeIDURI)
+	}
+
+	if len(mpd.PatchLocation) != 1 || mpd.PatchLocation[0].TTL != 60 {
+		t.Fatalf("Expecting 1 PatchLocation with ttl 60, but got %d", len(mpd.PatchLocation))
+	}
+
+	if mpd.PatchLocation[0].URL != "http://54.72.87.160/stattodyn/patch.php?id=multirate&publishTime=2013-08-10T22:03:00Z" {
+		t.Errorf("Expecting PatchLocation URL, but got %s", mpd.PatchLocation[0].URL)
+	}
 }
 
 func TestEventMessage(t *testing.T) {
@@ -109,3 +125,59 @@ func TestEventMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestLowLatencyParse(t *testing.T) {
+	f, err := os.Open("./testdata/lowlatency.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	mpd := &MPD{}
+	if err := mpd.Parse(bufio.NewReader(f)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mpd.ServiceDescription) != 1 {
+		t.Fatalf("Expecting 1 ServiceDescription, but got %d", len(mpd.ServiceDescription))
+	}
+	sd := mpd.ServiceDescription[0]
+	if len(sd.Latency) != 1 || sd.Latency[0].Target != 4000 || sd.Latency[0].Min != 2000 || sd.Latency[0].Max != 6000 {
+		t.Errorf("Expecting Latency target 4000, min 2000 and max 6000, but got %v", sd.Latency)
+	}
+	if sd.Latency[0].ReferenceID == nil || *sd.Latency[0].ReferenceID != 0 {
+		t.Errorf("Expecting Latency ReferenceID to be 0, but got %v", sd.Latency[0].ReferenceID)
+	}
+	if len(sd.PlaybackRate) != 1 || sd.PlaybackRate[0].Min != 0.96 || sd.PlaybackRate[0].Max != 1.04 {
+		t.Errorf("Expecting PlaybackRate min 0.96 and max 1.04, but got %v", sd.PlaybackRate)
+	}
+
+	video := mpd.Periods[0].AdaptationSets[1]
+	if len(video.ProducerReferenceTime) != 1 {
+		t.Fatalf("Expecting 1 ProducerReferenceTime, but got %d", len(video.ProducerReferenceTime))
+	}
+	prt := video.ProducerReferenceTime[0]
+	if prt.Type != "encoder" || prt.WallClockTime != "1970-01-01T00:00:00Z" || prt.UTCTiming == nil {
+		t.Errorf("Expecting encoder ProducerReferenceTime with UTCTiming, but got %v", prt)
+	}
+
+	st := video.SegmentTemplate
+	if st.AvTimeOffset != 7 || st.AvTimeComplete == nil || *st.AvTimeComplete {
+		t.Errorf("Expecting AvTimeOffset 7 and AvTimeComplete false, but got %v and %v", st.AvTimeOffset, st.AvTimeComplete)
+	}
+
+	f, err = os.Open("./testdata/lowlatencytimeline.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	mpd = &MPD{}
+	if err := mpd.Parse(bufio.NewReader(f)); err != nil {
+		t.Fatal(err)
+	}
+	resync := mpd.Periods[0].AdaptationSets[0].Resync
+	if len(resync) != 1 || resync[0].Type != 2 || resync[0].DT != 500000 || !resync[0].Marker {
+		t.Errorf("Expecting Resync type 2, dT 500000 with marker, but got %v", resync)
+	}
+}
@@ -173,3 +173,27 @@ func TestContentProtectionTrackEncryption(t *testing.T) {
 		t.Errorf("Expecting:\n%s \n but got \n%s", expect, buf.String())
 	}
 }
+
+func TestEncodeDeprecatedAvTmeComplete(t *testing.T) {
+	mpd := getDefaultMPD()
+	mpd.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate.AvTmeComplete = true
+
+	o, err := mpd.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(o)
+	if !bytes.Contains(buf.Bytes(), []byte(`availabilityTimeComplete="true"`)) {
+		t.Errorf("Expecting availabilityTimeComplete=\"true\", but got \n%s", buf.String())
+	}
+
+	m := &MPD{}
+	if err := m.Parse(buf); err != nil {
+		t.Fatal(err)
+	}
+	st := m.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	if st.AvTimeComplete == nil || !*st.AvTimeComplete || !st.AvTmeComplete {
+		t.Errorf("Expecting AvTimeComplete and AvTmeComplete to be true, but got %v and %v", st.AvTimeComplete, st.AvTmeComplete)
+	}
+}
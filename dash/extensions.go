@@ -0,0 +1,356 @@
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//positionTarget is the target of the processing instruction marking an Element with a position in
+//the output of the encoder, which MPD.MarshalXML removes.
+const positionTarget = "manifest-position"
+
+//position is the position of an Element among the elements of its parent, after the index-th
+//modelled element named after, or first if after is empty. The dash structs write elements in
+//schema order, while unknown elements are written after them, so they are moved back to their position.
+type position struct {
+	after string
+	index int
+}
+
+func (p *position) marker() xml.ProcInst {
+	return xml.ProcInst{Target: positionTarget, Inst: []byte(strconv.Itoa(p.index) + " " + p.after)}
+}
+
+func parsePosition(inst []byte) *position {
+	fields := strings.SplitN(string(inst), " ", 2)
+	if len(fields) != 2 {
+		return nil
+	}
+	index, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil
+	}
+	return &position{after: fields[1], index: index}
+}
+
+//sourceNode is an element of a parsed MPD, found by its input offset.
+type sourceNode struct {
+	name     string
+	parent   *sourceNode
+	children []*sourceNode
+}
+
+//scanExtensions records the namespace declarations of the MPD file data, and the position of
+//the unknown elements decoded into ExtraElements, so Encode writes them back as in data.
+func (m *MPD) scanExtensions(data []byte) {
+	nodes := make(map[int64]*sourceNode)
+	prefixes := make(map[string]bool)
+	uris := make(map[string]bool)
+	m.namespaces = []xml.Attr{}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	parent := &sourceNode{}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" && !prefixes[attr.Name.Local] && !uris[attr.Value] {
+					prefixes[attr.Name.Local] = true
+					uris[attr.Value] = true
+					m.namespaces = append(m.namespaces, attr)
+				}
+			}
+			n := &sourceNode{name: t.Name.Local, parent: parent}
+			parent.children = append(parent.children, n)
+			nodes[d.InputOffset()] = n
+			parent = n
+		case xml.EndElement:
+			if parent.parent != nil {
+				parent = parent.parent
+			}
+		}
+	}
+
+	var extras []*Element
+	walkElements(reflect.ValueOf(m), func(e *Element) {
+		extras = append(extras, e)
+	})
+	extra := make(map[*sourceNode]bool)
+	for _, e := range extras {
+		if n := nodes[e.offset]; n != nil {
+			extra[n] = true
+		}
+	}
+	for _, e := range extras {
+		n := nodes[e.offset]
+		if n == nil {
+			continue
+		}
+		p := &position{}
+		for _, sibling := range n.parent.children {
+			if sibling == n {
+				break
+			}
+			if !extra[sibling] {
+				p.after = sibling.name
+			}
+		}
+		for _, sibling := range n.parent.children {
+			if sibling == n {
+				break
+			}
+			if !extra[sibling] && sibling.name == p.after {
+				p.index++
+			}
+		}
+		p.index--
+		e.position = p
+	}
+	for _, e := range extras {
+		e.clearOffsets()
+	}
+}
+
+//clearOffsets resets the input offsets of e and its children once positions are known, so
+//Elements of equal files are equal.
+func (e *Element) clearOffsets() {
+	e.offset = 0
+	for _, c := range e.Elements {
+		c.clearOffsets()
+	}
+}
+
+//walkElements calls f with the Elements found in v, without walking their children.
+func walkElements(v reflect.Value, f func(*Element)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if e, ok := v.Interface().(*Element); ok {
+			f(e)
+			return
+		}
+		walkElements(v.Elem(), f)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkElements(v.Index(i), f)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				walkElements(v.Field(i), f)
+			}
+		}
+	}
+}
+
+//MarshalXML implements Marshaler interface for MPD. The MPD of a parsed file is written with the
+//namespace prefixes of the file, and its unknown elements at their position in the file.
+func (m *MPD) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type mpd MPD
+	if m.namespaces == nil {
+		return e.EncodeElement((*mpd)(m), start)
+	}
+
+	buf := new(bytes.Buffer)
+	enc := xml.NewEncoder(buf)
+	if err := enc.EncodeElement((*mpd)(m), start); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	root, err := parseOutputTree(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("re-encoding MPD: %v", err)
+	}
+	w := &namespaceWriter{e: e, prefixes: make(map[string]string), used: make(map[string]bool)}
+	for _, ns := range m.namespaces {
+		w.prefixes[ns.Value] = ns.Name.Local
+		w.used[ns.Name.Local] = true
+	}
+	return w.write(root, "", m.namespaces)
+}
+
+//outputNode is an element written by the encoder, with its children elements and other tokens.
+type outputNode struct {
+	start    xml.StartElement
+	position *position
+	children []interface{}
+}
+
+//parseOutputTree parses the output of the encoder into a tree, moving the marked Elements to their position.
+func parseOutputTree(data []byte) (*outputNode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := &outputNode{}
+	stack := []*outputNode{root}
+	var marked *position
+	for {
+		t, err := d.Token()
+		if err == io.EOF && len(stack) == 1 && len(root.children) == 1 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch t := t.(type) {
+		case xml.StartElement:
+			n := &outputNode{start: t.Copy(), position: marked}
+			marked = nil
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			parent.reorder()
+			stack = stack[:len(stack)-1]
+		case xml.ProcInst:
+			if t.Target == positionTarget {
+				marked = parsePosition(t.Inst)
+				continue
+			}
+			parent.children = append(parent.children, t.Copy())
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) != 0 {
+				parent.children = append(parent.children, t.Copy())
+			}
+		default:
+			parent.children = append(parent.children, xml.CopyToken(t))
+		}
+	}
+	return root.children[0].(*outputNode), nil
+}
+
+//reorder moves the children with a position after the modelled child they followed in the file.
+func (n *outputNode) reorder() {
+	var children, first, last []interface{}
+	after := make(map[*outputNode][]interface{})
+	var moved []*outputNode
+	for _, c := range n.children {
+		if c, ok := c.(*outputNode); ok && c.position != nil {
+			moved = append(moved, c)
+			continue
+		}
+		children = append(children, c)
+	}
+	if len(moved) == 0 {
+		return
+	}
+
+	for _, c := range moved {
+		if c.position.after == "" {
+			first = append(first, c)
+			continue
+		}
+		var anchor *outputNode
+		index := 0
+		for _, sibling := range children {
+			if sibling, ok := sibling.(*outputNode); ok && sibling.start.Name.Local == c.position.after {
+				anchor = sibling
+				if index == c.position.index {
+					break
+				}
+				index++
+			}
+		}
+		if anchor == nil {
+			last = append(last, c)
+			continue
+		}
+		after[anchor] = append(after[anchor], c)
+	}
+
+	n.children = first
+	for _, c := range children {
+		n.children = append(n.children, c)
+		if c, ok := c.(*outputNode); ok {
+			n.children = append(n.children, after[c]...)
+		}
+	}
+	n.children = append(n.children, last...)
+}
+
+//namespaceWriter writes an output tree with the namespace prefixes of the parsed file, declared on
+//the root element, rather than the prefixes and declarations of the encoder.
+type namespaceWriter struct {
+	e         *xml.Encoder
+	prefixes  map[string]string //namespace URI to prefix
+	used      map[string]bool
+	generated int
+}
+
+//write writes n and its children, in the default namespace defaultNS of its parent. The root
+//element is written with the namespace declarations of the parsed file, in namespaces.
+func (w *namespaceWriter) write(n *outputNode, defaultNS string, namespaces []xml.Attr) error {
+	root := namespaces != nil
+	start := xml.StartElement{}
+	var declarations []xml.Attr
+
+	ns := n.start.Name.Space
+	switch prefix, ok := w.prefixes[ns]; {
+	case ns == defaultNS:
+		start.Name.Local = n.start.Name.Local
+	case ok && !root:
+		start.Name.Local = prefix + ":" + n.start.Name.Local
+	default:
+		start.Name.Local = n.start.Name.Local
+		declarations = append(declarations, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: ns})
+		defaultNS = ns
+	}
+	for _, ns := range namespaces {
+		declarations = append(declarations, xml.Attr{Name: xml.Name{Local: "xmlns:" + ns.Name.Local}, Value: ns.Value})
+	}
+
+	var attrs []xml.Attr
+	for _, attr := range n.start.Attr {
+		switch {
+		case attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns"):
+			continue
+		case attr.Name.Space == "":
+		case attr.Name.Space == "http://www.w3.org/XML/1998/namespace":
+			attr.Name.Local = "xml:" + attr.Name.Local
+		default:
+			prefix, ok := w.prefixes[attr.Name.Space]
+			if !ok {
+				for prefix == "" || w.used[prefix] {
+					w.generated++
+					prefix = "ns" + strconv.Itoa(w.generated)
+				}
+				declarations = append(declarations, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: attr.Name.Space})
+				w.prefixes[attr.Name.Space] = prefix
+				defer delete(w.prefixes, attr.Name.Space)
+			}
+			attr.Name.Local = prefix + ":" + attr.Name.Local
+		}
+		attr.Name.Space = ""
+		attrs = append(attrs, attr)
+	}
+	start.Attr = append(declarations, attrs...)
+
+	if err := w.e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, c := range n.children {
+		var err error
+		if child, ok := c.(*outputNode); ok {
+			err = w.write(child, defaultNS, nil)
+		} else {
+			err = w.e.EncodeToken(c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return w.e.EncodeToken(start.End())
+}
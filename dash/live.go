@@ -0,0 +1,207 @@
+package dash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//Live maintains a dynamic MPD for a live presentation. Segments are appended to each
+//Representation's SegmentTimeline as they are packaged, and Update slides the timelines
+//according to TimeShiftBufferDepth before the MPD is encoded.
+//
+//Periods are addressed by their ID, which is never changed once the Period is added so
+//clients can match Periods across MPD updates.
+type Live struct {
+	*MPD
+}
+
+//NewLive initiates a dynamic MPD with the minimum required attributes.
+func NewLive(profile string, minBufferTime time.Duration, availabilityStartTime time.Time) *Live {
+	mpd := NewMPD(profile, minBufferTime)
+	mpd.Type = "dynamic"
+	mpd.AvStartTime = &CustomTime{Time: availabilityStartTime}
+	mpd.PublishTime = &CustomTime{Time: availabilityStartTime}
+	return &Live{MPD: mpd}
+}
+
+//SetTimeShiftBufferDepth sets the window of segments kept available by Update.
+func (l *Live) SetTimeShiftBufferDepth(depth time.Duration) {
+	l.TimeShiftBuffer = &CustomDuration{Duration: depth}
+}
+
+//AddPeriod appends a Period to the presentation. Period must have an unique ID.
+func (l *Live) AddPeriod(p *Period) error {
+	if p == nil {
+		return errors.New("Period must not be nil")
+	}
+	if p.ID == "" {
+		return errors.New("Period must have ID when Type = 'dynamic'")
+	}
+	if l.Period(p.ID) != nil {
+		return fmt.Errorf("Period with ID %s already exists", p.ID)
+	}
+	l.Periods = append(l.Periods, p)
+	return nil
+}
+
+//Period returns the Period with the given ID, or nil if it doesn't exist.
+func (l *Live) Period(id string) *Period {
+	return l.periodByID(id)
+}
+
+//AddSegment appends a segment starting at t with duration d, both in timescale units, to the
+//SegmentTimeline of a Representation. The Representation must have a SegmentTemplate.
+func (l *Live) AddSegment(periodID string, representationID string, t, d int) error {
+	p := l.Period(periodID)
+	if p == nil {
+		return fmt.Errorf("Period with ID %s not found", periodID)
+	}
+
+	rep := p.representation(representationID)
+	if rep == nil {
+		return fmt.Errorf("Representation with ID %s not found in Period %s", representationID, periodID)
+	}
+
+	st := rep.SegmentTemplate
+	if st == nil {
+		return fmt.Errorf("Representation %s must have a SegmentTemplate", representationID)
+	}
+	if st.Duration != 0 {
+		return fmt.Errorf("Representation %s SegmentTemplate uses duration instead of SegmentTimeline", representationID)
+	}
+	if st.SegmentTimeline == nil {
+		st.SegmentTimeline = &SegmentTimeline{}
+	}
+
+	st.SegmentTimeline.AppendSegment(t, d)
+	return nil
+}
+
+//Update sets PublishTime to now and removes every segment that ended before the
+//TimeShiftBufferDepth window, from the SegmentTemplate applying to each Representation. Periods that ended before the window are removed, except the last one.
+//A Period ends after its Duration, or where the next Period starts. Periods of unknown end are removed
+//once they are left without segments. The Period following a removed one gets its start, if it had none.
+func (l *Live) Update(now time.Time) {
+	l.PublishTime = &CustomTime{Time: now}
+
+	if l.TimeShiftBuffer == nil || l.AvStartTime == nil {
+		return
+	}
+	cutoff := now.Add(-l.TimeShiftBuffer.Duration)
+
+	var periods Periods
+	var start time.Duration
+	for i, p := range l.Periods {
+		if p.Start != nil {
+			start = p.Start.Duration
+		}
+		//media time relative to the Period start at which segments leave the window
+		elapsed := cutoff.Sub(l.AvStartTime.Time.Add(start))
+
+		empty := true
+		for _, as := range p.AdaptationSets {
+			for _, rep := range as.Representations {
+				st := effectiveTemplate(p, as, rep)
+				if st == nil || st.SegmentTimeline == nil {
+					continue
+				}
+				timescale := st.Timescale
+				if timescale == 0 {
+					timescale = 1
+				}
+				st.SegmentTimeline.TrimBefore(int(st.PresTimeOffset) + int(elapsed.Seconds()*float64(timescale)))
+				if len(st.SegmentTimeline.Segments) > 0 {
+					empty = false
+				}
+			}
+		}
+
+		last := i == len(l.Periods)-1
+		end, ended := time.Duration(0), false
+		switch {
+		case p.Duration != nil:
+			end, ended = start+p.Duration.Duration, true
+		case !last && l.Periods[i+1].Start != nil:
+			end, ended = l.Periods[i+1].Start.Duration, true
+		}
+
+		switch {
+		case last || (ended && l.AvStartTime.Time.Add(end).After(cutoff)) || (!ended && !empty):
+			periods = append(periods, p)
+		case ended && l.Periods[i+1].Start == nil:
+			//the next Period starts where the removed one ended
+			l.Periods[i+1].Start = &CustomDuration{Duration: end}
+		}
+		if ended {
+			start = end
+		}
+	}
+	l.Periods = periods
+}
+
+//AppendSegment adds a segment starting at t with duration d to the end of the SegmentTimeline.
+//If the segment follows the last S element without a gap and with the same duration,
+//it's folded into that element by incrementing its repeat count.
+func (st *SegmentTimeline) AppendSegment(t, d int) {
+	if st == nil {
+		return
+	}
+
+	if n := len(st.Segments); n > 0 {
+		last := st.Segments[n-1]
+		start := st.starts()[n-1]
+		if last.R >= 0 && last.D == d && start+last.D*(last.R+1) == t {
+			last.R++
+			return
+		}
+	}
+	st.Segments = append(st.Segments, &S{T: t, D: d})
+}
+
+//TrimBefore removes every segment that ends at or before t, in timescale units.
+//S elements partially before t are shortened and their start time moved forward.
+func (st *SegmentTimeline) TrimBefore(t int) {
+	if st == nil {
+		return
+	}
+
+	starts := st.starts()
+	var kept Segments
+	for i, s := range st.Segments {
+		start := starts[i]
+		if s.R < 0 || s.D <= 0 {
+			//open ended repeats can't be trimmed without knowing where they end
+			if len(kept) == 0 {
+				s.T = start
+			}
+			kept = append(kept, s)
+			continue
+		}
+
+		end := start + s.D*(s.R+1)
+		if end <= t {
+			continue
+		}
+		if start+s.D <= t {
+			removed := (t - start) / s.D
+			s.R -= removed
+			start += removed * s.D
+		}
+		s.T = start
+		kept = append(kept, s)
+	}
+	st.Segments = kept
+}
+
+//representation returns the Representation with the given ID in any of the Period's AdaptationSets.
+func (p *Period) representation(id string) *Representation {
+	for _, as := range p.AdaptationSets {
+		for _, rep := range as.Representations {
+			if rep.ID == id {
+				return rep
+			}
+		}
+	}
+	return nil
+}
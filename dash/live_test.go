@@ -0,0 +1,208 @@
+package dash
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getLivePeriod(id string) *Period {
+	return &Period{
+		ID:    id,
+		Start: &CustomDuration{Duration: 0},
+		AdaptationSets: AdaptationSets{
+			&AdaptationSet{
+				Representations: Representations{&Representation{
+					ID: "video", MimeType: "video/mp4", Codecs: "avc1.4d01f", Bandwidth: 980104,
+					SegmentTemplate: &SegmentTemplate{
+						Timescale: 1000,
+						Media:     "video_$Time$.mp4"}},
+				},
+			},
+		}}
+}
+
+func TestAppendSegment(t *testing.T) {
+	st := &SegmentTimeline{}
+	st.AppendSegment(0, 2000)
+	st.AppendSegment(2000, 2000)
+	st.AppendSegment(4000, 2000)
+	st.AppendSegment(6000, 1000)
+	st.AppendSegment(8000, 1000)
+
+	expect := Segments{&S{T: 0, D: 2000, R: 2}, &S{T: 6000, D: 1000}, &S{T: 8000, D: 1000}}
+	if !reflect.DeepEqual(st.Segments, expect) {
+		t.Errorf("Expected %v, but got %v", expect, st.Segments)
+	}
+
+	st.TrimBefore(3000)
+	expect = Segments{&S{T: 2000, D: 2000, R: 1}, &S{T: 6000, D: 1000}, &S{T: 8000, D: 1000}}
+	if !reflect.DeepEqual(st.Segments, expect) {
+		t.Errorf("Expected %v, but got %v", expect, st.Segments)
+	}
+
+	st.TrimBefore(7000)
+	expect = Segments{&S{T: 8000, D: 1000}}
+	if !reflect.DeepEqual(st.Segments, expect) {
+		t.Errorf("Expected %v, but got %v", expect, st.Segments)
+	}
+}
+
+func TestLive(t *testing.T) {
+	ast := time.Date(2016, 7, 13, 17, 0, 0, 0, time.UTC)
+	live := NewLive("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2, ast)
+	live.SetTimeShiftBufferDepth(30 * time.Second)
+
+	if err := live.AddPeriod(getLivePeriod("p0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.AddPeriod(getLivePeriod("p0")); err == nil {
+		t.Error("Expected error when adding a Period with a duplicated ID")
+	}
+	if err := live.AddSegment("p0", "audio", 0, 2000); err == nil {
+		t.Error("Expected error when adding segment to an unknown Representation")
+	}
+
+	for i := 0; i < 40; i++ {
+		if err := live.AddSegment("p0", "video", i*2000, 2000); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := ast.Add(80 * time.Second)
+	live.Update(now)
+
+	if !live.PublishTime.Time.Equal(now) {
+		t.Errorf("Expected PublishTime to be %v, but got %v", now, live.PublishTime.Time)
+	}
+
+	timeline := live.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate.SegmentTimeline
+	expect := Segments{&S{T: 50000, D: 2000, R: 14}}
+	if !reflect.DeepEqual(timeline.Segments, expect) {
+		t.Errorf("Expected timeline %v, but got %v", expect, timeline.Segments)
+	}
+
+	//a new Period, the old one leaves the window after it's fully out of the time shift buffer
+	p1 := getLivePeriod("p1")
+	p1.Start = &CustomDuration{Duration: 80 * time.Second}
+	if err := live.AddPeriod(p1); err != nil {
+		t.Fatal(err)
+	}
+	live.AddSegment("p1", "video", 0, 2000)
+
+	live.Update(ast.Add(100 * time.Second))
+	if len(live.Periods) != 2 || live.Periods[0].ID != "p0" {
+		t.Fatalf("Expected Periods p0 and p1, but got %d Periods", len(live.Periods))
+	}
+
+	live.Update(ast.Add(111 * time.Second))
+	if len(live.Periods) != 1 || live.Periods[0].ID != "p1" {
+		t.Fatalf("Expected only Period p1, but got %d Periods", len(live.Periods))
+	}
+
+	r, err := live.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &MPD{}
+	if err := m.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != "dynamic" || m.Periods[0].ID != "p1" {
+		t.Errorf("Expected dynamic MPD with Period p1, but got %s MPD with Period %s", m.Type, m.Periods[0].ID)
+	}
+}
+
+func TestLiveUpdatePeriodDuration(t *testing.T) {
+	ast := time.Date(2016, 7, 13, 17, 0, 0, 0, time.UTC)
+	live := NewLive("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2, ast)
+	live.SetTimeShiftBufferDepth(30 * time.Second)
+
+	//Periods of SegmentTemplate@duration, without SegmentTimeline, starting after the previous one
+	for i, id := range []string{"p0", "p1", "p2"} {
+		p := getLivePeriod(id)
+		p.AdaptationSets[0].Representations[0].SegmentTemplate.Duration = 2000
+		p.AdaptationSets[0].Representations[0].SegmentTemplate.Media = "video_$Number$.mp4"
+		if i > 0 {
+			p.Start = nil
+		}
+		if i < 2 {
+			p.Duration = &CustomDuration{Duration: 60 * time.Second}
+		}
+		if err := live.AddPeriod(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	live.Update(ast.Add(80 * time.Second))
+	if len(live.Periods) != 3 {
+		t.Fatalf("Expected Periods p0, p1 and p2 while p0 is in the time shift buffer, but got %d Periods", len(live.Periods))
+	}
+
+	live.Update(ast.Add(100 * time.Second))
+	if len(live.Periods) != 2 || live.Periods[0].ID != "p1" {
+		t.Fatalf("Expected Periods p1 and p2, but got %d Periods", len(live.Periods))
+	}
+	if live.Periods[0].Start == nil || live.Periods[0].Start.Duration != 60*time.Second {
+		t.Errorf("Expected Period p1 to start at 60s, but got %v", live.Periods[0].Start)
+	}
+
+	live.Update(ast.Add(149 * time.Second))
+	if len(live.Periods) != 2 {
+		t.Fatalf("Expected Periods p1 and p2, but got %d Periods", len(live.Periods))
+	}
+	live.Update(ast.Add(151 * time.Second))
+	if len(live.Periods) != 1 || live.Periods[0].ID != "p2" {
+		t.Fatalf("Expected only Period p2, but got %d Periods", len(live.Periods))
+	}
+}
+
+func TestLiveUpdateInheritedTemplate(t *testing.T) {
+	ast := time.Date(2016, 7, 13, 17, 0, 0, 0, time.UTC)
+	live := NewLive("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2, ast)
+	live.SetTimeShiftBufferDepth(10 * time.Second)
+
+	//the AdaptationSet's SegmentTemplate applies to the Representation
+	p := getLivePeriod("p0")
+	as := p.AdaptationSets[0]
+	as.SegmentTemplate, as.Representations[0].SegmentTemplate = as.Representations[0].SegmentTemplate, nil
+	as.SegmentTemplate.SegmentTimeline = &SegmentTimeline{Segments: Segments{&S{T: 0, D: 2000, R: 9}}}
+	if err := live.AddPeriod(p); err != nil {
+		t.Fatal(err)
+	}
+
+	live.Update(ast.Add(20 * time.Second))
+	expect := Segments{&S{T: 10000, D: 2000, R: 4}}
+	if !reflect.DeepEqual(as.SegmentTemplate.SegmentTimeline.Segments, expect) {
+		t.Errorf("Expected AdaptationSet timeline %v, but got %v", expect, as.SegmentTemplate.SegmentTimeline.Segments)
+	}
+}
+
+func TestLiveUpdateUnknownEnd(t *testing.T) {
+	ast := time.Date(2016, 7, 13, 17, 0, 0, 0, time.UTC)
+	live := NewLive("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2, ast)
+	live.SetTimeShiftBufferDepth(30 * time.Second)
+
+	//p0 has no known end, so p1 can't start before p0's start
+	for i, id := range []string{"p0", "p1"} {
+		p := getLivePeriod(id)
+		p.Start = &CustomDuration{Duration: 600 * time.Second}
+		if i > 0 {
+			p.Start = nil
+		}
+		p.AdaptationSets[0].Representations[0].SegmentTemplate.SegmentTimeline = &SegmentTimeline{Segments: Segments{&S{T: 0, D: 2000, R: 9}}}
+		if err := live.AddPeriod(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	live.Update(ast.Add(620 * time.Second))
+	if len(live.Periods) != 2 {
+		t.Fatalf("Expected Periods p0 and p1, but got %d Periods", len(live.Periods))
+	}
+	st := live.Periods[1].AdaptationSets[0].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 0, D: 2000, R: 9}}
+	if !reflect.DeepEqual(st.SegmentTimeline.Segments, expect) {
+		t.Errorf("Expected the segments of p1 to be kept, but got %v", st.SegmentTimeline.Segments)
+	}
+}
@@ -0,0 +1,684 @@
+package dash
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//PeriodContinuityScheme identifies the SupplementalProperty signalling an AdaptationSet
+//continues the AdaptationSet with the same ID in the Period referenced by the descriptor value.
+const PeriodContinuityScheme = "urn:mpeg:dash:period-continuity:2015"
+
+//ErrNotFlattenable is returned by Flatten when the Periods can't be merged into a single Period.
+var ErrNotFlattenable = errors.New("MPD Periods can't be flattened into a single Period")
+
+//Split returns a copy of a static MPD where the Period containing at, a time relative to the
+//start of the presentation, is split in two Periods. Segment addressing, presentationTimeOffset
+//and startNumber are adjusted for the second Period, and its AdaptationSets are signalled as
+//continuous with the first one.
+//
+//Representations using SegmentTemplate@duration or SegmentList@duration must be split on a
+//segment boundary. With a SegmentTimeline a segment can span both Periods.
+func Split(m *MPD, at time.Duration) (*MPD, error) {
+	if m == nil {
+		return nil, errors.New("MPD must not be nil")
+	}
+	if m.Type == "dynamic" {
+		return nil, errors.New("only static MPDs can be split")
+	}
+
+	starts, durations, err := m.periodTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	for i := range m.Periods {
+		if at > starts[i] && at < starts[i]+durations[i] {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("split point %v must be inside a Period, and not on a Period boundary", at)
+	}
+
+	out := m.clone()
+	first := out.Periods[index]
+	if first.ID == "" {
+		first.ID = out.uniquePeriodID(fmt.Sprintf("%d", index))
+	}
+	second := first.clone()
+	second.ID = out.uniquePeriodID(first.ID + "-2")
+
+	offset := at - starts[index]
+	first.Duration = &CustomDuration{Duration: offset}
+	second.Start = &CustomDuration{Duration: at}
+	second.Duration = &CustomDuration{Duration: durations[index] - offset}
+
+	splitter := &periodSplitter{offset: offset, end: durations[index]}
+	if err := splitter.split(first, second); err != nil {
+		return nil, err
+	}
+	splitEvents(first, second, offset)
+
+	markContinuity(first, second)
+
+	periods := append(Periods{}, out.Periods[:index+1]...)
+	periods = append(periods, second)
+	out.Periods = append(periods, out.Periods[index+1:]...)
+	return out, nil
+}
+
+//Join concatenates the Periods of every MPD into a single multi-period MPD. Period start times
+//are set from each MPD's position in the presentation, Period IDs are made unique and the
+//MPD level BaseURLs are moved to each Period. Adjacent Periods whose media timelines are
+//continuous are signalled with period-continuity descriptors.
+func Join(mpds ...*MPD) (*MPD, error) {
+	if len(mpds) == 0 {
+		return nil, errors.New("Join needs at least one MPD")
+	}
+	for n, m := range mpds {
+		if m == nil {
+			return nil, fmt.Errorf("MPD %d must not be nil", n)
+		}
+	}
+
+	out := mpds[0].clone()
+	out.Periods = nil
+	out.BaseURL = nil
+	out.MediaPresDuration = nil
+
+	var total time.Duration
+	for n, m := range mpds {
+		starts, durations, err := m.periodTimes()
+		if err != nil {
+			return nil, fmt.Errorf("MPD %d: %v", n, err)
+		}
+
+		for i, p := range m.Periods {
+			c := p.clone()
+			c.Start = &CustomDuration{Duration: total + starts[i]}
+			c.Duration = &CustomDuration{Duration: durations[i]}
+
+			id := c.ID
+			if id == "" {
+				id = fmt.Sprintf("%d", i)
+			}
+			if out.periodByID(id) != nil {
+				id = fmt.Sprintf("%d-%s", n, id)
+			}
+			c.ID = out.uniquePeriodID(id)
+
+			if err := inheritBaseURL(c, m.BaseURL); err != nil {
+				return nil, fmt.Errorf("MPD %d: %v", n, err)
+			}
+
+			if len(out.Periods) > 0 {
+				prev := out.Periods[len(out.Periods)-1]
+				if continuous(prev, c) {
+					markContinuity(prev, c)
+				}
+			}
+			out.Periods = append(out.Periods, c)
+		}
+
+		if len(m.Periods) > 0 {
+			last := len(m.Periods) - 1
+			total += starts[last] + durations[last]
+		}
+	}
+
+	out.MediaPresDuration = &CustomDuration{Duration: total}
+	return out, nil
+}
+
+//Flatten merges every Period of a static MPD into a single Period. This is only possible when
+//all Periods have the same AdaptationSets and Representations, each Representation continues
+//the media timeline and segment numbering of the previous Period, and the Periods have the same
+//BaseURLs, AssetIdentifier and unknown attributes and elements. ErrNotFlattenable is returned
+//otherwise. The events of every Period are merged into the EventStreams of the flattened Period.
+func Flatten(m *MPD) (*MPD, error) {
+	if m == nil {
+		return nil, errors.New("MPD must not be nil")
+	}
+	if len(m.Periods) < 2 {
+		return m.clone(), nil
+	}
+
+	starts, durations, err := m.periodTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	out := m.clone()
+	flat := out.Periods[0]
+	flat.Duration = &CustomDuration{Duration: durations[0]}
+	for i, p := range out.Periods[1:] {
+		if starts[i+1] != starts[i]+durations[i] {
+			return nil, ErrNotFlattenable
+		}
+		p.Duration = &CustomDuration{Duration: durations[i+1]}
+		if !continuous(flat, p) || !sameProperties(flat, p) {
+			return nil, ErrNotFlattenable
+		}
+		if err := appendPeriod(flat, p); err != nil {
+			return nil, err
+		}
+		mergeEvents(flat, p, starts[i+1]-starts[0])
+	}
+
+	for _, as := range flat.AdaptationSets {
+		as.SupplementalProperty = removeContinuity(as.SupplementalProperty)
+	}
+	out.Periods = Periods{flat}
+	return out, nil
+}
+
+//periodTimes returns the start and duration of every Period, relative to the start of the presentation.
+func (m *MPD) periodTimes() ([]time.Duration, []time.Duration, error) {
+	if len(m.Periods) == 0 {
+		return nil, nil, errors.New("MPD must have at least one Period element")
+	}
+
+	starts := make([]time.Duration, len(m.Periods))
+	durations := make([]time.Duration, len(m.Periods))
+	for i, p := range m.Periods {
+		switch {
+		case p.Start != nil:
+			starts[i] = p.Start.Duration
+		case i > 0:
+			starts[i] = starts[i-1] + durations[i-1]
+		}
+
+		switch {
+		case p.Duration != nil:
+			durations[i] = p.Duration.Duration
+		case i < len(m.Periods)-1 && m.Periods[i+1].Start != nil:
+			durations[i] = m.Periods[i+1].Start.Duration - starts[i]
+		case i == len(m.Periods)-1 && m.MediaPresDuration != nil:
+			durations[i] = m.MediaPresDuration.Duration - starts[i]
+		default:
+			return nil, nil, fmt.Errorf("unable to determine the duration of Period %d", i)
+		}
+	}
+	return starts, durations, nil
+}
+
+func (m *MPD) periodByID(id string) *Period {
+	for _, p := range m.Periods {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (m *MPD) uniquePeriodID(id string) string {
+	unique := id
+	for n := 2; m.periodByID(unique) != nil; n++ {
+		unique = fmt.Sprintf("%s-%d", id, n)
+	}
+	return unique
+}
+
+//periodSplitter moves the segments after offset from a Period into its copy.
+type periodSplitter struct {
+	offset time.Duration //split point relative to the Period start
+	end    time.Duration //duration of the Period being split
+}
+
+func (s *periodSplitter) split(first, second *Period) error {
+	if err := s.splitSegmentInfo(first.SegmentTemplate, second.SegmentTemplate, first.SegmentList, second.SegmentList, first.SegmentBase, second.SegmentBase); err != nil {
+		return err
+	}
+	for i, as := range first.AdaptationSets {
+		as2 := second.AdaptationSets[i]
+		if err := s.splitSegmentInfo(as.SegmentTemplate, as2.SegmentTemplate, as.SegmentList, as2.SegmentList, as.SegmentBase, as2.SegmentBase); err != nil {
+			return err
+		}
+		for j, rep := range as.Representations {
+			rep2 := as2.Representations[j]
+			if err := s.splitSegmentInfo(rep.SegmentTemplate, rep2.SegmentTemplate, rep.SegmentList, rep2.SegmentList, rep.SegmentBase, rep2.SegmentBase); err != nil {
+				return fmt.Errorf("Representation %s: %v", rep.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *periodSplitter) splitSegmentInfo(st, st2 *SegmentTemplate, sl, sl2 *SegmentList, sb, sb2 *SegmentBase) error {
+	if st != nil {
+		pto, skipped, _, err := s.splitSegments(st.Timescale, st.PresTimeOffset, st.Duration, st.SegmentTimeline, st2.SegmentTimeline)
+		if err != nil {
+			return err
+		}
+		st2.PresTimeOffset = pto
+		st2.setStartNumber(st.firstNumber() + skipped)
+	}
+
+	if sl != nil {
+		pto, skipped, kept, err := s.splitSegments(sl.Timescale, sl.PresTimeOffset, sl.Duration, sl.SegmentTimeline, sl2.SegmentTimeline)
+		if err != nil {
+			return err
+		}
+		sl2.PresTimeOffset = pto
+		sl2.setStartNumber(sl.firstNumber() + skipped)
+
+		//SegmentURLs are listed in the same order as the segments
+		if skipped > len(sl.SegmentURLs) {
+			skipped = len(sl.SegmentURLs)
+		}
+		if kept > len(sl.SegmentURLs) {
+			kept = len(sl.SegmentURLs)
+		}
+		sl2.SegmentURLs = append([]*SegmentURL(nil), sl.SegmentURLs[skipped:]...)
+		sl.SegmentURLs = sl.SegmentURLs[:kept]
+	}
+
+	if sb != nil {
+		sb2.PresTimeOffset = sb.PresTimeOffset + toTimescale(s.offset, sb.Timescale)
+	}
+	return nil
+}
+
+//splitSegments splits a SegmentTimeline, or checks a @duration based addressing can be split.
+//It returns the presentationTimeOffset of the second Period, the number of segments that aren't
+//part of the second Period and the number of segments kept in the first one.
+func (s *periodSplitter) splitSegments(timescale int, pto int64, duration int, timeline, timeline2 *SegmentTimeline) (int64, int, int, error) {
+	at := pto + toTimescale(s.offset, timescale)
+
+	if timeline != nil {
+		segments, err := timeline.Expand(int(pto + toTimescale(s.end, timescale)))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		//segments spanning the split point are kept in both Periods
+		var before, after Segments
+		for _, seg := range segments {
+			if int64(seg.T) < at {
+				before = append(before, seg)
+			}
+			if int64(seg.T+seg.D) > at {
+				after = append(after, &S{T: seg.T, D: seg.D})
+			}
+		}
+		timeline.Segments = before
+		timeline.Compact()
+		timeline2.Segments = after
+		timeline2.Compact()
+
+		return at, len(segments) - len(after), len(before), nil
+	}
+
+	if duration > 0 {
+		split := at - pto
+		if split%int64(duration) != 0 {
+			return 0, 0, 0, fmt.Errorf("split point %v isn't on a segment boundary", s.offset)
+		}
+		count := int(split / int64(duration))
+		return at, count, count, nil
+	}
+
+	return at, 0, 0, nil
+}
+
+//splitEvents moves the Period events at or after offset to the second Period.
+func splitEvents(first, second *Period, offset time.Duration) {
+	second.EventStream = nil
+	for _, es := range first.EventStream {
+		es2 := *es
+		es2.Event = nil
+
+		var events []*Event
+		at := toTimescale(offset, es.Timescale)
+		for _, e := range es.Event {
+			if e.PresTime >= at {
+				e2 := *e
+				e2.PresTime -= at
+				es2.Event = append(es2.Event, &e2)
+			} else {
+				events = append(events, e)
+			}
+		}
+		es.Event = events
+		second.EventStream = append(second.EventStream, &es2)
+	}
+}
+
+//continuous reports whether the media of every Representation of next continues where it ends in prev.
+func continuous(prev, next *Period) bool {
+	if len(prev.AdaptationSets) != len(next.AdaptationSets) || prev.Duration == nil {
+		return false
+	}
+
+	for i, as := range prev.AdaptationSets {
+		as2 := next.AdaptationSets[i]
+		if as.ID != as2.ID || len(as.Representations) != len(as2.Representations) {
+			return false
+		}
+		for j, rep := range as.Representations {
+			rep2 := as2.Representations[j]
+			if rep.ID != rep2.ID || rep.Codecs != rep2.Codecs {
+				return false
+			}
+
+			st := effectiveTemplate(prev, as, rep)
+			st2 := effectiveTemplate(next, as2, rep2)
+			if st == nil || st2 == nil || st.Timescale != st2.Timescale || st.Media != st2.Media {
+				return false
+			}
+			if st.PresTimeOffset+toTimescale(prev.Duration.Duration, st.Timescale) != st2.PresTimeOffset {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//sameProperties reports whether next has the BaseURLs, AssetIdentifier and unknown attributes and
+//elements of prev, which can't be kept for part of a flattened Period.
+func sameProperties(prev, next *Period) bool {
+	return reflect.DeepEqual(prev.BaseURL, next.BaseURL) && reflect.DeepEqual(prev.AssetIdentifier, next.AssetIdentifier) &&
+		reflect.DeepEqual(prev.ExtraAttrs, next.ExtraAttrs) && reflect.DeepEqual(prev.ExtraElements, next.ExtraElements)
+}
+
+//mergeEvents adds the events of next, starting offset after flat, to the EventStream of flat with the
+//same scheme, value and timescale, shifting their presentationTime by offset.
+func mergeEvents(flat, next *Period, offset time.Duration) {
+	for _, es := range next.EventStream {
+		var merged *EventStream
+		for _, fes := range flat.EventStream {
+			if fes.SchemeIDURI == es.SchemeIDURI && fes.Value == es.Value && fes.Timescale == es.Timescale {
+				merged = fes
+				break
+			}
+		}
+		if merged == nil {
+			c := *es
+			c.Event = nil
+			merged = &c
+			flat.EventStream = append(flat.EventStream, merged)
+		}
+
+		at := toTimescale(offset, es.Timescale)
+		for _, e := range es.Event {
+			e2 := *e
+			e2.PresTime += at
+			merged.Event = append(merged.Event, &e2)
+		}
+	}
+}
+
+//appendPeriod extends the segments of every Representation of flat with the ones of next.
+func appendPeriod(flat, next *Period) error {
+	extended := map[*SegmentTemplate]bool{}
+	for i, as := range flat.AdaptationSets {
+		as2 := next.AdaptationSets[i]
+		for j, rep := range as.Representations {
+			st := effectiveTemplate(flat, as, rep)
+			st2 := effectiveTemplate(next, as2, as2.Representations[j])
+
+			if st.SegmentTimeline == nil && st2.SegmentTimeline == nil {
+				//@duration based addressing continues when numbering does
+				if st.Duration != st2.Duration || st.Duration == 0 {
+					return ErrNotFlattenable
+				}
+				if st2.firstNumber()-st.firstNumber() != int(toTimescale(flat.Duration.Duration, st.Timescale)/int64(st.Duration)) {
+					return ErrNotFlattenable
+				}
+				continue
+			}
+			if st.SegmentTimeline == nil || st2.SegmentTimeline == nil {
+				return ErrNotFlattenable
+			}
+
+			//templates shared by several Representations are extended once
+			if extended[st] {
+				continue
+			}
+			extended[st] = true
+
+			segments, err := st.SegmentTimeline.Expand(0)
+			if err != nil {
+				return err
+			}
+			following, err := st2.SegmentTimeline.Expand(0)
+			if err != nil {
+				return err
+			}
+			spanning := len(segments) > 0 && len(following) > 0 && following[0].T == segments[len(segments)-1].T
+			//$Number$ templates continue when the first segment of next is numbered after the ones of flat
+			if strings.Contains(st.Media, "$Number") {
+				number := st.firstNumber() + len(segments)
+				if spanning {
+					number--
+				}
+				if st2.firstNumber() != number {
+					return ErrNotFlattenable
+				}
+			}
+			if spanning {
+				//a segment spanning the Period boundary is listed in both Periods
+				following = following[1:]
+			}
+			st.SegmentTimeline.Segments = append(segments, following...)
+			st.SegmentTimeline.Compact()
+		}
+	}
+	flat.Duration = &CustomDuration{Duration: flat.Duration.Duration + next.Duration.Duration}
+	return nil
+}
+
+//effectiveTemplate returns the most specific SegmentTemplate applying to a Representation.
+func effectiveTemplate(p *Period, as *AdaptationSet, rep *Representation) *SegmentTemplate {
+	switch {
+	case rep.SegmentTemplate != nil:
+		return rep.SegmentTemplate
+	case as.SegmentTemplate != nil:
+		return as.SegmentTemplate
+	}
+	return p.SegmentTemplate
+}
+
+//effectiveList returns the most specific SegmentList applying to a Representation.
+func effectiveList(p *Period, as *AdaptationSet, rep *Representation) *SegmentList {
+	switch {
+	case rep.SegmentList != nil:
+		return rep.SegmentList
+	case as.SegmentList != nil:
+		return as.SegmentList
+	}
+	return p.SegmentList
+}
+
+//startNumber returns the number of the first segment, which defaults to 1.
+func startNumber(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+//firstNumber returns the number of the first segment of the template, which is 0 if startNumber="0" was parsed.
+func (s *SegmentTemplate) firstNumber() int {
+	if s.zeroStartNumber && s.StartNumber == 0 {
+		return 0
+	}
+	return startNumber(s.StartNumber)
+}
+
+//setStartNumber sets the number of the first segment of the template, including 0.
+func (s *SegmentTemplate) setStartNumber(n int) {
+	s.StartNumber = n
+	s.zeroStartNumber = n == 0
+}
+
+//firstNumber returns the number of the first segment of the list, which is 0 if startNumber="0" was parsed.
+func (s *SegmentList) firstNumber() int {
+	if s.zeroStartNumber && s.StartNumber == 0 {
+		return 0
+	}
+	return startNumber(s.StartNumber)
+}
+
+//setStartNumber sets the number of the first segment of the list, including 0.
+func (s *SegmentList) setStartNumber(n int) {
+	s.StartNumber = n
+	s.zeroStartNumber = n == 0
+}
+
+//markContinuity signals every AdaptationSet of next continues the one with the same ID in prev.
+func markContinuity(prev, next *Period) {
+	for i, as := range next.AdaptationSets {
+		if as.ID == 0 {
+			//continuity is signalled by AdaptationSet ID, number them in both Periods
+			as.ID = i + 1
+			if i < len(prev.AdaptationSets) && prev.AdaptationSets[i].ID == 0 {
+				prev.AdaptationSets[i].ID = i + 1
+			}
+		}
+		as.SupplementalProperty = append(removeContinuity(as.SupplementalProperty),
+			&Descriptor{SchemeIDURI: PeriodContinuityScheme, Value: prev.ID})
+	}
+}
+
+func removeContinuity(descriptors []*Descriptor) []*Descriptor {
+	var kept []*Descriptor
+	for _, d := range descriptors {
+		if d.SchemeIDURI != PeriodContinuityScheme {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+//inheritBaseURL resolves the Period BaseURLs against the MPD BaseURLs it's being moved out of.
+func inheritBaseURL(p *Period, mpdBase []*BaseURL) error {
+	if len(mpdBase) == 0 {
+		return nil
+	}
+	if len(p.BaseURL) == 0 {
+		for _, b := range mpdBase {
+			c := *b
+			p.BaseURL = append(p.BaseURL, &c)
+		}
+		return nil
+	}
+
+	base, err := url.Parse(mpdBase[0].URL)
+	if err != nil {
+		return err
+	}
+	for i, b := range p.BaseURL {
+		ref, err := url.Parse(b.URL)
+		if err != nil {
+			return err
+		}
+		c := *b
+		c.URL = base.ResolveReference(ref).String()
+		p.BaseURL[i] = &c
+	}
+	return nil
+}
+
+//toTimescale converts d to timescale units. Whole seconds and the remainder are converted apart,
+//as d*timescale overflows past about 15 minutes at a timescale of 10000000.
+func toTimescale(d time.Duration, timescale int) int64 {
+	if timescale == 0 {
+		timescale = 1
+	}
+	ts := int64(timescale)
+	return int64(d/time.Second)*ts + int64(d%time.Second)*ts/int64(time.Second)
+}
+
+//clone copies the MPD and its Periods so they can be changed without affecting m.
+func (m *MPD) clone() *MPD {
+	c := *m
+	c.Periods = make(Periods, len(m.Periods))
+	for i, p := range m.Periods {
+		c.Periods[i] = p.clone()
+	}
+	c.BaseURL = append([]*BaseURL(nil), m.BaseURL...)
+	return &c
+}
+
+//clone copies the Period down to the segment information of its Representations.
+//Descriptors and other elements that aren't changed by the multi-period helpers are shared.
+func (p *Period) clone() *Period {
+	c := *p
+	c.BaseURL = append([]*BaseURL(nil), p.BaseURL...)
+	c.EventStream = make([]*EventStream, len(p.EventStream))
+	for i, es := range p.EventStream {
+		es2 := *es
+		es2.Event = append([]*Event(nil), es.Event...)
+		c.EventStream[i] = &es2
+	}
+	c.SegmentBase = p.SegmentBase.clone()
+	c.SegmentList = p.SegmentList.clone()
+	c.SegmentTemplate = p.SegmentTemplate.clone()
+
+	c.AdaptationSets = make(AdaptationSets, len(p.AdaptationSets))
+	for i, as := range p.AdaptationSets {
+		as2 := *as
+		as2.SupplementalProperty = append([]*Descriptor(nil), as.SupplementalProperty...)
+		as2.SegmentBase = as.SegmentBase.clone()
+		as2.SegmentList = as.SegmentList.clone()
+		as2.SegmentTemplate = as.SegmentTemplate.clone()
+
+		as2.Representations = make(Representations, len(as.Representations))
+		for j, rep := range as.Representations {
+			rep2 := *rep
+			rep2.SegmentBase = rep.SegmentBase.clone()
+			rep2.SegmentList = rep.SegmentList.clone()
+			rep2.SegmentTemplate = rep.SegmentTemplate.clone()
+			as2.Representations[j] = &rep2
+		}
+		c.AdaptationSets[i] = &as2
+	}
+	return &c
+}
+
+func (s *SegmentBase) clone() *SegmentBase {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func (s *SegmentList) clone() *SegmentList {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.SegmentURLs = append([]*SegmentURL(nil), s.SegmentURLs...)
+	c.SegmentTimeline = s.SegmentTimeline.clone()
+	return &c
+}
+
+func (s *SegmentTemplate) clone() *SegmentTemplate {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.SegmentTimeline = s.SegmentTimeline.clone()
+	return &c
+}
+
+func (st *SegmentTimeline) clone() *SegmentTimeline {
+	if st == nil {
+		return nil
+	}
+	c := &SegmentTimeline{Segments: make(Segments, len(st.Segments))}
+	for i, s := range st.Segments {
+		s2 := *s
+		c.Segments[i] = &s2
+	}
+	return c
+}
@@ -0,0 +1,267 @@
+package dash
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func getMultiPeriodMPD() *MPD {
+	mpd := NewMPD("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2)
+	mpd.MediaPresDuration = &CustomDuration{Duration: 20 * time.Second}
+	mpd.Periods = Periods{&Period{
+		ID: "main",
+		AdaptationSets: AdaptationSets{
+			&AdaptationSet{
+				ID: 1,
+				Representations: Representations{&Representation{
+					ID: "video", MimeType: "video/mp4", Codecs: "avc1.4d01f", Bandwidth: 980104,
+					SegmentTemplate: &SegmentTemplate{
+						Timescale:      12288,
+						PresTimeOffset: 1024,
+						Duration:       24576,
+						StartNumber:    1,
+						Media:          "video_$Number$.mp4"}},
+				},
+			},
+			&AdaptationSet{
+				ID: 2,
+				Representations: Representations{&Representation{
+					ID: "audio", MimeType: "audio/mp4", Codecs: "mp4a.40.2", Bandwidth: 64000,
+					SegmentTemplate: &SegmentTemplate{
+						Timescale: 48000,
+						Media:     "audio_$Time$.mp4",
+						SegmentTimeline: &SegmentTimeline{Segments: Segments{
+							&S{T: 0, D: 95232, R: 9}}}}},
+				},
+			},
+		},
+		EventStream: []*EventStream{&EventStream{SchemeIDURI: "urn:uuid:XYZY", Timescale: 1,
+			Event: []*Event{&Event{PresTime: 2, ID: 1}, &Event{PresTime: 14, ID: 2}}}},
+	}}
+	return mpd
+}
+
+func TestSplit(t *testing.T) {
+	mpd := getMultiPeriodMPD()
+
+	if _, err := Split(mpd, 9*time.Second); err == nil {
+		t.Error("Expected error when splitting off a segment boundary")
+	}
+	dynamic := getMultiPeriodMPD()
+	dynamic.Type = "dynamic"
+	if _, err := Split(dynamic, 10*time.Second); err == nil {
+		t.Error("Expected error when splitting a dynamic MPD")
+	}
+
+	split, err := Split(mpd, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mpd.Periods) != 1 || mpd.Periods[0].Duration != nil {
+		t.Error("Expected Split to not modify the original MPD")
+	}
+	if len(split.Periods) != 2 {
+		t.Fatalf("Expected 2 Periods, but got %d", len(split.Periods))
+	}
+
+	first, second := split.Periods[0], split.Periods[1]
+	if first.Duration.Duration != 10*time.Second || second.Start.Duration != 10*time.Second || second.Duration.Duration != 10*time.Second {
+		t.Errorf("Expected Periods of 10s, but got %v and %v starting at %v", first.Duration, second.Duration, second.Start)
+	}
+
+	video := second.AdaptationSets[0].Representations[0].SegmentTemplate
+	if video.StartNumber != 6 || video.PresTimeOffset != 1024+122880 {
+		t.Errorf("Expected startNumber 6 and presentationTimeOffset 123904, but got %d and %d", video.StartNumber, video.PresTimeOffset)
+	}
+
+	//the segment from 9.92s to 11.904s spans both Periods
+	audio := first.AdaptationSets[1].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 0, D: 95232, R: 5}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) {
+		t.Errorf("Expected first audio timeline %v, but got %v", expect, audio.SegmentTimeline.Segments)
+	}
+	audio = second.AdaptationSets[1].Representations[0].SegmentTemplate
+	expect = Segments{&S{T: 476160, D: 95232, R: 4}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) || audio.PresTimeOffset != 480000 {
+		t.Errorf("Expected second audio timeline %v, but got %v", expect, audio.SegmentTimeline.Segments)
+	}
+
+	for _, as := range second.AdaptationSets {
+		if len(as.SupplementalProperty) != 1 || as.SupplementalProperty[0].SchemeIDURI != PeriodContinuityScheme || as.SupplementalProperty[0].Value != "main" {
+			t.Errorf("Expected AdaptationSet %d to have a period-continuity descriptor", as.ID)
+		}
+	}
+
+	if len(first.EventStream[0].Event) != 1 || second.EventStream[0].Event[0].PresTime != 4 {
+		t.Errorf("Expected events to be split between Periods")
+	}
+
+	if _, err := split.Encode(); err != nil {
+		t.Fatal(err)
+	}
+
+	flat, err := Flatten(split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flat.Periods) != 1 || flat.Periods[0].Duration.Duration != 20*time.Second {
+		t.Fatalf("Expected a single Period of 20s, but got %d Periods", len(flat.Periods))
+	}
+	audio = flat.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate
+	expect = Segments{&S{T: 0, D: 95232, R: 9}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) {
+		t.Errorf("Expected flattened audio timeline %v, but got %v", expect, audio.SegmentTimeline.Segments)
+	}
+	if len(flat.Periods[0].AdaptationSets[0].SupplementalProperty) != 0 {
+		t.Error("Expected period-continuity descriptors to be removed")
+	}
+	events := flat.Periods[0].EventStream[0].Event
+	if len(events) != 2 || events[0].PresTime != 2 || events[1].PresTime != 14 || events[1].ID != 2 {
+		t.Errorf("Expected events of both Periods at their original presentationTime, but got %v", events)
+	}
+}
+
+func getNumberedPeriod(id string, startNumber int, pto int64) *Period {
+	return &Period{
+		ID:       id,
+		Duration: &CustomDuration{Duration: 4 * time.Second},
+		AdaptationSets: AdaptationSets{&AdaptationSet{
+			ID: 1,
+			Representations: Representations{&Representation{
+				ID: "video", MimeType: "video/mp4", Codecs: "avc1.4d01f", Bandwidth: 980104,
+				SegmentTemplate: &SegmentTemplate{
+					Timescale:       1,
+					PresTimeOffset:  pto,
+					StartNumber:     startNumber,
+					Media:           "$Number$.m4s",
+					SegmentTimeline: &SegmentTimeline{Segments: Segments{&S{T: int(pto), D: 2, R: 1}}}}},
+			},
+		}},
+		EventStream: []*EventStream{&EventStream{SchemeIDURI: "urn:uuid:XYZY", Timescale: 1,
+			Event: []*Event{&Event{PresTime: 1, ID: startNumber}}}},
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	mpd := NewMPD("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2)
+	mpd.Periods = Periods{getNumberedPeriod("a", 1, 0), getNumberedPeriod("b", 1, 4)}
+	if _, err := Flatten(mpd); err != ErrNotFlattenable {
+		t.Errorf("Expected ErrNotFlattenable when $Number$ restarts, but got %v", err)
+	}
+
+	mpd.Periods = Periods{getNumberedPeriod("a", 1, 0), getNumberedPeriod("b", 3, 4)}
+	mpd.Periods[1].BaseURL = []*BaseURL{&BaseURL{URL: "b/"}}
+	if _, err := Flatten(mpd); err != ErrNotFlattenable {
+		t.Errorf("Expected ErrNotFlattenable when BaseURLs differ, but got %v", err)
+	}
+
+	mpd.Periods[1].BaseURL = nil
+	flat, err := Flatten(mpd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := flat.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 0, D: 2, R: 3}}
+	if !reflect.DeepEqual(st.SegmentTimeline.Segments, expect) || st.StartNumber != 1 {
+		t.Errorf("Expected timeline %v from startNumber 1, but got %v from %d", expect, st.SegmentTimeline.Segments, st.StartNumber)
+	}
+	events := flat.Periods[0].EventStream[0].Event
+	if len(events) != 2 || events[1].ID != 3 || events[1].PresTime != 5 {
+		t.Errorf("Expected the event of Period b at 5, but got %v", events)
+	}
+}
+
+func TestSplitBroadcastTimescale(t *testing.T) {
+	mpd := getMultiPeriodMPD()
+	mpd.MediaPresDuration = &CustomDuration{Duration: 3 * time.Hour}
+	video := mpd.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	video.Timescale, video.PresTimeOffset, video.Duration = 10000000, 0, 20000000
+	audio := mpd.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate
+	audio.Timescale = 90000
+	audio.SegmentTimeline.Segments = Segments{&S{T: 0, D: 180000, R: 5399}}
+
+	split, err := Split(mpd, 90*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	video = split.Periods[1].AdaptationSets[0].Representations[0].SegmentTemplate
+	if video.StartNumber != 2701 || video.PresTimeOffset != 54000000000 {
+		t.Errorf("Expected startNumber 2701 and presentationTimeOffset 54000000000, but got %d and %d", video.StartNumber, video.PresTimeOffset)
+	}
+	audio = split.Periods[1].AdaptationSets[1].Representations[0].SegmentTemplate
+	expect := Segments{&S{T: 486000000, D: 180000, R: 2699}}
+	if !reflect.DeepEqual(audio.SegmentTimeline.Segments, expect) || audio.PresTimeOffset != 486000000 {
+		t.Errorf("Expected second audio timeline %v, but got %v", expect, audio.SegmentTimeline.Segments)
+	}
+	if err := split.ValidateTimelines(); err != nil {
+		t.Errorf("Expected split Periods to be covered by their timelines, but got %s", err)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	content := getMultiPeriodMPD()
+	content.BaseURL = []*BaseURL{&BaseURL{URL: "http://cdn.example.com/content/"}}
+	ad := getMultiPeriodMPD()
+	ad.Periods[0].ID = "ad"
+	ad.Periods[0].BaseURL = []*BaseURL{&BaseURL{URL: "ads/"}}
+	ad.BaseURL = []*BaseURL{&BaseURL{URL: "http://ads.example.com/"}}
+	ad.MediaPresDuration = &CustomDuration{Duration: 10 * time.Second}
+
+	if _, err := Join(nil, content); err == nil {
+		t.Error("Expected error when joining a nil MPD")
+	}
+
+	joined, err := Join(content, ad, getMultiPeriodMPD())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(joined.Periods) != 3 {
+		t.Fatalf("Expected 3 Periods, but got %d", len(joined.Periods))
+	}
+	if joined.MediaPresDuration.Duration != 50*time.Second {
+		t.Errorf("Expected mediaPresentationDuration of 50s, but got %v", joined.MediaPresDuration.Duration)
+	}
+
+	ids := []string{joined.Periods[0].ID, joined.Periods[1].ID, joined.Periods[2].ID}
+	if !reflect.DeepEqual(ids, []string{"main", "ad", "2-main"}) {
+		t.Errorf("Expected unique Period IDs, but got %v", ids)
+	}
+	if joined.Periods[2].Start.Duration != 30*time.Second {
+		t.Errorf("Expected third Period to start at 30s, but got %v", joined.Periods[2].Start.Duration)
+	}
+	if len(joined.BaseURL) != 0 || joined.Periods[1].BaseURL[0].URL != "http://ads.example.com/ads/" {
+		t.Errorf("Expected MPD BaseURL to be resolved into the Period, but got %v", joined.Periods[1].BaseURL[0].URL)
+	}
+
+	//the media of the joined MPDs restarts, so the Periods aren't continuous
+	if len(joined.Periods[1].AdaptationSets[0].SupplementalProperty) != 0 {
+		t.Error("Expected no period-continuity descriptor between discontinuous Periods")
+	}
+	if _, err := Flatten(joined); err != ErrNotFlattenable {
+		t.Errorf("Expected ErrNotFlattenable, but got %v", err)
+	}
+
+	split, err := Split(getMultiPeriodMPD(), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := split.clone(), split.clone()
+	first.Periods = first.Periods[:1]
+	first.MediaPresDuration = &CustomDuration{Duration: 10 * time.Second}
+	second.Periods = second.Periods[1:]
+	second.Periods[0].Start = nil
+	second.Periods[0].AdaptationSets[0].SupplementalProperty = nil
+	second.Periods[0].AdaptationSets[1].SupplementalProperty = nil
+
+	joined, err = Join(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := joined.Periods[1].AdaptationSets[1].SupplementalProperty; len(d) != 1 || d[0].Value != "main" {
+		t.Errorf("Expected continuous Periods to be signalled, but got %v", d)
+	}
+}
@@ -0,0 +1,160 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ingest/manifest/dash"
+)
+
+//ErrMPDMismatch is returned when a Patch doesn't apply to an MPD, either because the MPD ID
+//is different or because the MPD wasn't published at the Patch original publish time.
+var ErrMPDMismatch = errors.New("Patch doesn't apply to this MPD")
+
+//Apply applies the Patch operations, in order, to m and returns the resulting MPD.
+//m isn't modified.
+func Apply(p *Patch, m *dash.MPD) (*dash.MPD, error) {
+	if m == nil || p == nil {
+		return nil, errors.New("Patch and MPD must not be nil")
+	}
+	if m.ID != p.MPDID || m.PublishTime == nil || !m.PublishTime.Time.Equal(p.OriginalPublishTime) {
+		return nil, ErrMPDMismatch
+	}
+
+	root, err := mpdTree(m)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, o := range p.Operations {
+		if root, err = o.apply(root); err != nil {
+			return nil, fmt.Errorf("Patch operation %d: %v", i, err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	root.write(buf)
+
+	patched := &dash.MPD{}
+	if err := patched.Parse(buf); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+//apply applies the operation to the tree and returns its root, which changes
+//when the root element is replaced.
+func (o *Operation) apply(root *node) (*node, error) {
+	target, parent, attr, err := selectNode(root, o.Sel)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case OpAdd:
+		if attr != "" {
+			return nil, fmt.Errorf("add selector %s must select an element", o.Sel)
+		}
+		if strings.HasPrefix(o.Type, "@") {
+			name := o.Type[1:]
+			if _, ok := target.attr(name); ok {
+				return nil, fmt.Errorf("attribute %s already exists at %s", name, o.Sel)
+			}
+			target.setAttr(name, o.Content)
+			return root, nil
+		}
+
+		nodes, err := parseNodes(strings.NewReader(o.Content))
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("add at %s must have content", o.Sel)
+		}
+
+		switch o.Pos {
+		case "":
+			target.Children = append(target.Children, nodes...)
+		case PosPrepend:
+			target.Children = insertNodes(target.Children, 0, nodes)
+		case PosBefore, PosAfter:
+			if parent == nil {
+				return nil, fmt.Errorf("can't add siblings to the root element")
+			}
+			i := indexOf(parent.Children, target)
+			if o.Pos == PosAfter {
+				i++
+			}
+			parent.Children = insertNodes(parent.Children, i, nodes)
+		default:
+			return nil, fmt.Errorf("unknown add position %s", o.Pos)
+		}
+
+	case OpReplace:
+		if attr != "" {
+			if _, ok := target.attr(attr); !ok {
+				return nil, fmt.Errorf("attribute %s doesn't exist at %s", attr, o.Sel)
+			}
+			target.setAttr(attr, o.Content)
+			return root, nil
+		}
+
+		nodes, err := parseNodes(strings.NewReader(o.Content))
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) != 1 {
+			return nil, fmt.Errorf("replace at %s must have a single element, but got %d", o.Sel, len(nodes))
+		}
+		if parent == nil {
+			return nodes[0], nil
+		}
+		parent.Children[indexOf(parent.Children, target)] = nodes[0]
+
+	case OpRemove:
+		if attr != "" {
+			if !target.removeAttr(attr) {
+				return nil, fmt.Errorf("attribute %s doesn't exist at %s", attr, o.Sel)
+			}
+			return root, nil
+		}
+		if parent == nil {
+			return nil, errors.New("can't remove the root element")
+		}
+		i := indexOf(parent.Children, target)
+		parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+
+	default:
+		return nil, fmt.Errorf("unknown operation %s", o.Op)
+	}
+	return root, nil
+}
+
+//mpdTree marshals the MPD into a generic XML tree.
+func mpdTree(m *dash.MPD) (*node, error) {
+	output, err := xml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(bytes.NewReader(output))
+}
+
+func indexOf(nodes []*node, n *node) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertNodes(nodes []*node, i int, add []*node) []*node {
+	result := make([]*node, 0, len(nodes)+len(add))
+	result = append(result, nodes[:i]...)
+	result = append(result, add...)
+	return append(result, nodes[i:]...)
+}
@@ -0,0 +1,252 @@
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/ingest/manifest/dash"
+)
+
+//Diff computes the Patch that updates MPD a to MPD b. Both MPDs must have the same ID
+//and a PublishTime, usually they are consecutive revisions of a dynamic MPD.
+//
+//Elements are matched by their id attribute when they have one, by name when they are the only
+//element with that name, and by content otherwise, so segments sliding out of and into a
+//SegmentTimeline produce remove and add operations instead of replacing every S element.
+func Diff(a, b *dash.MPD) (*Patch, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("MPDs must not be nil")
+	}
+	if a.ID == "" || a.ID != b.ID {
+		return nil, errors.New("MPDs must have the same ID")
+	}
+	if a.PublishTime == nil || b.PublishTime == nil {
+		return nil, errors.New("MPDs must have PublishTime")
+	}
+
+	ta, err := mpdTree(a)
+	if err != nil {
+		return nil, err
+	}
+	tb, err := mpdTree(b)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Patch{MPDID: a.ID, OriginalPublishTime: a.PublishTime.Time, PublishTime: b.PublishTime.Time}
+	p.Operations = diffNode("/"+ta.Name, ta, tb, nil)
+	return p, nil
+}
+
+//diffNode appends the operations turning element a, selected by path, into element b.
+func diffNode(path string, a, b *node, ops []*Operation) []*Operation {
+	if a.Text != b.Text {
+		return append(ops, &Operation{Op: OpReplace, Sel: path, Content: b.String()})
+	}
+
+	for _, attr := range a.Attr {
+		name := attr.Name.Local
+		if isNamespaceDecl(name) {
+			continue
+		}
+		if v, ok := b.attr(name); !ok {
+			ops = append(ops, &Operation{Op: OpRemove, Sel: path + "/@" + name})
+		} else if v != attr.Value {
+			ops = append(ops, &Operation{Op: OpReplace, Sel: path + "/@" + name, Content: v})
+		}
+	}
+	for _, attr := range b.Attr {
+		name := attr.Name.Local
+		if isNamespaceDecl(name) {
+			continue
+		}
+		if _, ok := a.attr(name); !ok {
+			ops = append(ops, &Operation{Op: OpAdd, Sel: path, Type: "@" + name, Content: attr.Value})
+		}
+	}
+
+	return diffChildren(path, a.Children, b.Children, ops)
+}
+
+//diffChildren appends the operations turning the children of the element selected by path
+//from one list into the other. Selectors are computed against the children as they are when each
+//operation is applied: removals come first, then changes to matched elements, then additions.
+func diffChildren(path string, from, to []*node, ops []*Operation) []*Operation {
+	pairs := matchNodes(from, to)
+
+	matchedFrom := make(map[int]bool, len(pairs))
+	matchedTo := make(map[int]*node, len(pairs))
+	for _, pr := range pairs {
+		matchedFrom[pr[0]] = true
+		matchedTo[pr[1]] = from[pr[0]]
+	}
+
+	cur := append([]*node(nil), from...)
+	for i := len(from) - 1; i >= 0; i-- {
+		if !matchedFrom[i] {
+			ops = append(ops, &Operation{Op: OpRemove, Sel: path + "/" + stepOf(cur, from[i]).String()})
+			cur = append(cur[:i], cur[i+1:]...)
+		}
+	}
+
+	for _, pr := range pairs {
+		a, b := from[pr[0]], to[pr[1]]
+		if !a.equal(b) {
+			ops = diffNode(path+"/"+stepOf(cur, a).String(), a, b, ops)
+		}
+	}
+
+	//cur holds the matched elements in the order of to, runs of added elements are inserted
+	//after the element preceding them
+	for j := 0; j < len(to); {
+		if matchedTo[j] != nil {
+			j++
+			continue
+		}
+		start := j
+		for j < len(to) && matchedTo[j] == nil {
+			j++
+		}
+		run := to[start:j]
+
+		buf := new(bytes.Buffer)
+		for _, n := range run {
+			n.write(buf)
+		}
+		o := &Operation{Op: OpAdd, Sel: path, Content: buf.String()}
+
+		i := 0
+		if start > 0 {
+			prev := matchedTo[start-1]
+			i = indexOf(cur, prev) + 1
+			if i < len(cur) {
+				o.Sel, o.Pos = path+"/"+stepOf(cur, prev).String(), PosAfter
+			}
+		} else if len(cur) > 0 {
+			o.Pos = PosPrepend
+		}
+		ops = append(ops, o)
+		cur = insertNodes(cur, i, run)
+		for k, n := range run {
+			matchedTo[start+k] = n
+		}
+	}
+	return ops
+}
+
+//matchNodes pairs the elements of from and to that are the same element in both revisions,
+//returning the indexes of each pair in order.
+func matchNodes(from, to []*node) [][2]int {
+	fromCount, toCount := nameCount(from), nameCount(to)
+	same := func(a, b *node) bool {
+		if a.Name != b.Name {
+			return false
+		}
+		aid, aok := a.attr("id")
+		bid, bok := b.attr("id")
+		if aok || bok {
+			return aok && bok && aid == bid
+		}
+		if fromCount[a.Name] == 1 && toCount[b.Name] == 1 {
+			return true
+		}
+		return a.equal(b)
+	}
+
+	//longest common subsequence of same elements
+	n, m := len(from), len(to)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case same(from[i], to[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	//gapFrom and gapTo are the first unmatched indexes since the previous pair
+	gapFrom, gapTo := 0, 0
+	pairGap := func(endFrom, endTo int) {
+		//elements without id changed between two matched elements are paired by name,
+		//so they are patched instead of removed and added again
+		j := gapTo
+		for i := gapFrom; i < endFrom && j < endTo; i++ {
+			if _, ok := from[i].attr("id"); ok {
+				continue
+			}
+			for k := j; k < endTo; k++ {
+				if _, ok := to[k].attr("id"); !ok && from[i].Name == to[k].Name {
+					pairs = append(pairs, [2]int{i, k})
+					j = k + 1
+					break
+				}
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case same(from[i], to[j]):
+			pairGap(i, j)
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+			gapFrom, gapTo = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	pairGap(n, m)
+	return pairs
+}
+
+//stepOf returns the location step selecting n among its siblings.
+func stepOf(siblings []*node, n *node) step {
+	s := step{Name: n.Name}
+	var count, position, sameID int
+	id, hasID := n.attr("id")
+	for _, c := range siblings {
+		if c.Name != n.Name {
+			continue
+		}
+		count++
+		if c == n {
+			position = count
+		}
+		if v, ok := c.attr("id"); hasID && ok && v == id {
+			sameID++
+		}
+	}
+
+	switch {
+	case hasID && sameID == 1:
+		s.Predicates = []predicate{{Attr: "id", Value: id}}
+	case count > 1:
+		s.Predicates = []predicate{{Position: position}}
+	}
+	return s
+}
+
+func nameCount(nodes []*node) map[string]int {
+	count := make(map[string]int)
+	for _, n := range nodes {
+		count[n.Name]++
+	}
+	return count
+}
+
+func isNamespaceDecl(name string) bool {
+	return name == "xmlns" || strings.HasPrefix(name, "xmlns:")
+}
@@ -0,0 +1,198 @@
+//Package patch computes and applies MPD Patch documents, as defined by ISO/IEC 23009-1 Amd. 3,
+//so clients of a live presentation can fetch only the changes between MPD revisions instead
+//of the full MPD on every refresh.
+//
+//A Patch is a list of add, replace and remove operations (RFC 5261) whose selectors are a
+//subset of XPath: absolute paths of elements with position and attribute predicates.
+//Ex: /MPD/Period[@id='1']/AdaptationSet[@id='2']/SegmentTemplate/SegmentTimeline/S[3]
+package patch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ingest/manifest"
+)
+
+const (
+	//patchNS is the namespace of MPD Patch documents.
+	patchNS = "urn:mpeg:dash:schema:mpd-patch:2020"
+	//opsNS is the namespace of RFC 5261 patch operations.
+	opsNS = "urn:ietf:params:xml:schema:patch-ops"
+)
+
+//Patch operations.
+const (
+	OpAdd     = "add"
+	OpReplace = "replace"
+	OpRemove  = "remove"
+)
+
+//Positions of an add operation relative to the selected element.
+//An add operation without position appends to the selected element's children.
+const (
+	PosPrepend = "prepend"
+	PosBefore  = "before"
+	PosAfter   = "after"
+)
+
+//Patch represents an MPD Patch document. It applies to the MPD with ID MPDID
+//published at OriginalPublishTime and results in the MPD published at PublishTime.
+type Patch struct {
+	MPDID               string
+	OriginalPublishTime time.Time
+	PublishTime         time.Time
+	Operations          []*Operation
+}
+
+//Operation is an add, replace or remove operation.
+type Operation struct {
+	Op   string //Required. Possible Values: add, replace, remove
+	Sel  string //Required. Selects an element, or an attribute when ending with /@name.
+	Pos  string //Optional for add. Possible Values: prepend, before, after
+	Type string //Optional for add. "@name" adds an attribute to the selected element.
+	//Content is the XML content added or replacing the selected element,
+	//or the value of the attribute added or replaced.
+	Content string
+}
+
+//isAttr reports whether the operation applies to an attribute instead of elements.
+func (o *Operation) isAttr() bool {
+	return strings.HasPrefix(o.Type, "@") || strings.Contains(o.Sel, "/@")
+}
+
+//Parse decodes an MPD Patch document.
+func (p *Patch) Parse(reader io.Reader) error {
+	root, err := parseTree(reader)
+	if err != nil {
+		return err
+	}
+	if localName(root.Name) != "Patch" {
+		return fmt.Errorf("expected Patch root element, but got %s", root.Name)
+	}
+
+	p.MPDID, _ = root.attr("mpdId")
+	if v, ok := root.attr("originalPublishTime"); ok {
+		if p.OriginalPublishTime, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return err
+		}
+	}
+	if v, ok := root.attr("publishTime"); ok {
+		if p.PublishTime, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return err
+		}
+	}
+
+	p.Operations = nil
+	for _, n := range root.Children {
+		o := &Operation{Op: localName(n.Name)}
+		o.Sel, _ = n.attr("sel")
+		o.Pos, _ = n.attr("pos")
+		o.Type, _ = n.attr("type")
+		if o.isAttr() {
+			o.Content = n.Text
+		} else {
+			buf := new(bytes.Buffer)
+			for _, c := range n.Children {
+				c.write(buf)
+			}
+			o.Content = buf.String()
+		}
+		p.Operations = append(p.Operations, o)
+	}
+
+	return p.validate()
+}
+
+//Encode marshals a Patch structure into an MPD Patch document.
+func (p *Patch) Encode() (io.Reader, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	root := &node{Name: "Patch"}
+	root.setAttr("xmlns", patchNS)
+	root.setAttr("xmlns:p", opsNS)
+	root.setAttr("mpdId", p.MPDID)
+	root.setAttr("originalPublishTime", p.OriginalPublishTime.Format(time.RFC3339Nano))
+	root.setAttr("publishTime", p.PublishTime.Format(time.RFC3339Nano))
+
+	out := new(bytes.Buffer)
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	root.writeStart(out, false)
+	out.WriteString("\n")
+	for _, o := range p.Operations {
+		op := &node{Name: "p:" + o.Op}
+		op.setAttr("sel", o.Sel)
+		if o.Pos != "" {
+			op.setAttr("pos", o.Pos)
+		}
+		if o.Type != "" {
+			op.setAttr("type", o.Type)
+		}
+
+		out.WriteString(" ")
+		switch {
+		case o.isAttr():
+			op.Text = o.Content
+			op.write(out)
+		case o.Content == "":
+			op.writeStart(out, true)
+		default:
+			//element content is already XML
+			op.writeStart(out, false)
+			out.WriteString(o.Content + "</" + op.Name + ">")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("</Patch>\n")
+
+	return bytes.NewReader(out.Bytes()), nil
+}
+
+func (p *Patch) validate() error {
+	buf := manifest.NewBufWrapper()
+	if p.MPDID == "" {
+		buf.WriteString("Patch field MPDID is required.\n")
+	}
+	if p.OriginalPublishTime.IsZero() || p.PublishTime.IsZero() {
+		buf.WriteString("Patch fields OriginalPublishTime and PublishTime are required.\n")
+	}
+
+	for _, o := range p.Operations {
+		if o.Sel == "" {
+			buf.WriteString("Operation field Sel is required.\n")
+		}
+		switch o.Op {
+		case OpAdd:
+			if o.Pos != "" && o.Pos != PosPrepend && o.Pos != PosBefore && o.Pos != PosAfter {
+				buf.WriteString("Possible values for Operation field Pos are 'prepend', 'before' and 'after'.\n")
+			}
+			if o.Type != "" && !strings.HasPrefix(o.Type, "@") {
+				buf.WriteString("Operation field Type must select an attribute.\n")
+			}
+		case OpReplace, OpRemove:
+			if o.Pos != "" || o.Type != "" {
+				buf.WriteString(fmt.Sprintf("Operation fields Pos and Type must not be present when Op = '%s'.\n", o.Op))
+			}
+		default:
+			buf.WriteString("Possible values for Operation field Op are 'add', 'replace' and 'remove'.\n")
+		}
+	}
+
+	if buf.Buf.String() != "" {
+		return errors.New(buf.Buf.String())
+	}
+	return buf.Err
+}
+
+func localName(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
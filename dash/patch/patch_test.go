@@ -0,0 +1,307 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+)
+
+var ast = time.Date(2016, 7, 13, 17, 0, 0, 0, time.UTC)
+
+func getLive() *dash.Live {
+	live := dash.NewLive("urn:mpeg:dash:profile:isoff-live:2011", time.Second*2, ast)
+	live.ID = "live"
+	live.SetTimeShiftBufferDepth(30 * time.Second)
+	live.PatchLocation = []*dash.PatchLocation{&dash.PatchLocation{URL: "patch.mpp", TTL: 60}}
+	live.AddPeriod(&dash.Period{
+		ID:    "p0",
+		Start: &dash.CustomDuration{Duration: 0},
+		AdaptationSets: dash.AdaptationSets{
+			&dash.AdaptationSet{
+				ID: 1,
+				Representations: dash.Representations{&dash.Representation{
+					ID: "video", MimeType: "video/mp4", Codecs: "avc1.4d01f", Bandwidth: 980104,
+					SegmentTemplate: &dash.SegmentTemplate{Timescale: 1000, Media: "video_$Time$.mp4"}},
+				},
+			},
+			&dash.AdaptationSet{
+				ID: 2,
+				Representations: dash.Representations{&dash.Representation{
+					ID: "audio", MimeType: "audio/mp4", Codecs: "mp4a.40.2", Bandwidth: 64000,
+					SegmentTemplate: &dash.SegmentTemplate{Timescale: 1000, Media: "audio_$Time$.mp4"}},
+				},
+			},
+		}})
+	return live
+}
+
+//addSegments adds segments to the video and audio Representations of Period p0 until end.
+//Audio segments alternate durations, so the audio SegmentTimeline has a S element per segment.
+func addSegments(live *dash.Live, start, end int) {
+	for t := start; t < end; t += 2000 {
+		live.AddSegment("p0", "video", t, 2000)
+	}
+	for t := start; t < end; t += 2000 {
+		live.AddSegment("p0", "audio", t, 1990)
+		live.AddSegment("p0", "audio", t+1990, 10)
+	}
+}
+
+//snapshot returns a copy of the MPD, with its SegmentTimelines compacted so S elements
+//only have t where there's a gap.
+func snapshot(t *testing.T, m *dash.MPD) *dash.MPD {
+	r, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dash.MPD{}
+	if err := s.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range s.Periods {
+		for _, as := range p.AdaptationSets {
+			for _, rep := range as.Representations {
+				if rep.SegmentTemplate != nil {
+					rep.SegmentTemplate.SegmentTimeline.Compact()
+				}
+			}
+		}
+	}
+	return s
+}
+
+func roundTrip(t *testing.T, a, b *dash.MPD) *Patch {
+	p, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the patch goes through its XML document before being applied
+	r, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := new(bytes.Buffer)
+	doc.ReadFrom(r)
+	parsed := &Patch{}
+	if err := parsed.Parse(bytes.NewReader(doc.Bytes())); err != nil {
+		t.Fatalf("%v\n%s", err, doc)
+	}
+
+	patched, err := Apply(parsed, a)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, doc)
+	}
+	if !reflect.DeepEqual(patched, snapshot(t, b)) {
+		t.Errorf("Expected patched MPD to be equal to the new revision, patch:\n%s", doc)
+	}
+	return p
+}
+
+func TestLiveRoundTrip(t *testing.T) {
+	live := getLive()
+	addSegments(live, 0, 40000)
+	live.Update(ast.Add(40 * time.Second))
+	a := snapshot(t, live.MPD)
+
+	//segments slide out of the window and new ones are appended
+	addSegments(live, 40000, 46000)
+	live.Update(ast.Add(44 * time.Second))
+	b := snapshot(t, live.MPD)
+
+	p := roundTrip(t, a, b)
+	if !p.OriginalPublishTime.Equal(ast.Add(40*time.Second)) || !p.PublishTime.Equal(ast.Add(44*time.Second)) {
+		t.Errorf("Expected Patch from %v to %v, but got %v to %v", ast.Add(40*time.Second), ast.Add(44*time.Second), p.OriginalPublishTime, p.PublishTime)
+	}
+
+	//the window slides by 2 segments and grows by one, only the start time of the timelines
+	//changes and the new segments are appended
+	timeline := "/MPD/Period[@id='p0']/AdaptationSet[@id='%d']/Representation[@id='%s']/SegmentTemplate/SegmentTimeline"
+	expect := []*Operation{
+		&Operation{Op: OpReplace, Sel: "/MPD/@publishTime", Content: "2016-07-13T17:00:44Z"},
+		&Operation{Op: OpReplace, Sel: fmt.Sprintf(timeline, 1, "video") + "/S/@t", Content: "14000"},
+		&Operation{Op: OpReplace, Sel: fmt.Sprintf(timeline, 1, "video") + "/S/@r", Content: "15"},
+		&Operation{Op: OpReplace, Sel: fmt.Sprintf(timeline, 2, "audio") + "/S[1]/@t", Content: "14000"},
+		&Operation{Op: OpAdd, Sel: fmt.Sprintf(timeline, 2, "audio"), Content: `<S d="1990"/><S d="10"/>`},
+	}
+	if !reflect.DeepEqual(p.Operations, expect) {
+		t.Errorf("Expected %d operations, but got %d", len(expect), len(p.Operations))
+		for _, o := range p.Operations {
+			t.Logf("%s %s %s", o.Op, o.Sel, o.Content)
+		}
+	}
+
+	//a new Period starts, the first one leaves the window
+	live.AddPeriod(&dash.Period{
+		ID:    "p1",
+		Start: &dash.CustomDuration{Duration: 46 * time.Second},
+		AdaptationSets: dash.AdaptationSets{
+			&dash.AdaptationSet{
+				ID: 1,
+				Representations: dash.Representations{&dash.Representation{
+					ID: "video", MimeType: "video/mp4", Codecs: "avc1.4d01f", Bandwidth: 980104,
+					SegmentTemplate: &dash.SegmentTemplate{Timescale: 1000, Media: "p1_video_$Time$.mp4"}},
+				},
+			},
+		}})
+	for t := 0; t < 40000; t += 2000 {
+		live.AddSegment("p1", "video", t, 2000)
+	}
+	live.Update(ast.Add(86 * time.Second))
+	c := snapshot(t, live.MPD)
+	if len(c.Periods) != 1 {
+		t.Fatalf("Expected Period p0 to leave the window, but got %d Periods", len(c.Periods))
+	}
+	roundTrip(t, b, c)
+}
+
+func TestRoundTrip(t *testing.T) {
+	f, err := os.Open("../testdata/dynamic.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(f)
+
+	tests := []struct {
+		Case   string
+		Update func(m *dash.MPD)
+	}{
+		{"no changes", func(m *dash.MPD) {}},
+		{"attributes", func(m *dash.MPD) {
+			m.SuggestedPresDelay = nil
+			m.TimeShiftBuffer = &dash.CustomDuration{Duration: 60 * time.Second}
+			m.MaxSegmentDuration = &dash.CustomDuration{Duration: 5 * time.Second}
+		}},
+		{"text", func(m *dash.MPD) {
+			m.Location[0] = "http://example.com/live.mpd?a=1&b=2"
+			m.ProgramInformation[0].Title = "Live"
+		}},
+		{"elements", func(m *dash.MPD) {
+			m.UTCTiming = m.UTCTiming[1:]
+			m.PatchLocation = append(m.PatchLocation, &dash.PatchLocation{URL: "http://example.com/patch"})
+			reps := m.Periods[0].AdaptationSets[0].Representations
+			m.Periods[0].AdaptationSets[0].Representations = dash.Representations{reps[2], reps[0]}
+			m.Periods[0].AdaptationSets[1].Representations[0].Bandwidth = 64000
+		}},
+		{"new Period", func(m *dash.MPD) {
+			p := *m.Periods[0]
+			p.ID = "2"
+			p.Start = &dash.CustomDuration{Duration: 654 * time.Second}
+			m.Periods = append(m.Periods, &p)
+		}},
+		{"replaced AdaptationSets", func(m *dash.MPD) {
+			sets := m.Periods[0].AdaptationSets
+			m.Periods[0].AdaptationSets = dash.AdaptationSets{sets[1], sets[0]}
+		}},
+	}
+
+	for _, tt := range tests {
+		a, b := &dash.MPD{}, &dash.MPD{}
+		if err := a.Parse(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Parse(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		tt.Update(b)
+		b.PublishTime = &dash.CustomTime{Time: a.PublishTime.Time.Add(time.Minute)}
+
+		p := roundTrip(t, a, b)
+		if tt.Case == "no changes" && len(p.Operations) != 1 {
+			t.Errorf("%s - expected only publishTime to be replaced, but got %d operations", tt.Case, len(p.Operations))
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	live := getLive()
+	addSegments(live, 0, 10000)
+	m := snapshot(t, live.MPD)
+
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<Patch xmlns="urn:mpeg:dash:schema:mpd-patch:2020" xmlns:p="urn:ietf:params:xml:schema:patch-ops"
+  mpdId="live" originalPublishTime="2016-07-13T17:00:00Z" publishTime="2016-07-13T17:00:02Z">
+  <p:replace sel="/MPD/@publishTime">2016-07-13T17:00:02Z</p:replace>
+  <p:add sel="/MPD/Period[@id='p0']/AdaptationSet[@id=&quot;1&quot;]/Representation/SegmentTemplate/SegmentTimeline">
+    <S d="2000"/>
+  </p:add>
+  <p:add sel="/MPD/Period[@id='p0']" type="@duration">PT12S</p:add>
+  <p:remove sel="/MPD/Period/AdaptationSet[2]/Representation/SegmentTemplate/SegmentTimeline/S[1]"/>
+  <p:add sel="/MPD/Period/AdaptationSet[2]/Representation/SegmentTemplate/SegmentTimeline/S[1]" pos="before"><S t="0" d="1990"/></p:add>
+</Patch>`
+
+	p := &Patch{}
+	if err := p.Parse(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Operations) != 5 || p.Operations[1].Content != `<S d="2000"/>` {
+		t.Fatalf("Expected 5 operations, but got %d", len(p.Operations))
+	}
+
+	patched, err := Apply(p, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !patched.PublishTime.Time.Equal(ast.Add(2 * time.Second)) {
+		t.Errorf("Expected publishTime %v, but got %v", ast.Add(2*time.Second), patched.PublishTime.Time)
+	}
+	if patched.Periods[0].Duration == nil || patched.Periods[0].Duration.Duration != 12*time.Second {
+		t.Errorf("Expected Period duration to be added")
+	}
+	video := patched.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate.SegmentTimeline
+	if !reflect.DeepEqual(video.Segments, dash.Segments{&dash.S{T: 0, D: 2000, R: 4}, &dash.S{D: 2000}}) {
+		t.Errorf("Expected S element to be appended to the video timeline, but got %v", video.Segments)
+	}
+	audio := patched.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate.SegmentTimeline
+	if !reflect.DeepEqual(audio.Segments, m.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate.SegmentTimeline.Segments) {
+		t.Errorf("Expected audio timeline to be unchanged, but got %v", audio.Segments)
+	}
+	if len(m.Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate.SegmentTimeline.Segments) != 1 {
+		t.Error("Expected Apply to not modify the MPD")
+	}
+
+	//the patch no longer applies once the MPD is updated
+	if _, err := Apply(p, patched); err != ErrMPDMismatch {
+		t.Errorf("Expected ErrMPDMismatch, but got %v", err)
+	}
+
+	errors := []struct {
+		Case      string
+		Operation *Operation
+	}{
+		{"no match", &Operation{Op: OpRemove, Sel: "/MPD/Period[@id='p1']"}},
+		{"ambiguous", &Operation{Op: OpRemove, Sel: "/MPD/Period/AdaptationSet"}},
+		{"wrong root", &Operation{Op: OpRemove, Sel: "/Patch/Period"}},
+		{"remove root", &Operation{Op: OpRemove, Sel: "/MPD"}},
+		{"existing attribute", &Operation{Op: OpAdd, Sel: "/MPD", Type: "@type", Content: "static"}},
+		{"missing attribute", &Operation{Op: OpReplace, Sel: "/MPD/@id2", Content: "x"}},
+		{"unterminated predicate", &Operation{Op: OpRemove, Sel: "/MPD/Period[@id='p0'"}},
+	}
+	for _, tt := range errors {
+		p := &Patch{MPDID: "live", OriginalPublishTime: ast, PublishTime: ast, Operations: []*Operation{tt.Operation}}
+		if _, err := Apply(p, m); err == nil {
+			t.Errorf("%s - expected error", tt.Case)
+		}
+	}
+}
+
+func TestEncodeValidation(t *testing.T) {
+	p := &Patch{MPDID: "live", OriginalPublishTime: ast, PublishTime: ast,
+		Operations: []*Operation{&Operation{Op: "move", Sel: "/MPD"}, &Operation{Op: OpAdd, Sel: "/MPD", Pos: "inside"}}}
+	_, err := p.Encode()
+	if err == nil {
+		t.Fatal("Expected error when encoding an invalid Patch")
+	}
+	if !strings.Contains(err.Error(), "Op are 'add', 'replace' and 'remove'") || !strings.Contains(err.Error(), "Pos are") {
+		t.Errorf("Expected Op and Pos errors, but got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//node is a generic XML element. Names keep the prefix used in the document instead of the
+//resolved namespace, so selectors can address elements and attributes the way they're written.
+type node struct {
+	Name     string
+	Attr     []xml.Attr
+	Text     string
+	Children []*node
+}
+
+//parseTree reads the root element of an XML document.
+func parseTree(r io.Reader) (*node, error) {
+	nodes, err := parseNodes(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) != 1 {
+		return nil, errors.New("XML document must have a single root element")
+	}
+	return nodes[0], nil
+}
+
+//parseNodes reads a sequence of XML elements. Whitespace between elements, comments and
+//processing instructions are dropped.
+func parseNodes(r io.Reader) ([]*node, error) {
+	d := xml.NewDecoder(r)
+	var roots []*node
+	var stack []*node
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{Name: qualifiedName(t.Name)}
+			for _, a := range t.Attr {
+				n.Attr = append(n.Attr, xml.Attr{Name: xml.Name{Local: qualifiedName(a.Name)}, Value: a.Value})
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			} else {
+				roots = append(roots, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].Name != qualifiedName(t.Name) {
+				return nil, fmt.Errorf("unexpected end element %s", qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 && len(bytes.TrimSpace(t)) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("element %s is not closed", stack[len(stack)-1].Name)
+	}
+	return roots, nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+//write serializes the node and its children.
+func (n *node) write(buf *bytes.Buffer) {
+	if n.Text == "" && len(n.Children) == 0 {
+		n.writeStart(buf, true)
+		return
+	}
+	n.writeStart(buf, false)
+	xml.EscapeText(buf, []byte(n.Text))
+	for _, c := range n.Children {
+		c.write(buf)
+	}
+	buf.WriteString("</" + n.Name + ">")
+}
+
+//writeStart writes the start tag of the node, or an empty element tag if empty is true.
+func (n *node) writeStart(buf *bytes.Buffer, empty bool) {
+	buf.WriteString("<" + n.Name)
+	for _, a := range n.Attr {
+		buf.WriteString(" " + a.Name.Local + `="`)
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+	if empty {
+		buf.WriteString("/>")
+	} else {
+		buf.WriteString(">")
+	}
+}
+
+func (n *node) String() string {
+	buf := new(bytes.Buffer)
+	n.write(buf)
+	return buf.String()
+}
+
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (n *node) setAttr(name, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Name.Local == name {
+			n.Attr[i].Value = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+func (n *node) removeAttr(name string) bool {
+	for i, a := range n.Attr {
+		if a.Name.Local == name {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//equal reports whether both nodes have the same name, attributes, text and children.
+//Attribute order is ignored.
+func (n *node) equal(o *node) bool {
+	if n.Name != o.Name || n.Text != o.Text || len(n.Attr) != len(o.Attr) || len(n.Children) != len(o.Children) {
+		return false
+	}
+	for _, a := range n.Attr {
+		if v, ok := o.attr(a.Name.Local); !ok || v != a.Value {
+			return false
+		}
+	}
+	for i := range n.Children {
+		if !n.Children[i].equal(o.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+//step is a location step of a selector: an element name followed by predicates,
+//either a 1-based position or an attribute value.
+type step struct {
+	Name       string
+	Predicates []predicate
+}
+
+type predicate struct {
+	Position int
+	Attr     string
+	Value    string
+}
+
+//parseSelector parses the subset of XPath used by MPD Patch documents: an absolute path
+//of elements with position and attribute predicates, optionally ending with an attribute.
+//Ex: /MPD/Period[@id='1']/AdaptationSet[2]/@lang
+func parseSelector(sel string) ([]step, string, error) {
+	if !strings.HasPrefix(sel, "/") {
+		return nil, "", fmt.Errorf("selector %s must be an absolute path", sel)
+	}
+
+	var steps []step
+	var attr string
+	rest := sel[1:]
+	for rest != "" {
+		if attr != "" {
+			return nil, "", fmt.Errorf("selector %s must end with the attribute", sel)
+		}
+
+		end := stepEnd(rest)
+		if end < 0 {
+			return nil, "", fmt.Errorf("selector %s has an unterminated predicate", sel)
+		}
+		part := rest[:end]
+		rest = strings.TrimPrefix(rest[end:], "/")
+
+		if strings.HasPrefix(part, "@") {
+			attr = part[1:]
+			continue
+		}
+
+		s, err := parseStep(part)
+		if err != nil {
+			return nil, "", fmt.Errorf("selector %s: %v", sel, err)
+		}
+		steps = append(steps, s)
+	}
+
+	if len(steps) == 0 {
+		return nil, "", fmt.Errorf("selector %s must select an element", sel)
+	}
+	return steps, attr, nil
+}
+
+//stepEnd returns the index of the / ending the first step, skipping quoted predicate values.
+func stepEnd(s string) int {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return -1
+	}
+	return len(s)
+}
+
+func parseStep(s string) (step, error) {
+	i := strings.Index(s, "[")
+	if i < 0 {
+		return step{Name: s}, nil
+	}
+
+	st := step{Name: s[:i]}
+	for s = s[i:]; s != ""; {
+		if s[0] != '[' {
+			return st, fmt.Errorf("unexpected %s", s)
+		}
+		end := closingBracket(s)
+		if end < 0 {
+			return st, fmt.Errorf("unterminated predicate %s", s)
+		}
+
+		p, err := parsePredicate(s[1:end])
+		if err != nil {
+			return st, err
+		}
+		st.Predicates = append(st.Predicates, p)
+		s = s[end+1:]
+	}
+	return st, nil
+}
+
+//closingBracket returns the index of the ] closing the predicate s starts with.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parsePredicate(s string) (predicate, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 {
+			return predicate{}, fmt.Errorf("position %d must be greater than 0", n)
+		}
+		return predicate{Position: n}, nil
+	}
+
+	eq := strings.Index(s, "=")
+	if !strings.HasPrefix(s, "@") || eq < 0 {
+		return predicate{}, fmt.Errorf("unsupported predicate [%s]", s)
+	}
+	value := strings.TrimSpace(s[eq+1:])
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return predicate{}, fmt.Errorf("predicate [%s] value must be quoted", s)
+	}
+	return predicate{Attr: strings.TrimSpace(s[1:eq]), Value: value[1 : len(value)-1]}, nil
+}
+
+func (s step) String() string {
+	str := s.Name
+	for _, p := range s.Predicates {
+		if p.Attr == "" {
+			str += "[" + strconv.Itoa(p.Position) + "]"
+			continue
+		}
+		quote := "'"
+		if strings.Contains(p.Value, "'") {
+			quote = `"`
+		}
+		str += "[@" + p.Attr + "=" + quote + p.Value + quote + "]"
+	}
+	return str
+}
+
+//match returns the children of n selected by the step.
+func (s step) match(n *node) []*node {
+	var matched []*node
+	for _, c := range n.Children {
+		if c.Name == s.Name {
+			matched = append(matched, c)
+		}
+	}
+	for _, p := range s.Predicates {
+		if p.Attr == "" {
+			if p.Position > len(matched) {
+				return nil
+			}
+			matched = matched[p.Position-1 : p.Position]
+			continue
+		}
+		var filtered []*node
+		for _, c := range matched {
+			if v, ok := c.attr(p.Attr); ok && v == p.Value {
+				filtered = append(filtered, c)
+			}
+		}
+		matched = filtered
+	}
+	return matched
+}
+
+//selectNode resolves a selector against root. It returns the selected element, its parent
+//(nil for root) and the selected attribute name, if any. A selector must match a single element.
+func selectNode(root *node, sel string) (*node, *node, string, error) {
+	steps, attr, err := parseSelector(sel)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if len(steps[0].Predicates) > 0 || steps[0].Name != root.Name {
+		return nil, nil, "", fmt.Errorf("selector %s doesn't match the root element %s", sel, root.Name)
+	}
+
+	var parent *node
+	n := root
+	for _, s := range steps[1:] {
+		matched := s.match(n)
+		if len(matched) != 1 {
+			return nil, nil, "", fmt.Errorf("selector %s matches %d elements at %s", sel, len(matched), s)
+		}
+		parent, n = n, matched[0]
+	}
+	return n, parent, attr, nil
+}
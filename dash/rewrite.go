@@ -0,0 +1,112 @@
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+
+	"github.com/ingest/manifest"
+)
+
+//Kinds of the URIs passed to the manifest.Rewriter of Rewrite, named after the element listing them.
+const (
+	KindBaseURL             = "BaseURL"
+	KindLocation            = "Location"
+	KindPatchLocation       = "PatchLocation"
+	KindSegmentTemplate     = "SegmentTemplate"     //media, index, initialization and bitstreamSwitching templates
+	KindSegmentURL          = "SegmentURL"          //media and index
+	KindInitialization      = "Initialization"      //sourceURL
+	KindRepresentationIndex = "RepresentationIndex" //sourceURL
+	KindBitstreamSwitching  = "BitstreamSwitching"  //sourceURL
+)
+
+//Rewrite replaces every URI of the MPD with the URI returned by r, such as to add CDN tokens or move
+//resources to another host. r is called with the name of the element listing the URI as kind:
+//KindBaseURL, KindLocation, KindPatchLocation, KindSegmentTemplate for its media, index, initialization
+//and bitstreamSwitching templates, KindSegmentURL for its media and index, and KindInitialization,
+//KindRepresentationIndex or KindBitstreamSwitching for their sourceURL.
+//
+//URIs are passed as listed, so relative URIs resolve against the BaseURL elements in scope, and
+//SegmentTemplate URIs include identifiers such as $Number$.
+func (m *MPD) Rewrite(r manifest.Rewriter) {
+	rewriteBaseURLs(r, m.BaseURL)
+	for i := range m.Location {
+		rewriteURI(r, KindLocation, &m.Location[i])
+	}
+	for _, pl := range m.PatchLocation {
+		rewriteURI(r, KindPatchLocation, &pl.URL)
+	}
+
+	for _, p := range m.Periods {
+		rewriteBaseURLs(r, p.BaseURL)
+		rewriteSegments(r, p.SegmentBase, p.SegmentList, p.SegmentTemplate)
+		for _, as := range p.AdaptationSets {
+			rewriteBaseURLs(r, as.BaseURL)
+			rewriteSegments(r, as.SegmentBase, as.SegmentList, as.SegmentTemplate)
+			for _, rep := range as.Representations {
+				rewriteBaseURLs(r, rep.BaseURL)
+				rewriteSegments(r, rep.SegmentBase, rep.SegmentList, rep.SegmentTemplate)
+			}
+		}
+	}
+}
+
+//rewriteBaseURLs rewrites the BaseURL elements, which are listed as XML, so are unescaped before r
+//is called, and escaped again if r changed them.
+func rewriteBaseURLs(r manifest.Rewriter, baseURLs []*BaseURL) {
+	for _, b := range baseURLs {
+		var text struct {
+			Value string `xml:",chardata"`
+		}
+		if err := xml.Unmarshal([]byte("<BaseURL>"+b.URL+"</BaseURL>"), &text); err != nil || text.Value == "" {
+			continue
+		}
+		uri := r(KindBaseURL, text.Value)
+		if uri == text.Value {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := xml.EscapeText(buf, []byte(uri)); err != nil {
+			continue
+		}
+		b.URL = buf.String()
+	}
+}
+
+//rewriteSegments rewrites the URIs of the segment information of a Period, AdaptationSet or Representation.
+func rewriteSegments(r manifest.Rewriter, sb *SegmentBase, sl *SegmentList, st *SegmentTemplate) {
+	if sb != nil {
+		rewriteURLType(r, KindInitialization, sb.Initialization)
+		rewriteURLType(r, KindRepresentationIndex, sb.RepresentationIndex)
+	}
+	if sl != nil {
+		rewriteURLType(r, KindInitialization, sl.Initialization)
+		rewriteURLType(r, KindRepresentationIndex, sl.RepresentationIndex)
+		rewriteURLType(r, KindBitstreamSwitching, sl.BitstreamSwitching)
+		for _, s := range sl.SegmentURLs {
+			rewriteURI(r, KindSegmentURL, &s.Media)
+			rewriteURI(r, KindSegmentURL, &s.Index)
+		}
+	}
+	if st != nil {
+		rewriteURI(r, KindSegmentTemplate, &st.Media)
+		rewriteURI(r, KindSegmentTemplate, &st.Index)
+		rewriteURI(r, KindSegmentTemplate, &st.InitializationAttr)
+		rewriteURI(r, KindSegmentTemplate, &st.BitstreamSwitchingAttr)
+		rewriteURLType(r, KindInitialization, st.Initialization)
+		rewriteURLType(r, KindRepresentationIndex, st.RepresentationIndex)
+		rewriteURLType(r, KindBitstreamSwitching, st.BitstreamSwitching)
+	}
+}
+
+func rewriteURLType(r manifest.Rewriter, kind string, u *URLType) {
+	if u != nil {
+		rewriteURI(r, kind, &u.SourceURL)
+	}
+}
+
+//rewriteURI sets uri to the URI returned by r, unless it's empty.
+func rewriteURI(r manifest.Rewriter, kind string, uri *string) {
+	if *uri != "" {
+		*uri = r(kind, *uri)
+	}
+}
@@ -0,0 +1,95 @@
+package dash
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ingest/manifest"
+)
+
+const rewriteMPD = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="static" mediaPresentationDuration="PT8S" minBufferTime="PT2S">
+  <BaseURL>https://origin.example.com/vod/?a=1&amp;b=2</BaseURL>
+  <Location>https://origin.example.com/vod/manifest.mpd</Location>
+  <Period id="1">
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1" duration="4" media="video/$Number$.m4s" initialization="video/init.mp4"></SegmentTemplate>
+      <Representation id="v" bandwidth="800000"></Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <Representation id="a" bandwidth="64000">
+        <BaseURL>audio/</BaseURL>
+        <SegmentList timescale="1" duration="4">
+          <Initialization sourceURL="init.mp4"></Initialization>
+          <SegmentURL media="1.m4s"></SegmentURL>
+          <SegmentURL mediaRange="0-99"></SegmentURL>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestRewrite(t *testing.T) {
+	mpd := &MPD{}
+	if err := mpd.Parse(strings.NewReader(rewriteMPD)); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string][]string)
+	mpd.Rewrite(func(kind string, uri string) string {
+		kinds[kind] = append(kinds[kind], uri)
+		return uri
+	})
+	expect := map[string]string{
+		"BaseURL":         "https://origin.example.com/vod/?a=1&b=2,audio/",
+		"Location":        "https://origin.example.com/vod/manifest.mpd",
+		"SegmentTemplate": "video/$Number$.m4s,video/init.mp4",
+		"Initialization":  "init.mp4",
+		"SegmentURL":      "1.m4s",
+	}
+	if len(kinds) != len(expect) {
+		t.Errorf("Expected %d kinds of URIs, but got %v", len(expect), kinds)
+	}
+	for kind, uris := range expect {
+		if got := strings.Join(kinds[kind], ","); got != uris {
+			t.Errorf("Expected %s URIs %s, but got %s", kind, uris, got)
+		}
+	}
+
+	mpd.Rewrite(manifest.Chain(manifest.Host("origin.example.com", "cdn.example.com"), manifest.Kinds(func(kind string, uri string) string {
+		return uri + "?token=x&y"
+	}, KindSegmentTemplate, KindBaseURL)))
+	if b := mpd.BaseURL[0].URL; b != "https://cdn.example.com/vod/?a=1&amp;b=2?token=x&amp;y" {
+		t.Errorf("Expected the BaseURL to be escaped, but got %s", b)
+	}
+	if l := mpd.Location[0]; l != "https://cdn.example.com/vod/manifest.mpd" {
+		t.Errorf("Expected the Location to be moved to the CDN, but got %s", l)
+	}
+	if m := mpd.Periods[0].AdaptationSets[0].SegmentTemplate.Media; m != "video/$Number$.m4s?token=x&y" {
+		t.Errorf("Expected the template to keep its identifiers, but got %s", m)
+	}
+	if s := mpd.Periods[0].AdaptationSets[1].Representations[0].SegmentList.SegmentURLs[1]; s.Media != "" {
+		t.Errorf("Expected a SegmentURL without media to be skipped, but got %s", s.Media)
+	}
+
+	r, err := mpd.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	decoded := &MPD{}
+	if err := decoded.Parse(strings.NewReader(string(b))); err != nil {
+		t.Fatal(err)
+	}
+	var base string
+	decoded.Rewrite(manifest.Kinds(func(kind string, uri string) string {
+		if base == "" {
+			base = uri
+		}
+		return uri
+	}, KindBaseURL))
+	if base != "https://cdn.example.com/vod/?a=1&b=2?token=x&y" {
+		t.Errorf("Expected the rewritten BaseURL to round trip, but got %s", base)
+	}
+}
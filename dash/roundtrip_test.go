@@ -0,0 +1,234 @@
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//xmlTree is a namespace resolved XML element, used to compare MPDs semantically.
+type xmlTree struct {
+	Name     xml.Name
+	Attr     map[xml.Name]string
+	Text     string
+	Children []*xmlTree
+}
+
+func parseXMLTree(r io.Reader) (*xmlTree, error) {
+	d := xml.NewDecoder(r)
+	var root *xmlTree
+	var stack []*xmlTree
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlTree{Name: t.Name, Attr: map[xml.Name]string{}}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+				n.Attr[a.Name] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			} else {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += strings.TrimSpace(string(t))
+			}
+		}
+	}
+}
+
+//attrDefaults are attribute values that are the same as the attribute being absent.
+var attrDefaults = map[string]string{
+	"t":                      "0",
+	"r":                      "0",
+	"presentationTime":       "0",
+	"presentationTimeOffset": "0",
+	"segmentAlignment":       "false",
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?T(?:(\d+)H)?(?:(\d+)M)?(?:([\d.]+)S)?$`)
+
+func parseISODuration(s string) (time.Duration, bool) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	var d float64
+	for i, unit := range []float64{24 * 3600, 3600, 60, 1} {
+		if m[i+1] != "" {
+			v, _ := strconv.ParseFloat(m[i+1], 64)
+			d += v * unit
+		}
+	}
+	return time.Duration(d * float64(time.Second)), true
+}
+
+//sameValue reports whether both attribute values are the same once parsed as a boolean,
+//a number, a duration or a date.
+func sameValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ba, errA := strconv.ParseBool(a)
+	bb, errB := strconv.ParseBool(b)
+	if errA == nil && errB == nil {
+		return ba == bb
+	}
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		fb, err := strconv.ParseFloat(b, 64)
+		return err == nil && fa == fb
+	}
+	if da, ok := parseISODuration(a); ok {
+		db, ok := parseISODuration(b)
+		return ok && da == db
+	}
+	if ta, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		tb, err := time.Parse(time.RFC3339Nano, b)
+		return err == nil && ta.Equal(tb)
+	}
+	return false
+}
+
+//diffXMLTree returns the semantic differences between two elements. Attribute order and namespace
+//prefixes are ignored, but child elements must be in the same order, as MPD elements are sequences.
+func diffXMLTree(path string, a, b *xmlTree) []string {
+	path += "/" + a.Name.Local
+	if a.Name != b.Name {
+		return []string{fmt.Sprintf("%s: element %v became %v", path, a.Name, b.Name)}
+	}
+
+	var diffs []string
+	for name, va := range a.Attr {
+		vb, ok := b.Attr[name]
+		if !ok {
+			vb, ok = attrDefaults[name.Local]
+		}
+		if !ok || !sameValue(va, vb) {
+			diffs = append(diffs, fmt.Sprintf("%s/@%s: %q became %q", path, name.Local, va, b.Attr[name]))
+		}
+	}
+	for name, vb := range b.Attr {
+		if _, ok := a.Attr[name]; !ok && attrDefaults[name.Local] != vb {
+			diffs = append(diffs, fmt.Sprintf("%s/@%s: added %q", path, name.Local, vb))
+		}
+	}
+	if a.Text != b.Text {
+		diffs = append(diffs, fmt.Sprintf("%s: text %q became %q", path, a.Text, b.Text))
+	}
+
+	if len(a.Children) != len(b.Children) {
+		return append(diffs, fmt.Sprintf("%s: children %s became %s", path, childNames(a.Children), childNames(b.Children)))
+	}
+	for i := range a.Children {
+		diffs = append(diffs, diffXMLTree(path, a.Children[i], b.Children[i])...)
+	}
+	return diffs
+}
+
+func childNames(children []*xmlTree) string {
+	var names []string
+	for _, c := range children {
+		names = append(names, c.Name.Local)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLosslessEncode(t *testing.T) {
+	files, err := filepath.Glob("./testdata/*.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := &MPD{}
+		if err := m.Parse(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s - %s", file, err)
+		}
+		r, err := m.Encode()
+		if err != nil {
+			t.Fatalf("%s - %s", file, err)
+		}
+
+		original, err := parseXMLTree(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := parseXMLTree(r)
+		if err != nil {
+			t.Fatalf("%s - encoded MPD isn't well formed: %s", file, err)
+		}
+
+		for _, diff := range diffXMLTree("", original, encoded) {
+			t.Errorf("%s - %s", file, diff)
+		}
+	}
+}
+
+func TestEncodeExtensions(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/extensions.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &MPD{}
+	if err := m.Parse(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(encoded)
+
+	for _, s := range []string{
+		`xmlns:dvb="urn:dvb:dash:dash-extensions:2014-1"`,
+		`dvb:priority="1"`,
+		`scte214:supplementalCodecs="dvh1.05.01"`,
+		`<dashif:Laurl Lic_type="EME-1.0">`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Expected encoded MPD to contain %s, but got:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{"xmlns:_", "_:", "&#xA;"} {
+		if strings.Contains(out, s) {
+			t.Errorf("Expected encoded MPD not to contain %s, but got:\n%s", s, out)
+		}
+	}
+	label, prt := strings.Index(out, "<Label"), strings.Index(out, "<ProducerReferenceTime")
+	if label == -1 || label > prt {
+		t.Errorf("Expected Label before ProducerReferenceTime, but got:\n%s", out)
+	}
+}
@@ -0,0 +1,48 @@
+package dash
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ingest/manifest/internal/source"
+)
+
+//Source represents how you can fetch a MPD, the remote elements it references through xlink
+//and its segments from different locations. Sources are Fetchers, so a Resolver can dereference
+//remote elements with them.
+type Source interface {
+	//MPD fetches and parses the MPD at uri, recording uri in MPD.URI and the time it was fetched in MPD.FetchTime.
+	MPD(ctx context.Context, uri string) (*MPD, error)
+	//Fetch returns the remote element entity referenced by a xlink:href.
+	Fetcher
+	//Resource returns the content at uri, such as a segment.
+	Resource(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+//UpdateURL returns the URL updates of the MPD are fetched from: its first Location, resolved
+//against URI, or URI if it has no Location.
+func (m *MPD) UpdateURL() (string, error) {
+	if len(m.Location) == 0 || m.Location[0] == "" {
+		if m.URI == "" {
+			return "", errors.New("MPD has no URI or Location to fetch updates from")
+		}
+		return m.URI, nil
+	}
+
+	if m.URI == "" {
+		return m.Location[0], nil
+	}
+	return source.ResolveURL(m.URI, m.Location[0])
+}
+
+//NextUpdate returns when the MPD should be fetched again: minimumUpdatePeriod after FetchTime.
+//It returns false if the MPD isn't updated: it's static, has no minimumUpdatePeriod, or its
+//minimumUpdatePeriod is zero, which signals updates inband in the segments instead.
+func (m *MPD) NextUpdate() (time.Time, bool) {
+	if m.Type != "dynamic" || m.MinUpdatePeriod == nil || m.MinUpdatePeriod.Duration <= 0 {
+		return time.Time{}, false
+	}
+	return m.FetchTime.Add(m.MinUpdatePeriod.Duration), true
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/internal/source"
+)
+
+type httpSource struct {
+	Client *http.Client
+}
+
+//HTTP returns a source interface that fetches content using HTTP.
+//By default uses the http.DefaultClient if a nil pointer is passed.
+//Responses without a 2xx status code fail with a *manifest.StatusError.
+func HTTP(c *http.Client) dash.Source {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &httpSource{
+		Client: c,
+	}
+}
+
+//MPD will download, and attempt to parse the document at the URI into a MPD.
+//If the request is redirected, MPD.URI is the URL it was redirected to, so relative
+//BaseURLs and Locations resolve against the server that served it.
+func (s *httpSource) MPD(ctx context.Context, uri string) (*dash.MPD, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := source.Do(ctx, s.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	fetched := time.Now()
+
+	m := &dash.MPD{}
+	if err := m.Parse(res.Body); err != nil {
+		return nil, err
+	}
+	m.URI = res.Request.URL.String()
+	m.FetchTime = fetched
+	return m, nil
+}
+
+//Fetch will download the remote element entity referenced by a xlink:href.
+func (s *httpSource) Fetch(ctx context.Context, uri string) ([]byte, error) {
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+//Resource will download, and return the http.Response.Body for further parsing for whatever the structure might be,
+//such as a segment.
+func (s *httpSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := source.Do(ctx, s.Client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
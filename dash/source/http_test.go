@@ -0,0 +1,165 @@
+package source_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest"
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/dash/source"
+)
+
+const dynamicMPD = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" profiles="urn:mpeg:dash:profile:isoff-live:2011" minBufferTime="PT2S" minimumUpdatePeriod="PT0.05S" availabilityStartTime="2017-01-01T00:00:00Z" publishTime="2017-01-01T00:00:00Z">
+  <Location>updated.mpd</Location>
+  <Period id="1" start="PT0S">
+    <AdaptationSet xlink:href="adaptation.xml" xlink:actuate="onLoad" xmlns:xlink="http://www.w3.org/1999/xlink"></AdaptationSet>
+  </Period>
+</MPD>`
+
+const staticMPD = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" profiles="urn:mpeg:dash:profile:isoff-on-demand:2011" minBufferTime="PT2S" mediaPresentationDuration="PT10S">
+  <Period id="1" start="PT0S">
+    <AdaptationSet xlink:href="adaptation.xml" xlink:actuate="onLoad" xmlns:xlink="http://www.w3.org/1999/xlink"></AdaptationSet>
+  </Period>
+</MPD>`
+
+const adaptationSet = `<AdaptationSet mimeType="video/mp4"><Representation id="1" bandwidth="800000"></Representation></AdaptationSet>`
+
+func liveServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect.mpd":
+			http.Redirect(w, r, "/live/manifest.mpd", http.StatusFound)
+		case "/live/manifest.mpd":
+			w.Write([]byte(dynamicMPD))
+		case "/live/updated.mpd":
+			//the live presentation ends on the second update
+			if atomic.AddInt32(requests, 1) > 1 {
+				w.Write([]byte(staticMPD))
+				return
+			}
+			w.Write([]byte(dynamicMPD))
+		case "/live/adaptation.xml":
+			w.Write([]byte(adaptationSet))
+		case "/live/seg0.mp4":
+			w.Write([]byte("segment"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestHTTP(t *testing.T) {
+	var requests int32
+	server := liveServer(&requests)
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.HTTP(nil)
+
+	before := time.Now()
+	m, err := src.MPD(ctx, server.URL+"/redirect.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.URI != server.URL+"/live/manifest.mpd" {
+		t.Errorf("Expected URI of the redirect %s, but got %s", server.URL+"/live/manifest.mpd", m.URI)
+	}
+	if m.FetchTime.Before(before) || m.FetchTime.After(time.Now()) {
+		t.Errorf("Expected FetchTime of the request, but got %s", m.FetchTime)
+	}
+
+	r := dash.NewResolver(src)
+	r.BaseURL = m.URI
+	if err := r.Resolve(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if as := m.Periods[0].AdaptationSets; len(as) != 1 || as[0].MimeType != "video/mp4" {
+		t.Errorf("Expected remote AdaptationSet to be resolved, but got %v", as)
+	}
+
+	body, err := src.Resource(ctx, server.URL+"/live/seg0.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(b) != "segment" {
+		t.Errorf("Expected content %q, but got %q", "segment", b)
+	}
+
+	_, err = src.Resource(ctx, server.URL+"/live/missing.mp4")
+	if serr, ok := err.(*manifest.StatusError); !ok || serr.StatusCode != http.StatusNotFound || serr.Temporary() {
+		t.Errorf("Expected 404 StatusError, but got %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	var requests int32
+	server := liveServer(&requests)
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.HTTP(nil)
+	m, err := src.MPD(ctx, server.URL+"/live/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := source.Refresh(ctx, src, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := updated.FetchTime.Sub(m.FetchTime); d < m.MinUpdatePeriod.Duration {
+		t.Errorf("Expected update after minimumUpdatePeriod %s, but got %s", m.MinUpdatePeriod.Duration, d)
+	}
+	if updated.URI != server.URL+"/live/updated.mpd" {
+		t.Errorf("Expected update from Location %s, but got %s", server.URL+"/live/updated.mpd", updated.URI)
+	}
+
+	static, err := source.Refresh(ctx, src, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Refresh(ctx, src, static); err != source.ErrNotUpdated {
+		t.Errorf("Expected ErrNotUpdated for a static MPD, but got %v", err)
+	}
+
+	//cancelled while waiting for the update
+	m.FetchTime = time.Now().Add(time.Hour)
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := source.Refresh(cancelled, src, m); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var requests int32
+	server := liveServer(&requests)
+	defer server.Close()
+
+	var types []string
+	err := source.Watch(context.Background(), source.HTTP(nil), server.URL+"/live/manifest.mpd", func(m *dash.MPD) error {
+		types = append(types, m.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"dynamic", "dynamic", "static"}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected %d MPDs, but got %v", len(expected), types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Expected MPD %d of type %s, but got %s", i, expected[i], types[i])
+		}
+	}
+}
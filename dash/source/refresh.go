@@ -0,0 +1,55 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+)
+
+//ErrNotUpdated is returned by Refresh for a MPD that isn't updated: a static MPD, or a dynamic
+//MPD without a minimumUpdatePeriod.
+var ErrNotUpdated = errors.New("MPD isn't updated")
+
+//Refresh waits until the dynamic MPD m is due an update, minimumUpdatePeriod after it was fetched,
+//then fetches the update from src. The update is fetched from the first Location of m if it has one,
+//the MPD Location redirect, otherwise from the URI m was fetched from.
+func Refresh(ctx context.Context, src dash.Source, m *dash.MPD) (*dash.MPD, error) {
+	next, ok := m.NextUpdate()
+	if !ok {
+		return nil, ErrNotUpdated
+	}
+	uri, err := m.UpdateURL()
+	if err != nil {
+		return nil, err
+	}
+
+	if d := next.Sub(time.Now()); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return src.MPD(ctx, uri)
+}
+
+//Watch fetches the MPD at uri from src and calls fn with it, then with every update of it
+//fetched by Refresh. It returns nil once the MPD isn't updated anymore, such as when a live
+//presentation ends and the MPD becomes static, or the first error of src, fn or ctx.
+func Watch(ctx context.Context, src dash.Source, uri string, fn func(*dash.MPD) error) error {
+	m, err := src.MPD(ctx, uri)
+	for err == nil {
+		if err = fn(m); err != nil {
+			break
+		}
+		m, err = Refresh(ctx, src, m)
+	}
+	if err == ErrNotUpdated {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/internal/source"
+)
+
+//resourceSource is a Source reading MPDs and resources with open, such as from storage.
+type resourceSource struct {
+	open source.Open
+}
+
+//Dir returns a source interface that reads content from the directory root, such as the output of a packager.
+//URIs are resolved as paths relative to root. The path of URLs with a scheme and host is used, so MPDs
+//listing absolute URLs can be read from a copy of their origin.
+func Dir(root string) dash.Source {
+	return &resourceSource{open: source.Dir(root)}
+}
+
+//Memory returns a source interface that serves content from resources, keyed by URI.
+//Missing resources return an error satisfying os.IsNotExist.
+func Memory(resources map[string][]byte) dash.Source {
+	return &resourceSource{open: source.Memory(resources)}
+}
+
+//MPD will read, and attempt to parse the document at the URI into a MPD.
+func (s *resourceSource) MPD(ctx context.Context, uri string) (*dash.MPD, error) {
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	fetched := time.Now()
+
+	m := &dash.MPD{}
+	if err := m.Parse(body); err != nil {
+		return nil, err
+	}
+	m.URI = uri
+	m.FetchTime = fetched
+	return m, nil
+}
+
+//Fetch will read the remote element entity referenced by a xlink:href.
+func (s *resourceSource) Fetch(ctx context.Context, uri string) ([]byte, error) {
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+//Resource will return the content at the URI, for further parsing for whatever the structure might be.
+func (s *resourceSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.open(ctx, uri)
+}
@@ -0,0 +1,78 @@
+package source_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/dash/source"
+)
+
+var files = map[string][]byte{
+	"vod/manifest.mpd":   []byte(staticMPD),
+	"vod/adaptation.xml": []byte(adaptationSet),
+	"vod/seg0.mp4":       []byte("segment"),
+}
+
+func testSource(t *testing.T, src dash.Source, uri string) {
+	ctx := context.Background()
+	m, err := src.MPD(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.URI != uri || m.FetchTime.IsZero() {
+		t.Errorf("Expected URI %s and FetchTime to be recorded, but got %s and %s", uri, m.URI, m.FetchTime)
+	}
+
+	r := dash.NewResolver(src)
+	r.BaseURL = m.URI
+	if err := r.Resolve(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if as := m.Periods[0].AdaptationSets; len(as) != 1 || as[0].MimeType != "video/mp4" {
+		t.Errorf("Expected remote AdaptationSet to be resolved, but got %v", as)
+	}
+
+	body, err := src.Resource(ctx, "vod/seg0.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(b) != "segment" {
+		t.Errorf("Expected content %q, but got %q", "segment", b)
+	}
+
+	if _, err := src.Resource(ctx, "vod/missing.mp4"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, but got %v", err)
+	}
+	if _, err := source.Refresh(ctx, src, m); err != source.ErrNotUpdated {
+		t.Errorf("Expected ErrNotUpdated for a static MPD, but got %v", err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "dash-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for name, b := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testSource(t, source.Dir(root), "vod/manifest.mpd")
+}
+
+func TestMemory(t *testing.T) {
+	testSource(t, source.Memory(files), "vod/manifest.mpd")
+}
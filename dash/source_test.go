@@ -0,0 +1,56 @@
+package dash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateURL(t *testing.T) {
+	tests := []struct {
+		uri      string
+		location []string
+		expected string
+	}{
+		{"https://example.com/live/manifest.mpd", nil, "https://example.com/live/manifest.mpd"},
+		{"https://example.com/live/manifest.mpd", []string{"updated.mpd"}, "https://example.com/live/updated.mpd"},
+		{"https://example.com/live/manifest.mpd", []string{"https://cdn.example.com/live.mpd"}, "https://cdn.example.com/live.mpd"},
+		{"live/manifest.mpd", []string{"updated.mpd"}, "live/updated.mpd"},
+		{"", []string{"https://cdn.example.com/live.mpd"}, "https://cdn.example.com/live.mpd"},
+	}
+	for _, tt := range tests {
+		m := &MPD{URI: tt.uri, Location: tt.location}
+		uri, err := m.UpdateURL()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri != tt.expected {
+			t.Errorf("Expected update URL %s, but got %s", tt.expected, uri)
+		}
+	}
+
+	if _, err := (&MPD{}).UpdateURL(); err == nil {
+		t.Errorf("Expected error for a MPD without URI or Location")
+	}
+}
+
+func TestNextUpdate(t *testing.T) {
+	fetched := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		mpdType  string
+		period   *CustomDuration
+		expected time.Time
+		ok       bool
+	}{
+		{"dynamic", &CustomDuration{Duration: 2 * time.Second}, fetched.Add(2 * time.Second), true},
+		{"dynamic", &CustomDuration{}, time.Time{}, false},
+		{"dynamic", nil, time.Time{}, false},
+		{"static", nil, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		m := &MPD{Type: tt.mpdType, MinUpdatePeriod: tt.period, FetchTime: fetched}
+		next, ok := m.NextUpdate()
+		if ok != tt.ok || !next.Equal(tt.expected) {
+			t.Errorf("Expected next update %s (%t), but got %s (%t)", tt.expected, tt.ok, next, ok)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package dash
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 const (
 	dashNS = "urn:mpeg:dash:schema:mpd:2011"
@@ -28,17 +31,58 @@ type MPD struct {
 	ProgramInformation    []*ProgramInformation `xml:"ProgramInformation,omitempty"`
 	BaseURL               []*BaseURL            `xml:"BaseURL,omitempty"`
 	Location              []string              `xml:"Location,omitempty"`
-	Metrics               []*Metrics            `xml:"Metrics,omitempty"`
+	PatchLocation         []*PatchLocation      `xml:"PatchLocation,omitempty"`      //Optional. Location of MPD Patch documents for type "dynamic", requires ID.
+	ServiceDescription    []*ServiceDescription `xml:"ServiceDescription,omitempty"` //Optional. Latency and playback rate targets for low latency playback.
 	Periods               Periods               `xml:"Period,omitempty"`
+	Metrics               []*Metrics            `xml:"Metrics,omitempty"`
+	UTCTiming             []*Descriptor         `xml:"UTCTiming,omitempty"` //Optional. Specifies a way to synchronise the client clock with the server.
+	ExtraAttrs            Attrs                 `xml:",any,attr"`
+	ExtraElements         []*Element            `xml:",any"`
+
+	URI       string    `xml:"-"` //Location the MPD was fetched from by a Source. Relative Location and BaseURL resolve against it.
+	FetchTime time.Time `xml:"-"` //Time the MPD was fetched by a Source. Updates of a dynamic MPD are due minimumUpdatePeriod after it.
+
+	namespaces []xml.Attr //Namespace declarations of the parsed file, written back on the MPD element by Encode.
+}
+
+//ServiceDescription describes the service the Media Presentation is part of, such as the
+//latency and playback rates a client should target for low latency playback.
+type ServiceDescription struct {
+	ID            *int            `xml:"id,attr,omitempty"`
+	Scope         []*Descriptor   `xml:"Scope,omitempty"` //Optional. Clients the ServiceDescription applies to. If not present, it applies to all clients.
+	Latency       []*Latency      `xml:"Latency,omitempty"`
+	PlaybackRate  []*PlaybackRate `xml:"PlaybackRate,omitempty"`
+	ExtraAttrs    Attrs           `xml:",any,attr"`
+	ExtraElements []*Element      `xml:",any"`
+}
+
+//Latency specifies the target, minimum and maximum latency of the service, in milliseconds.
+type Latency struct {
+	ReferenceID   *int       `xml:"referenceId,attr,omitempty"` //Optional. ID of the ProducerReferenceTime the latency is measured against.
+	Target        int        `xml:"target,attr,omitempty"`
+	Max           int        `xml:"max,attr,omitempty"`
+	Min           int        `xml:"min,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
+}
+
+//PlaybackRate specifies the playback rates a client may use to keep the latency on target.
+type PlaybackRate struct {
+	Max           float64    `xml:"max,attr,omitempty"`
+	Min           float64    `xml:"min,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //ProgramInformation specifies descriptive information about the program
 type ProgramInformation struct {
-	Lang               string `xml:"lang,attr,omitempty"`               //Optional
-	MoreInformationURL string `xml:"moreInformationURL,attr,omitempty"` //Optional
-	Title              string `xml:"Title,omitempty"`                   //Optional
-	Source             string `xml:"Source,omitempty"`                  //Optional
-	Copyright          string `xml:"Copyright,omitempty"`               //Optional
+	Lang               string     `xml:"lang,attr,omitempty"`               //Optional
+	MoreInformationURL string     `xml:"moreInformationURL,attr,omitempty"` //Optional
+	Title              string     `xml:"Title,omitempty"`                   //Optional
+	Source             string     `xml:"Source,omitempty"`                  //Optional
+	Copyright          string     `xml:"Copyright,omitempty"`               //Optional
+	ExtraAttrs         Attrs      `xml:",any,attr"`
+	ExtraElements      []*Element `xml:",any"`
 }
 
 //BaseURL can be used for reference resolution and alternative URL selection.
@@ -47,27 +91,41 @@ type BaseURL struct {
 	ServiceLocation string  `xml:"serviceLocation,attr,omitempty"` //Optional
 	ByteRange       string  `xml:"byteRange,attr,omitempty"`       //
 	AvTimeOffset    float64 `xml:"availabilityTimeOffset,attr,omitempty"`
-	AvTimeComplete  bool    `xml:"availabilityTimeComplete,attr,omitempty"`
+	AvTimeComplete  *bool   `xml:"availabilityTimeComplete,attr,omitempty"` //Optional. Default: true. A *bool, where it used to be a bool, so "false" is written.
+	ExtraAttrs      Attrs   `xml:",any,attr"`
+}
+
+//PatchLocation specifies a location where MPD Patch documents can be fetched to update the MPD.
+type PatchLocation struct {
+	URL        string  `xml:",chardata"`
+	TTL        float64 `xml:"ttl,attr,omitempty"` //Optional. Time in seconds the location is valid after the MPD publishTime.
+	ExtraAttrs Attrs   `xml:",any,attr"`
 }
 
 //Metrics ...
 type Metrics struct {
-	Metrics   string        `xml:"metrics,attr,omitempty"` //Required
-	Range     []*Range      `xml:"Range,omitempty"`        //Optional
-	Reporting []*Descriptor `xml:"Reporting,omitempty"`    //Required
+	Metrics       string        `xml:"metrics,attr,omitempty"` //Required
+	Range         []*Range      `xml:"Range,omitempty"`        //Optional
+	Reporting     []*Descriptor `xml:"Reporting,omitempty"`    //Required
+	ExtraAttrs    Attrs         `xml:",any,attr"`
+	ExtraElements []*Element    `xml:",any"`
 }
 
 //Range ...
 type Range struct {
-	StartTime float64 `xml:"starttime,attr,omitempty"`
-	Duration  float64 `xml:"duration,attr,omitempty"`
+	StartTime     float64    `xml:"starttime,attr,omitempty"`
+	Duration      float64    `xml:"duration,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //Descriptor ...
 type Descriptor struct {
-	SchemeIDURI string `xml:"schemeIdUri,attr,omitempty"`
-	Value       string `xml:"value,attr,omitempty"`
-	ID          string `xml:"id,attr,omitempty"`
+	SchemeIDURI   string     `xml:"schemeIdUri,attr,omitempty"`
+	Value         string     `xml:"value,attr,omitempty"`
+	ID            string     `xml:"id,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //Period represents a media content period.
@@ -86,31 +144,40 @@ type Period struct {
 	EventStream        []*EventStream   `xml:"EventStream,omitempty"`
 	AdaptationSets     `xml:"AdaptationSet,omitempty"`
 	Subsets            `xml:"Subset,omitempty"`
+	ExtraAttrs         Attrs      `xml:",any,attr"`
+	ExtraElements      []*Element `xml:",any"`
 }
 
 //EventStream represents a sequence of related events.
 type EventStream struct {
-	XlinkHref    string   `xml:"http://www.w3.org/1999/xlink href,attr,omitempty"`
-	XlinkActuate string   `xml:"http://www.w3.org/1999/xlink actuate,attr,omitempty"`
-	SchemeIDURI  string   `xml:"schemeIdUri,attr,omitempty"`
-	Value        string   `xml:"value,attr,omitempty"`
-	Timescale    int      `xml:"timescale,attr,omitempty"`
-	Event        []*Event `xml:"Event,omitempty"`
+	XlinkHref     string     `xml:"http://www.w3.org/1999/xlink href,attr,omitempty"`
+	XlinkActuate  string     `xml:"http://www.w3.org/1999/xlink actuate,attr,omitempty"`
+	SchemeIDURI   string     `xml:"schemeIdUri,attr,omitempty"`
+	Value         string     `xml:"value,attr,omitempty"`
+	Timescale     int        `xml:"timescale,attr,omitempty"`
+	Event         []*Event   `xml:"Event,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //Event represents aperiodic sparse media-time related auxiliary information to DASH
 //client or an application.
 type Event struct {
-	Message  string `xml:",innerxml"`
-	PresTime int64  `xml:"presentationTime,attr,omitempty"`
-	Duration int64  `xml:"duration,attr,omitempty"`
-	ID       int    `xml:"id,attr,omitempty"`
+	Message    string `xml:",innerxml"`
+	PresTime   int64  `xml:"presentationTime,attr,omitempty"`
+	Duration   int64  `xml:"duration,attr,omitempty"`
+	ID         int    `xml:"id,attr,omitempty"`
+	ExtraAttrs Attrs  `xml:",any,attr"`
+
+	zeroID bool //id="0" was parsed, which ID can't tell from an absent id
 }
 
 //URLType ...
 type URLType struct {
-	SourceURL string `xml:"sourceURL,attr,omitempty"`
-	Range     string `xml:"range,attr,omitempty"`
+	SourceURL     string     `xml:"sourceURL,attr,omitempty"`
+	Range         string     `xml:"range,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //SegmentBase represents a media file played by a DASH client
@@ -121,9 +188,12 @@ type SegmentBase struct {
 	IndexRange          string          `xml:"indexRange,attr,omitempty"`               //Optional. ByteRange that contains the Segment Index in all Segments of the Representation.
 	IndexRangeExact     bool            `xml:"indexRangeExact,attr,omitempty"`          //Default: false. Must not be present if IndexRange isn't present.
 	AvTimeOffset        float64         `xml:"availabilityTimeOffset,attr,omitempty"`   //Optional.
-	AvTmeComplete       bool            `xml:"availabilityTimeComplete,attr,omitempty"` //Optional.
+	AvTimeComplete      *bool           `xml:"availabilityTimeComplete,attr,omitempty"` //Optional. Default: true. If false, Segments are available in chunks before they are complete.
+	AvTmeComplete       bool            `xml:"-"`                                       //Deprecated: use AvTimeComplete. Parse sets it if AvTimeComplete is true, and Encode writes availabilityTimeComplete="true" if it's set and AvTimeComplete is nil.
 	Initialization      *URLType        `xml:"Initialization,omitempty"`
 	RepresentationIndex *URLType        `xml:"RepresentationIndex,omitempty"`
+	ExtraAttrs          Attrs           `xml:",any,attr"`
+	ExtraElements       []*Element      `xml:",any"`
 }
 
 //SegmentList contains a list of SegmentURL elements.
@@ -136,7 +206,8 @@ type SegmentList struct {
 	IndexRange          string           `xml:"indexRange,attr,omitempty"`               //Optional. ByteRange that contains the Segment Index in all Segments of the Representation.
 	IndexRangeExact     bool             `xml:"indexRangeExact,attr,omitempty"`          //Default: false. Must not be present if IndexRange isn't present.
 	AvTimeOffset        float64          `xml:"availabilityTimeOffset,attr,omitempty"`   //Optional.
-	AvTmeComplete       bool             `xml:"availabilityTimeComplete,attr,omitempty"` //Optional.
+	AvTimeComplete      *bool            `xml:"availabilityTimeComplete,attr,omitempty"` //Optional. Default: true. If false, Segments are available in chunks before they are complete.
+	AvTmeComplete       bool             `xml:"-"`                                       //Deprecated: use AvTimeComplete. Parse sets it if AvTimeComplete is true, and Encode writes availabilityTimeComplete="true" if it's set and AvTimeComplete is nil.
 	Duration            int              `xml:"duration,attr,omitempty"`
 	StartNumber         int              `xml:"startNumber,attr,omitempty"`
 	Initialization      *URLType         `xml:"Initialization,omitempty"`
@@ -144,14 +215,19 @@ type SegmentList struct {
 	SegmentTimeline     *SegmentTimeline `xml:"SegmentTimeline,omitempty"`
 	BitstreamSwitching  *URLType         `xml:"BitstreamSwitching,omitempty"`
 	SegmentURLs         []*SegmentURL    `xml:"SegmentURL,omitempty"`
+	ExtraAttrs          Attrs            `xml:",any,attr"`
+	ExtraElements       []*Element       `xml:",any"`
+	zeroStartNumber     bool             //startNumber="0" was parsed, which StartNumber can't tell from the default
 }
 
 //SegmentURL may contain the Media Segment URL.
 type SegmentURL struct {
-	Media      string `xml:"media,attr,omitempty"`      //Optional. Combined with MediaRange, specifies HTTP-URL for Media Segment. If not present, MediaRange must be present and it's combined with BaseURL
-	MediaRange string `xml:"mediaRange,attr,omitempty"` //Optional. If not present, Media Segment is the entire resource in Media
-	Index      string `xml:"index,attr,omitempty"`      //Optional.
-	IndexRange string `xml:"indexRange,attr,omitempty"`
+	Media         string     `xml:"media,attr,omitempty"`      //Optional. Combined with MediaRange, specifies HTTP-URL for Media Segment. If not present, MediaRange must be present and it's combined with BaseURL
+	MediaRange    string     `xml:"mediaRange,attr,omitempty"` //Optional. If not present, Media Segment is the entire resource in Media
+	Index         string     `xml:"index,attr,omitempty"`      //Optional.
+	IndexRange    string     `xml:"indexRange,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //SegmentTemplate specifies identifiers that are substituted by dynamic values assigned
@@ -163,7 +239,8 @@ type SegmentTemplate struct {
 	IndexRange             string           `xml:"indexRange,attr,omitempty"`               //Optional. ByteRange that contains the Segment Index in all Segments of the Representation.
 	IndexRangeExact        bool             `xml:"indexRangeExact,attr,omitempty"`          //Default: false. Must not be present if IndexRange isn't present.
 	AvTimeOffset           float64          `xml:"availabilityTimeOffset,attr,omitempty"`   //Optional.
-	AvTmeComplete          bool             `xml:"availabilityTimeComplete,attr,omitempty"` //Optional.
+	AvTimeComplete         *bool            `xml:"availabilityTimeComplete,attr,omitempty"` //Optional. Default: true. If false, Segments are available in chunks before they are complete.
+	AvTmeComplete          bool             `xml:"-"`                                       //Deprecated: use AvTimeComplete. Parse sets it if AvTimeComplete is true, and Encode writes availabilityTimeComplete="true" if it's set and AvTimeComplete is nil.
 	Duration               int              `xml:"duration,attr,omitempty"`
 	StartNumber            int              `xml:"startNumber,attr,omitempty"`
 	Media                  string           `xml:"media,attr,omitempty"`              //Optional. Template to create Media Segment List
@@ -174,81 +251,120 @@ type SegmentTemplate struct {
 	RepresentationIndex    *URLType         `xml:"RepresentationIndex,omitempty"`
 	SegmentTimeline        *SegmentTimeline `xml:"SegmentTimeline,omitempty"`
 	BitstreamSwitching     *URLType         `xml:"BitstreamSwitching,omitempty"`
+	ExtraAttrs             Attrs            `xml:",any,attr"`
+	ExtraElements          []*Element       `xml:",any"`
+	zeroStartNumber        bool             //startNumber="0" was parsed, which StartNumber can't tell from the default
 }
 
 //SegmentTimeline represents the earliest presentation time and duration for each Segment in the Representation.
 //It contains a list of S elements, each describing a sequence of continguous segments of identical MPD duration.
 //The order of the S elements must match the numbering order (time) of the corresponding Media Segments.
 type SegmentTimeline struct {
-	Segments Segments `xml:"S"` //Must have at least 1 S element.
+	Segments      Segments   `xml:"S"` //Must have at least 1 S element.
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //S is contained in a SegmentTimeline tag.
 type S struct {
-	T int `xml:"t,attr"` //Optional. Specifies MPD start time, in timescale units. Relative to the befinning of the Period.
-	D int `xml:"d,attr"` //Required. Segment duration int timescale units. Must not exceed the value of MPD.MaxSegmentDuration.
-	R int `xml:"r,attr"` //Default: 0. Specifies repeat count of number of following continguous segments with same duration as D.
+	T             int        `xml:"t,attr,omitempty"` //Optional. Specifies MPD start time, in timescale units. Relative to the befinning of the Period.
+	D             int        `xml:"d,attr"`           //Required. Segment duration int timescale units. Must not exceed the value of MPD.MaxSegmentDuration.
+	R             int        `xml:"r,attr,omitempty"` //Default: 0. Specifies repeat count of number of following continguous segments with same duration as D.
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //Subset restricts the combination of active AdaptationSets where an active
 //Adaptation Set is one for which the DASH client is presenting at least one of the
 //contained Representation. No subset should contain all the Adaptaion Sets.
 type Subset struct {
-	Contains CustomInt `xml:"contains,attr"` //Required. Whitespace separated list.
-	ID       string    `xml:"id,attr,omitempty"`
+	Contains      CustomInt  `xml:"contains,attr"` //Required. Whitespace separated list.
+	ID            string     `xml:"id,attr,omitempty"`
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //AdaptationSet represents a set of versions of one or more media streams.
 type AdaptationSet struct {
-	XlinkHref               string                 `xml:"http://www.w3.org/1999/xlink href,attr,omitempty"`
-	XlinkActuate            string                 `xml:"http://www.w3.org/1999/xlink actuate,attr,omitempty"` //Possible Values: 'onLoad', 'onRequest'. Default: onRequest.
-	ID                      int                    `xml:"id,attr,omitempty"`
-	Group                   int                    `xml:"group,attr,omitempty"`
-	Lang                    string                 `xml:"lang,attr,omitempty"`
-	ContentType             string                 `xml:"contentType,attr,omitempty"`
-	Par                     string                 `xml:"par,attr,omitempty"` //Optional. Picture Aspect Ratio. TODO:check specs for validation (regex)
-	MinBandwidth            int                    `xml:"minBandwidth,attr,omitempty"`
-	MaxBandwidth            int                    `xml:"maxBandwidth,attr,omitempty"`
-	MinWidth                int                    `xml:"minWidth,attr,omitempty"`
-	MaxWidth                int                    `xml:"maxWidth,attr,omitempty"`
-	MinHeight               int                    `xml:"minHeight,attr,omitempty"`
-	MaxHeight               int                    `xml:"maxHeight,attr,omitempty"`
-	MinFrameRate            string                 `xml:"minFrameRate,attr,omitempty"` //TODO:Check specs for validation (regex)
-	MaxFrameRate            string                 `xml:"maxFrameRate,attr,omitempty"`
-	SegmentAlignment        bool                   `xml:"segmentAlignment,attr,omitempty"`        //Default: false. TODO: check specs for validation. Accepts 0,1 or true,false
-	BitstreamSwitching      bool                   `xml:"bitstreamSwitching,attr,omitempty"`      //TODO: check specs for validation. Accepts 0,1 or true,false
-	SubsegmentAlignment     bool                   `xml:"subsegmentAlignment,attr,omitempty"`     //Default: false. TODO: check specs for validation
-	SubsegmentStartsWithSAP int                    `xml:"subsegmentStartsWithSap,attr,omitempty"` //Default: 0. TODO: check specs for validation
-	Profiles                string                 `xml:"profiles,attr,omitempty"`
-	Width                   int                    `xml:"width,attr,omitempty"`
-	Height                  int                    `xml:"height,attr,omitempty"`
-	Sar                     string                 `xml:"sar,attr,omitempty"`       //RatioType
-	FrameRate               string                 `xml:"frameRate,attr,omitempty"` //FrameRateType
-	AudioSamplingRate       string                 `xml:"audioSamplingRate,attr,omitempty"`
-	MimeType                string                 `xml:"mimeType,attr,omitempty"`
-	SegmentProfiles         string                 `xml:"segmentProfiles,attr,omitempty"`
-	Codecs                  string                 `xml:"codecs,attr,omitempty"`
-	MaxSAPPeriod            float64                `xml:"maximumSAPPeriod,attr,omitempty"` //seconds
-	StartWithSAP            int                    `xml:"startWithSAP,attr,omitempty"`     //SAPType
-	MaxPlayoutRate          float64                `xml:"maxPlayoutRate,attr,omitempty"`
-	CodingDependency        bool                   `xml:"codingDependency,attr,omitempty"`
-	ScanType                string                 `xml:"scanType,attr,omitempty"` //VideoScanType
-	CENCContentProtections  CENCContentProtections `xml:"ContentProtection,omitempty"`
-	FramePacking            []*Descriptor          `xml:"FramePacking,omitempty"`
-	AudioChannelConfig      []*Descriptor          `xml:"AudioChannelConfiguration,omitempty"`
-	EssentialProperty       []*Descriptor          `xml:"EssentialProperty,omitempty"`
-	SupplementalProperty    []*Descriptor          `xml:"SupplementalProperty,omitempty"`
-	InbandEventStream       []*Descriptor          `xml:"InbandEventStream,omitempty"`
-	Accessibility           []*Descriptor          `xml:"Accessibility,omitempty"`
-	Role                    []*Descriptor          `xml:"Role,omitempty"`
-	Rating                  []*Descriptor          `xml:"Rating,omitempty"`
-	ViewPoint               []*Descriptor          `xml:"Viewpoint,omitempty"`
-	ContentComponent        []*ContentComponent    `xml:"ContentComponent,omitempty"`
-	BaseURL                 []*BaseURL             `xml:"BaseURL,omitempty"`
-	SegmentBase             *SegmentBase           `xml:"SegmentBase,omitempty"`
-	SegmentList             *SegmentList           `xml:"SegmentList,omitempty"`
-	SegmentTemplate         *SegmentTemplate       `xml:"SegmentTemplate,omitempty"`
-	Representations         Representations        `xml:"Representation,omitempty"`
+	XlinkHref               string                   `xml:"http://www.w3.org/1999/xlink href,attr,omitempty"`
+	XlinkActuate            string                   `xml:"http://www.w3.org/1999/xlink actuate,attr,omitempty"` //Possible Values: 'onLoad', 'onRequest'. Default: onRequest.
+	ID                      int                      `xml:"id,attr,omitempty"`
+	Group                   int                      `xml:"group,attr,omitempty"`
+	Lang                    string                   `xml:"lang,attr,omitempty"`
+	ContentType             string                   `xml:"contentType,attr,omitempty"`
+	Par                     string                   `xml:"par,attr,omitempty"` //Optional. Picture Aspect Ratio. TODO:check specs for validation (regex)
+	MinBandwidth            int                      `xml:"minBandwidth,attr,omitempty"`
+	MaxBandwidth            int                      `xml:"maxBandwidth,attr,omitempty"`
+	MinWidth                int                      `xml:"minWidth,attr,omitempty"`
+	MaxWidth                int                      `xml:"maxWidth,attr,omitempty"`
+	MinHeight               int                      `xml:"minHeight,attr,omitempty"`
+	MaxHeight               int                      `xml:"maxHeight,attr,omitempty"`
+	MinFrameRate            string                   `xml:"minFrameRate,attr,omitempty"` //TODO:Check specs for validation (regex)
+	MaxFrameRate            string                   `xml:"maxFrameRate,attr,omitempty"`
+	SegmentAlignment        bool                     `xml:"segmentAlignment,attr,omitempty"`        //Default: false. TODO: check specs for validation. Accepts 0,1 or true,false
+	BitstreamSwitching      bool                     `xml:"bitstreamSwitching,attr,omitempty"`      //TODO: check specs for validation. Accepts 0,1 or true,false
+	SubsegmentAlignment     bool                     `xml:"subsegmentAlignment,attr,omitempty"`     //Default: false. TODO: check specs for validation
+	SubsegmentStartsWithSAP int                      `xml:"subsegmentStartsWithSap,attr,omitempty"` //Default: 0. TODO: check specs for validation
+	Profiles                string                   `xml:"profiles,attr,omitempty"`
+	Width                   int                      `xml:"width,attr,omitempty"`
+	Height                  int                      `xml:"height,attr,omitempty"`
+	Sar                     string                   `xml:"sar,attr,omitempty"`       //RatioType
+	FrameRate               string                   `xml:"frameRate,attr,omitempty"` //FrameRateType
+	AudioSamplingRate       string                   `xml:"audioSamplingRate,attr,omitempty"`
+	MimeType                string                   `xml:"mimeType,attr,omitempty"`
+	SegmentProfiles         string                   `xml:"segmentProfiles,attr,omitempty"`
+	Codecs                  string                   `xml:"codecs,attr,omitempty"`
+	MaxSAPPeriod            float64                  `xml:"maximumSAPPeriod,attr,omitempty"` //seconds
+	StartWithSAP            int                      `xml:"startWithSAP,attr,omitempty"`     //SAPType
+	MaxPlayoutRate          float64                  `xml:"maxPlayoutRate,attr,omitempty"`
+	CodingDependency        bool                     `xml:"codingDependency,attr,omitempty"`
+	ScanType                string                   `xml:"scanType,attr,omitempty"` //VideoScanType
+	FramePacking            []*Descriptor            `xml:"FramePacking,omitempty"`
+	AudioChannelConfig      []*Descriptor            `xml:"AudioChannelConfiguration,omitempty"`
+	CENCContentProtections  CENCContentProtections   `xml:"ContentProtection,omitempty"`
+	EssentialProperty       []*Descriptor            `xml:"EssentialProperty,omitempty"`
+	SupplementalProperty    []*Descriptor            `xml:"SupplementalProperty,omitempty"`
+	InbandEventStream       []*Descriptor            `xml:"InbandEventStream,omitempty"`
+	ProducerReferenceTime   []*ProducerReferenceTime `xml:"ProducerReferenceTime,omitempty"`
+	Resync                  []*Resync                `xml:"Resync,omitempty"`
+	Accessibility           []*Descriptor            `xml:"Accessibility,omitempty"`
+	Role                    []*Descriptor            `xml:"Role,omitempty"`
+	Rating                  []*Descriptor            `xml:"Rating,omitempty"`
+	ViewPoint               []*Descriptor            `xml:"Viewpoint,omitempty"`
+	ContentComponent        []*ContentComponent      `xml:"ContentComponent,omitempty"`
+	BaseURL                 []*BaseURL               `xml:"BaseURL,omitempty"`
+	SegmentBase             *SegmentBase             `xml:"SegmentBase,omitempty"`
+	SegmentList             *SegmentList             `xml:"SegmentList,omitempty"`
+	SegmentTemplate         *SegmentTemplate         `xml:"SegmentTemplate,omitempty"`
+	Representations         Representations          `xml:"Representation,omitempty"`
+	ExtraAttrs              Attrs                    `xml:",any,attr"`
+	ExtraElements           []*Element               `xml:",any"`
+}
+
+//ProducerReferenceTime maps a presentation time to the wall-clock time it was produced at,
+//which clients use to measure latency.
+type ProducerReferenceTime struct {
+	ID                int         `xml:"id,attr"`                          //Required.
+	Inband            bool        `xml:"inband,attr,omitempty"`            //Default: false. If true, 'prft' boxes are carried in the Segments.
+	Type              string      `xml:"type,attr,omitempty"`              //Optional. Default: "encoder". Possible Values: encoder, captured, application
+	ApplicationScheme string      `xml:"applicationScheme,attr,omitempty"` //Must be present when Type = "application".
+	WallClockTime     string      `xml:"wallClockTime,attr"`               //Required. Formatted as specified by the UTCTiming scheme.
+	PresTime          int64       `xml:"presentationTime,attr"`            //Required. In timescale units.
+	UTCTiming         *Descriptor `xml:"UTCTiming,omitempty"`              //Optional. Must be one of the MPD UTCTiming elements.
+	ExtraAttrs        Attrs       `xml:",any,attr"`
+	ExtraElements     []*Element  `xml:",any"`
+}
+
+//Resync signals resynchronization points inside the Segments, such as the start of each
+//CMAF chunk, where a client can start decoding.
+type Resync struct {
+	Type          int        `xml:"type,attr,omitempty"`   //Default: 0. SAPType of the resynchronization points, 0 to 3.
+	DT            int64      `xml:"dT,attr,omitempty"`     //Optional. Maximum duration between resynchronization points, in timescale units.
+	DImax         float64    `xml:"dImax,attr,omitempty"`  //Optional. Maximum distance between resynchronization points, in kbits.
+	DImin         float64    `xml:"dImin,attr,omitempty"`  //Default: 0. Minimum distance between resynchronization points, in kbits.
+	Marker        bool       `xml:"marker,attr,omitempty"` //Default: false. If true, each resynchronization point starts with an 'styp' box.
+	ExtraAttrs    Attrs      `xml:",any,attr"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //ContentProtection represents the root ContentProtection element.
@@ -259,6 +375,7 @@ type ContentProtection struct {
 	SchemeIDURI string   `xml:"schemeIdUri,attr,omitempty"`
 	Value       string   `xml:"value,attr,omitempty"`
 	DefaultKID  string   `xml:"cenc:default_KID,attr,omitempty"`
+	ExtraAttrs  Attrs    `xml:",any,attr"`
 }
 
 //CENCContentProtection represents the full ContentProtection element.
@@ -271,11 +388,12 @@ type ContentProtection struct {
 //but are not needed in MPD ContentProtection Descriptor elements.
 type CENCContentProtection struct {
 	ContentProtection
-	Pssh        *Pssh
-	Pro         *Pro
-	IsEncrypted string `xml:"mspr:IsEncrypted,omitempty"`
-	IVSize      int    `xml:"mspr:IV_size,omitempty"`
-	KID         string `xml:"mspr:kid,omitempty"`
+	Pssh          *Pssh
+	Pro           *Pro
+	IsEncrypted   string     `xml:"mspr:IsEncrypted,omitempty"`
+	IVSize        int        `xml:"mspr:IV_size,omitempty"`
+	KID           string     `xml:"mspr:kid,omitempty"`
+	ExtraElements []*Element `xml:",any"`
 }
 
 //Pssh (Protection System Specific Header) represents the optional cenc:pssh element
@@ -303,40 +421,46 @@ type ContentComponent struct {
 	Role          []*Descriptor `xml:"Role,omitempty"`
 	Rating        []*Descriptor `xml:"Rating,omitempty"`
 	ViewPoint     []*Descriptor `xml:"Viewpoint,omitempty"`
+	ExtraAttrs    Attrs         `xml:",any,attr"`
+	ExtraElements []*Element    `xml:",any"`
 }
 
 //Representation represents a deliverable encoded version of one or more media components.
 type Representation struct {
-	ID                      string                 `xml:"id,attr"`        //Required. It must not contain whitespace characters.
-	Bandwidth               int64                  `xml:"bandwidth,attr"` //Required.
-	QualityRanking          int                    `xml:"qualityRanking,attr,omitempty"`
-	DependencyID            string                 `xml:"dependencyId,attr,omitempty"`            //Whitespace separated list of int
-	MediaStreamsStructureID string                 `xml:"mediaStreamsStructureId,attr,omitempty"` //Whitespace separated list of int
-	Profiles                string                 `xml:"profiles,attr,omitempty"`
-	Width                   int                    `xml:"width,attr,omitempty"`
-	Height                  int                    `xml:"height,attr,omitempty"`
-	Sar                     string                 `xml:"sar,attr,omitempty"`       //RatioType
-	FrameRate               string                 `xml:"frameRate,attr,omitempty"` //FrameRateType
-	AudioSamplingRate       string                 `xml:"audioSamplingRate,attr,omitempty"`
-	MimeType                string                 `xml:"mimeType,attr,omitempty"`
-	SegmentProfiles         string                 `xml:"segmentProfiles,attr,omitempty"`
-	Codecs                  string                 `xml:"codecs,attr,omitempty"`
-	MaxSAPPeriod            float64                `xml:"maximumSAPPeriod,attr,omitempty"`
-	StartWithSAP            int                    `xml:"startWithSAP,attr,omitempty"` //SAPType
-	MaxPlayoutRate          float64                `xml:"maxPlayoutRate,attr,omitempty"`
-	CodingDependency        bool                   `xml:"codingDependency,attr,omitempty"`
-	ScanType                string                 `xml:"scanType,attr,omitempty"` //VideoScanType
-	CENCContentProtections  CENCContentProtections `xml:"ContentProtection,omitempty"`
-	FramePacking            []*Descriptor          `xml:"FramePacking,omitempty"`
-	AudioChannelConfig      []*Descriptor          `xml:"AudioChannelConfiguration,omitempty"`
-	EssentialProperty       []*Descriptor          `xml:"EssentialProperty,omitempty"`
-	SupplementalProperty    []*Descriptor          `xml:"SupplementalProperty,omitempty"`
-	InbandEventStream       []*Descriptor          `xml:"InbandEventStream,omitempty"`
-	BaseURL                 []*BaseURL             `xml:"BaseURL,omitempty"`
-	SubRepresentation       []*SubRepresentation   `xml:"SubRepresentation,omitempty"`
-	SegmentBase             *SegmentBase           `xml:"SegmentBase,omitempty"`
-	SegmentList             *SegmentList           `xml:"SegmentList,omitempty"`
-	SegmentTemplate         *SegmentTemplate       `xml:"SegmentTemplate,omitempty"`
+	ID                      string                   `xml:"id,attr"`        //Required. It must not contain whitespace characters.
+	Bandwidth               int64                    `xml:"bandwidth,attr"` //Required.
+	QualityRanking          int                      `xml:"qualityRanking,attr,omitempty"`
+	DependencyID            string                   `xml:"dependencyId,attr,omitempty"`            //Whitespace separated list of int
+	MediaStreamsStructureID string                   `xml:"mediaStreamsStructureId,attr,omitempty"` //Whitespace separated list of int
+	Profiles                string                   `xml:"profiles,attr,omitempty"`
+	Width                   int                      `xml:"width,attr,omitempty"`
+	Height                  int                      `xml:"height,attr,omitempty"`
+	Sar                     string                   `xml:"sar,attr,omitempty"`       //RatioType
+	FrameRate               string                   `xml:"frameRate,attr,omitempty"` //FrameRateType
+	AudioSamplingRate       string                   `xml:"audioSamplingRate,attr,omitempty"`
+	MimeType                string                   `xml:"mimeType,attr,omitempty"`
+	SegmentProfiles         string                   `xml:"segmentProfiles,attr,omitempty"`
+	Codecs                  string                   `xml:"codecs,attr,omitempty"`
+	MaxSAPPeriod            float64                  `xml:"maximumSAPPeriod,attr,omitempty"`
+	StartWithSAP            int                      `xml:"startWithSAP,attr,omitempty"` //SAPType
+	MaxPlayoutRate          float64                  `xml:"maxPlayoutRate,attr,omitempty"`
+	CodingDependency        bool                     `xml:"codingDependency,attr,omitempty"`
+	ScanType                string                   `xml:"scanType,attr,omitempty"` //VideoScanType
+	FramePacking            []*Descriptor            `xml:"FramePacking,omitempty"`
+	AudioChannelConfig      []*Descriptor            `xml:"AudioChannelConfiguration,omitempty"`
+	CENCContentProtections  CENCContentProtections   `xml:"ContentProtection,omitempty"`
+	EssentialProperty       []*Descriptor            `xml:"EssentialProperty,omitempty"`
+	SupplementalProperty    []*Descriptor            `xml:"SupplementalProperty,omitempty"`
+	InbandEventStream       []*Descriptor            `xml:"InbandEventStream,omitempty"`
+	ProducerReferenceTime   []*ProducerReferenceTime `xml:"ProducerReferenceTime,omitempty"`
+	Resync                  []*Resync                `xml:"Resync,omitempty"`
+	BaseURL                 []*BaseURL               `xml:"BaseURL,omitempty"`
+	SubRepresentation       []*SubRepresentation     `xml:"SubRepresentation,omitempty"`
+	SegmentBase             *SegmentBase             `xml:"SegmentBase,omitempty"`
+	SegmentList             *SegmentList             `xml:"SegmentList,omitempty"`
+	SegmentTemplate         *SegmentTemplate         `xml:"SegmentTemplate,omitempty"`
+	ExtraAttrs              Attrs                    `xml:",any,attr"`
+	ExtraElements           []*Element               `xml:",any"`
 }
 
 //SubRepresentation describes properties of one or several media content components
@@ -360,10 +484,12 @@ type SubRepresentation struct {
 	MaxPlayoutRate         float64                `xml:"maxPlayoutRate,attr,omitempty"`
 	CodingDependency       bool                   `xml:"codingDependency,attr,omitempty"`
 	ScanType               string                 `xml:"scanType,attr,omitempty"` //VideoScanType
-	CENCContentProtections CENCContentProtections `xml:"ContentProtection,omitempty"`
 	FramePacking           []*Descriptor          `xml:"FramePacking,omitempty"`
 	AudioChannelConfig     []*Descriptor          `xml:"AudioChannelConfiguration,omitempty"`
+	CENCContentProtections CENCContentProtections `xml:"ContentProtection,omitempty"`
 	EssentialProperty      []*Descriptor          `xml:"EssentialProperty,omitempty"`
 	SupplementalProperty   []*Descriptor          `xml:"SupplementalProperty,omitempty"`
 	InbandEventStream      []*Descriptor          `xml:"InbandEventStream,omitempty"`
+	ExtraAttrs             Attrs                  `xml:",any,attr"`
+	ExtraElements          []*Element             `xml:",any"`
 }
@@ -0,0 +1,206 @@
+package dash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ingest/manifest"
+)
+
+//Expand returns every segment of the SegmentTimeline as a S element with an explicit start time
+//and no repeat count. end is the end of the Period in timescale units, it's only used to resolve
+//a negative repeat count on the last S element.
+func (st *SegmentTimeline) Expand(end int) (Segments, error) {
+	if st == nil {
+		return nil, nil
+	}
+
+	starts := st.starts()
+	var segments Segments
+	for i, s := range st.Segments {
+		if s.D <= 0 {
+			return nil, fmt.Errorf("SegmentTimeline S element %d must have a positive duration", i)
+		}
+		start := starts[i]
+
+		repeat := s.R
+		if repeat < 0 {
+			//a negative repeat count lasts until the next S element or the end of the Period
+			until := end
+			if i < len(st.Segments)-1 {
+				if st.Segments[i+1].T == 0 {
+					return nil, fmt.Errorf("SegmentTimeline S element %d must have t when the previous one has a negative repeat count", i+1)
+				}
+				until = st.Segments[i+1].T
+			} else if end <= 0 {
+				return nil, errors.New("SegmentTimeline needs the Period end to expand a negative repeat count on the last S element")
+			}
+			repeat = (until-start+s.D-1)/s.D - 1
+		}
+
+		for r := 0; r <= repeat; r++ {
+			segments = append(segments, &S{T: start + r*s.D, D: s.D})
+		}
+	}
+	return segments, nil
+}
+
+//Compact rewrites the SegmentTimeline into the fewest S elements: consecutive segments with
+//the same duration are folded into repeat counts, and t is only kept where a segment doesn't
+//start at the end of the previous one. S elements with a negative repeat count are kept as is.
+func (st *SegmentTimeline) Compact() {
+	if st == nil {
+		return
+	}
+
+	starts := st.starts()
+	var compact Segments
+	var end int
+	for i, s := range st.Segments {
+		start := starts[i]
+
+		var last *S
+		if len(compact) > 0 {
+			last = compact[len(compact)-1]
+		}
+
+		switch {
+		case s.R < 0 || s.D <= 0:
+			compact = append(compact, &S{T: start, D: s.D, R: s.R})
+		case last != nil && last.R >= 0 && last.D == s.D && end == start:
+			last.R += s.R + 1
+		case last != nil && last.R >= 0 && end == start:
+			compact = append(compact, &S{D: s.D, R: s.R})
+		default:
+			compact = append(compact, &S{T: start, D: s.D, R: s.R})
+		}
+
+		if s.R >= 0 {
+			end = start + s.D*(s.R+1)
+		}
+	}
+	st.Segments = compact
+}
+
+//Validate checks the SegmentTimeline covers [start, end) without gaps or overlaps. start and end
+//are in timescale units, usually the presentationTimeOffset and presentationTimeOffset plus the
+//Period duration, see MPD.ValidateTimelines. Coverage of the Period isn't checked if end is zero.
+func (st *SegmentTimeline) Validate(start, end int) error {
+	if st == nil || len(st.Segments) == 0 {
+		return errors.New("SegmentTimeline must have at least one S element")
+	}
+
+	segments, err := st.Expand(end)
+	if err != nil {
+		return err
+	}
+
+	buf := manifest.NewBufWrapper()
+	if segments[0].T > start {
+		buf.WriteString(fmt.Sprintf("SegmentTimeline starts at %d, after the start of the Period at %d.\n", segments[0].T, start))
+	}
+
+	for i := 1; i < len(segments); i++ {
+		prevEnd := segments[i-1].T + segments[i-1].D
+		switch {
+		case segments[i].T > prevEnd:
+			buf.WriteString(fmt.Sprintf("SegmentTimeline has a gap of %d between %d and %d.\n", segments[i].T-prevEnd, prevEnd, segments[i].T))
+		case segments[i].T < prevEnd:
+			buf.WriteString(fmt.Sprintf("SegmentTimeline segment at %d overlaps the previous segment ending at %d.\n", segments[i].T, prevEnd))
+		}
+	}
+
+	last := segments[len(segments)-1]
+	if end > 0 && last.T+last.D < end {
+		buf.WriteString(fmt.Sprintf("SegmentTimeline ends at %d, before the end of the Period at %d.\n", last.T+last.D, end))
+	}
+
+	if buf.Buf.String() != "" {
+		return errors.New(buf.Buf.String())
+	}
+	return buf.Err
+}
+
+//ValidateTimelines validates the SegmentTimeline of every Representation with SegmentTimeline.Validate,
+//against the presentationTimeOffset and the duration of its Period. The duration of a Period comes from
+//its duration, the start of the next Period or the MPD mediaPresentationDuration. Coverage of a
+//Period with an unknown duration, such as the last Period of a dynamic MPD, isn't checked.
+func (m *MPD) ValidateTimelines() error {
+	if m == nil || len(m.Periods) == 0 {
+		return errors.New("MPD must have at least one Period element")
+	}
+
+	buf := manifest.NewBufWrapper()
+	var start time.Duration
+	for i, p := range m.Periods {
+		if p.Start != nil {
+			start = p.Start.Duration
+		}
+
+		var duration time.Duration
+		switch {
+		case p.Duration != nil:
+			duration = p.Duration.Duration
+		case i < len(m.Periods)-1 && m.Periods[i+1].Start != nil:
+			duration = m.Periods[i+1].Start.Duration - start
+		case i == len(m.Periods)-1 && m.MediaPresDuration != nil:
+			duration = m.MediaPresDuration.Duration - start
+		}
+		p.validateTimelines(buf, i, duration)
+		start += duration
+	}
+
+	if buf.Buf.String() != "" {
+		return errors.New(buf.Buf.String())
+	}
+	return buf.Err
+}
+
+//validateTimelines validates the SegmentTimelines of the i-th Period, lasting duration. A SegmentTimeline
+//inherited by several Representations is only validated once.
+func (p *Period) validateTimelines(buf *manifest.BufWrapper, i int, duration time.Duration) {
+	seen := make(map[*SegmentTimeline]bool)
+	for _, as := range p.AdaptationSets {
+		for _, rep := range as.Representations {
+			var timeline *SegmentTimeline
+			var timescale int
+			var pto int64
+			if st := effectiveTemplate(p, as, rep); st != nil && st.SegmentTimeline != nil {
+				timeline, timescale, pto = st.SegmentTimeline, st.Timescale, st.PresTimeOffset
+			} else if sl := effectiveList(p, as, rep); sl != nil && sl.SegmentTimeline != nil {
+				timeline, timescale, pto = sl.SegmentTimeline, sl.Timescale, sl.PresTimeOffset
+			}
+			if timeline == nil || seen[timeline] {
+				continue
+			}
+			seen[timeline] = true
+
+			var end int
+			if duration > 0 {
+				end = int(pto + toTimescale(duration, timescale))
+			}
+			if err := timeline.Validate(int(pto), end); err != nil {
+				for _, msg := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+					buf.WriteString(fmt.Sprintf("Period %d Representation %s: %s\n", i, rep.ID, msg))
+				}
+			}
+		}
+	}
+}
+
+//starts returns the start time of every S element. A S element without t
+//starts where the previous one ends.
+func (st *SegmentTimeline) starts() []int {
+	starts := make([]int, len(st.Segments))
+	var t int
+	for i, s := range st.Segments {
+		if i == 0 || s.T != 0 {
+			t = s.T
+		}
+		starts[i] = t
+		t += s.D * (s.R + 1)
+	}
+	return starts
+}
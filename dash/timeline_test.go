@@ -0,0 +1,110 @@
+package dash
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	st := &SegmentTimeline{Segments: Segments{
+		&S{T: 100, D: 10, R: 1},
+		&S{D: 20},
+		&S{T: 200, D: 30, R: -1},
+		&S{T: 300, D: 50, R: -1},
+	}}
+
+	segments, err := st.Expand(400)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := Segments{
+		&S{T: 100, D: 10}, &S{T: 110, D: 10}, &S{T: 120, D: 20},
+		&S{T: 200, D: 30}, &S{T: 230, D: 30}, &S{T: 260, D: 30}, &S{T: 290, D: 30},
+		&S{T: 300, D: 50}, &S{T: 350, D: 50},
+	}
+	if !reflect.DeepEqual(segments, expect) {
+		t.Errorf("Expected %v, but got %v", expect, segments)
+	}
+
+	if _, err := st.Expand(0); err == nil {
+		t.Error("Expected error expanding a trailing negative repeat count without the Period end")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	st := &SegmentTimeline{}
+	for i := 0; i < 10; i++ {
+		st.Segments = append(st.Segments, &S{T: i * 2000, D: 2000})
+	}
+	st.Segments = append(st.Segments, &S{T: 20000, D: 1500}, &S{T: 30000, D: 2000}, &S{T: 32000, D: 2000, R: 2})
+
+	st.Compact()
+	expect := Segments{&S{T: 0, D: 2000, R: 9}, &S{D: 1500}, &S{T: 30000, D: 2000, R: 3}}
+	if !reflect.DeepEqual(st.Segments, expect) {
+		t.Errorf("Expected %v, but got %v", expect, st.Segments)
+	}
+
+	segments, err := st.Expand(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 15 || segments[10].T != 20000 || segments[11].T != 30000 {
+		t.Errorf("Expected compacted timeline to expand into the original segments, but got %v", segments)
+	}
+}
+
+func TestValidateTimeline(t *testing.T) {
+	st := &SegmentTimeline{Segments: Segments{&S{T: 0, D: 10, R: 9}}}
+	if err := st.Validate(0, 100); err != nil {
+		t.Errorf("Expected err to be nil, but got %s", err)
+	}
+
+	st = &SegmentTimeline{Segments: Segments{
+		&S{T: 10, D: 10},
+		&S{T: 30, D: 10},
+		&S{T: 35, D: 10},
+	}}
+	err := st.Validate(0, 100)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, msg := range []string{"starts at 10", "gap of 10", "overlaps", "before the end of the Period"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to contain %q, but got %s", msg, err)
+		}
+	}
+
+	if err := (&SegmentTimeline{}).Validate(0, 0); err == nil {
+		t.Error("Expected error for empty SegmentTimeline")
+	}
+}
+
+func TestValidateTimelines(t *testing.T) {
+	//the audio timeline covers 19.84s of the 20s Period
+	mpd := getMultiPeriodMPD()
+	err := mpd.ValidateTimelines()
+	if err == nil || !strings.Contains(err.Error(), "Period 0 Representation audio: SegmentTimeline ends at 952320, before the end of the Period at 960000") {
+		t.Errorf("Expected the audio timeline to not cover the Period, but got %v", err)
+	}
+
+	mpd.Periods[0].AdaptationSets[1].Representations[0].SegmentTemplate.SegmentTimeline.Segments[0].R = 10
+	if err := mpd.ValidateTimelines(); err != nil {
+		t.Errorf("Expected err to be nil, but got %s", err)
+	}
+
+	split, err := Split(mpd, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := split.ValidateTimelines(); err != nil {
+		t.Errorf("Expected split Periods to be covered by their timelines, but got %s", err)
+	}
+	split.Periods[0].Duration.Duration = 12 * time.Second
+	split.Periods[1].Start.Duration = 12 * time.Second
+	if err := split.ValidateTimelines(); err == nil || !strings.Contains(err.Error(), "Period 0 Representation audio") {
+		t.Errorf("Expected the first audio timeline to not cover a 12s Period, but got %v", err)
+	}
+}
@@ -73,7 +73,12 @@ func (c *CustomDuration) UnmarshalXMLAttr(attr xml.Attr) (err error) {
 //MarshalXMLAttr implementes MarshalerAttr interface for CustomDuration
 func (c *CustomDuration) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 	attr := xml.Attr{Name: name}
-	attr.Value = fmt.Sprintf("PT%s", strings.ToUpper(c.Duration.String()))
+	d := c.Duration.String()
+	if c.Duration != 0 && c.Duration > -time.Second && c.Duration < time.Second {
+		//Duration.String uses MS, US and NS units below a second, which xs:duration doesn't have
+		d = strconv.FormatFloat(c.Duration.Seconds(), 'f', -1, 64) + "s"
+	}
+	attr.Value = fmt.Sprintf("PT%s", strings.ToUpper(d))
 	return attr, nil
 }
 
@@ -109,3 +114,214 @@ func (c *CustomInt) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 	}
 	return attr, nil
 }
+
+//Attrs holds the attributes of an element that aren't modelled by its struct, such as DVB or
+//SCTE 214 extensions, so they are written back by Encode. Namespace declarations are dropped,
+//Encode declares the namespaces of a parsed file on the MPD element.
+type Attrs []xml.Attr
+
+//UnmarshalXMLAttr implementes UnmarshalerAttr interface for Attrs
+func (a *Attrs) UnmarshalXMLAttr(attr xml.Attr) error {
+	if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+		return nil
+	}
+	*a = append(*a, attr)
+	return nil
+}
+
+//Element is an XML element that isn't modelled by the dash structs, such as Label, Preselection
+//or elements from other namespaces. It keeps its namespace, attributes, text and children,
+//so it's written back by Encode.
+type Element struct {
+	XMLName  xml.Name
+	Attrs    Attrs      `xml:",any,attr"`
+	Value    string     `xml:",chardata"`
+	Elements []*Element `xml:",any"`
+
+	offset   int64     //Input offset of the element, to find its position among the elements of its parent.
+	position *position //Position among the elements of its parent, written back by Encode.
+}
+
+//UnmarshalXML implements Unmarshaler interface for Element. It records the input offset of the element
+//and drops text of whitespace only, which is indentation of its children.
+func (el *Element) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type element Element
+	el.offset = d.InputOffset()
+	if err := d.DecodeElement((*element)(el), &start); err != nil {
+		return err
+	}
+	if strings.TrimSpace(el.Value) == "" {
+		el.Value = ""
+	}
+	return nil
+}
+
+//MarshalXML implements Marshaler interface for Element. An element with a position is preceded by a
+//marker, so MPD.MarshalXML moves it back to its position.
+func (el *Element) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type element Element
+	if el.XMLName.Local != "" {
+		start.Name = el.XMLName
+	}
+	if el.position != nil {
+		if err := e.EncodeToken(el.position.marker()); err != nil {
+			return err
+		}
+	}
+	return e.EncodeElement((*element)(el), start)
+}
+
+//UnmarshalXML implements Unmarshaler interface for CENCContentProtection. The cenc and mspr
+//fields are tagged with prefixed names so they encode as expected, but the decoder matches
+//them by namespace, so they are moved from ExtraAttrs and ExtraElements after decoding.
+func (c *CENCContentProtection) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type contentProtection CENCContentProtection
+	if err := d.DecodeElement((*contentProtection)(c), &start); err != nil {
+		return err
+	}
+
+	var attrs Attrs
+	for _, attr := range c.ExtraAttrs {
+		if attr.Name.Space == cencNS && attr.Name.Local == "default_KID" {
+			c.XMLNsCenc = cencNS
+			c.DefaultKID = attr.Value
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	c.ExtraAttrs = attrs
+
+	var elements []*Element
+	for _, e := range c.ExtraElements {
+		switch {
+		case e.XMLName.Space == cencNS && e.XMLName.Local == "pssh":
+			c.XMLNsCenc = cencNS
+			c.Pssh = &Pssh{XMLName: xml.Name{Local: "pssh", Space: "cenc"}, Value: e.Value}
+		case e.XMLName.Space == msprNS && e.XMLName.Local == "pro":
+			c.XMLNsMspr = msprNS
+			c.Pro = &Pro{XMLName: xml.Name{Local: "pro", Space: "mspr"}, Value: e.Value}
+		case e.XMLName.Space == msprNS && e.XMLName.Local == "IsEncrypted":
+			c.XMLNsMspr = msprNS
+			c.IsEncrypted = e.Value
+		case e.XMLName.Space == msprNS && e.XMLName.Local == "IV_size":
+			size, err := strconv.Atoi(e.Value)
+			if err != nil {
+				return fmt.Errorf("invalid mspr:IV_size: %v", err)
+			}
+			c.XMLNsMspr = msprNS
+			c.IVSize = size
+		case e.XMLName.Space == msprNS && e.XMLName.Local == "kid":
+			c.XMLNsMspr = msprNS
+			c.KID = e.Value
+		default:
+			elements = append(elements, e)
+		}
+	}
+	c.ExtraElements = elements
+	return nil
+}
+
+//explicitZero returns true if the attribute name of start is 0, which an int field with omitempty
+//can't tell from an absent attribute.
+func explicitZero(start xml.StartElement, name string) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			n, err := strconv.Atoi(strings.TrimSpace(attr.Value))
+			return err == nil && n == 0
+		}
+	}
+	return false
+}
+
+//zeroAttr returns start with the attribute name set to 0.
+func zeroAttr(start xml.StartElement, name string) xml.StartElement {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: "0"})
+	return start
+}
+
+//availabilityTimeComplete returns start with availabilityTimeComplete="true" if only the deprecated
+//AvTmeComplete field is set.
+func availabilityTimeComplete(start xml.StartElement, complete *bool, deprecated bool) xml.StartElement {
+	if complete == nil && deprecated {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "availabilityTimeComplete"}, Value: "true"})
+	}
+	return start
+}
+
+//UnmarshalXML implements Unmarshaler interface for SegmentBase, to set the deprecated AvTmeComplete.
+func (s *SegmentBase) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type segmentBase SegmentBase
+	if err := d.DecodeElement((*segmentBase)(s), &start); err != nil {
+		return err
+	}
+	s.AvTmeComplete = s.AvTimeComplete != nil && *s.AvTimeComplete
+	return nil
+}
+
+//MarshalXML implements Marshaler interface for SegmentBase, to write the deprecated AvTmeComplete.
+func (s *SegmentBase) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type segmentBase SegmentBase
+	start = availabilityTimeComplete(start, s.AvTimeComplete, s.AvTmeComplete)
+	return e.EncodeElement((*segmentBase)(s), start)
+}
+
+//UnmarshalXML implements Unmarshaler interface for SegmentTemplate, to keep a startNumber of 0.
+func (s *SegmentTemplate) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type segmentTemplate SegmentTemplate
+	if err := d.DecodeElement((*segmentTemplate)(s), &start); err != nil {
+		return err
+	}
+	s.zeroStartNumber = explicitZero(start, "startNumber")
+	s.AvTmeComplete = s.AvTimeComplete != nil && *s.AvTimeComplete
+	return nil
+}
+
+//MarshalXML implements Marshaler interface for SegmentTemplate, to write back a startNumber of 0.
+func (s *SegmentTemplate) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type segmentTemplate SegmentTemplate
+	if s.zeroStartNumber && s.StartNumber == 0 {
+		start = zeroAttr(start, "startNumber")
+	}
+	start = availabilityTimeComplete(start, s.AvTimeComplete, s.AvTmeComplete)
+	return e.EncodeElement((*segmentTemplate)(s), start)
+}
+
+//UnmarshalXML implements Unmarshaler interface for SegmentList, to keep a startNumber of 0.
+func (s *SegmentList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type segmentList SegmentList
+	if err := d.DecodeElement((*segmentList)(s), &start); err != nil {
+		return err
+	}
+	s.zeroStartNumber = explicitZero(start, "startNumber")
+	s.AvTmeComplete = s.AvTimeComplete != nil && *s.AvTimeComplete
+	return nil
+}
+
+//MarshalXML implements Marshaler interface for SegmentList, to write back a startNumber of 0.
+func (s *SegmentList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type segmentList SegmentList
+	if s.zeroStartNumber && s.StartNumber == 0 {
+		start = zeroAttr(start, "startNumber")
+	}
+	start = availabilityTimeComplete(start, s.AvTimeComplete, s.AvTmeComplete)
+	return e.EncodeElement((*segmentList)(s), start)
+}
+
+//UnmarshalXML implements Unmarshaler interface for Event, to keep an id of 0.
+func (ev *Event) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type event Event
+	if err := d.DecodeElement((*event)(ev), &start); err != nil {
+		return err
+	}
+	ev.zeroID = explicitZero(start, "id")
+	return nil
+}
+
+//MarshalXML implements Marshaler interface for Event, to write back an id of 0.
+func (ev *Event) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type event Event
+	if ev.zeroID && ev.ID == 0 {
+		start = zeroAttr(start, "id")
+	}
+	return e.EncodeElement((*event)(ev), start)
+}
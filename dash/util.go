@@ -118,6 +118,28 @@ func (m *MPD) validateMPD(buf *manifest.BufWrapper) {
 				metric.validate(buf)
 			}
 		}
+		if m.PatchLocation != nil {
+			if m.ID == "" {
+				buf.WriteString("MPD field ID must be present when PatchLocation is present.\n")
+			}
+			for _, pl := range m.PatchLocation {
+				if pl.URL == "" {
+					buf.WriteString("PatchLocation field URL is required.\n")
+				}
+			}
+		}
+		if m.UTCTiming != nil {
+			for _, ut := range m.UTCTiming {
+				ut.validate(buf, "UTCTiming")
+			}
+		}
+		for _, b := range m.BaseURL {
+			b.validate(buf)
+		}
+		for _, sd := range m.ServiceDescription {
+			sd.validate(buf)
+		}
+		m.validateLowLatency(buf)
 		//validate Period
 		if m.Periods != nil {
 			for _, period := range m.Periods {
@@ -145,6 +167,15 @@ func (p *Period) validate(buf *manifest.BufWrapper, mpdType string) {
 	if p.SegmentList != nil {
 		p.SegmentList.validate(buf)
 	}
+	if p.SegmentTemplate != nil {
+		p.SegmentTemplate.validate(buf)
+	}
+	for _, b := range p.BaseURL {
+		b.validate(buf)
+	}
+	for _, es := range p.EventStream {
+		validateXlinkActuate(buf, "EventStream", es.XlinkActuate)
+	}
 	//validate AdaptationSets
 	if p.AdaptationSets != nil {
 		for _, adaptationSet := range p.AdaptationSets {
@@ -162,9 +193,19 @@ func (p *Period) validate(buf *manifest.BufWrapper, mpdType string) {
 
 func (s *SegmentList) validate(buf *manifest.BufWrapper) {
 	validateXlinkActuate(buf, "SegmentList", s.XlinkActuate)
+	validateAvailabilityTime(buf, "SegmentList", s.AvTimeOffset, s.AvTimeComplete)
+}
+
+func (s *SegmentTemplate) validate(buf *manifest.BufWrapper) {
+	validateAvailabilityTime(buf, "SegmentTemplate", s.AvTimeOffset, s.AvTimeComplete)
+}
+
+func (b *BaseURL) validate(buf *manifest.BufWrapper) {
+	validateAvailabilityTime(buf, "BaseURL", b.AvTimeOffset, b.AvTimeComplete)
 }
 
 func (a *AdaptationSet) validate(buf *manifest.BufWrapper) {
+	validateXlinkActuate(buf, "AdaptationSet", a.XlinkActuate)
 	validateScanType(buf, "AdaptationSet", a.ScanType)
 
 	if a.Accessibility != nil {
@@ -212,6 +253,15 @@ func (a *AdaptationSet) validate(buf *manifest.BufWrapper) {
 			v.validate(buf, "Viewpoint")
 		}
 	}
+	for _, prt := range a.ProducerReferenceTime {
+		prt.validate(buf)
+	}
+	for _, rs := range a.Resync {
+		rs.validate(buf)
+	}
+	for _, b := range a.BaseURL {
+		b.validate(buf)
+	}
 	if a.Representations != nil {
 		for _, re := range a.Representations {
 			re.validate(buf)
@@ -221,6 +271,12 @@ func (a *AdaptationSet) validate(buf *manifest.BufWrapper) {
 	if a.SegmentBase != nil {
 		a.SegmentBase.validate(buf)
 	}
+	if a.SegmentList != nil {
+		a.SegmentList.validate(buf)
+	}
+	if a.SegmentTemplate != nil {
+		a.SegmentTemplate.validate(buf)
+	}
 	//check if only one out of SegmentBase, SegmentList and SegmentTemplate is present
 	if !validateSegmentPresence(a.SegmentBase, a.SegmentTemplate, a.SegmentList) {
 		buf.WriteString("At most one of the three, SegmentBase, SegmentTemplate and SegmentList shall be present in AdaptationSet element.\n")
@@ -261,10 +317,25 @@ func (r *Representation) validate(buf *manifest.BufWrapper) {
 			sp.validate(buf, "SupplementalProperty")
 		}
 	}
+	for _, prt := range r.ProducerReferenceTime {
+		prt.validate(buf)
+	}
+	for _, rs := range r.Resync {
+		rs.validate(buf)
+	}
+	for _, b := range r.BaseURL {
+		b.validate(buf)
+	}
 
 	validateScanType(buf, "Representation", r.ScanType)
 
 	r.SegmentBase.validate(buf)
+	if r.SegmentList != nil {
+		r.SegmentList.validate(buf)
+	}
+	if r.SegmentTemplate != nil {
+		r.SegmentTemplate.validate(buf)
+	}
 
 	if r.SubRepresentation != nil {
 		for _, sr := range r.SubRepresentation {
@@ -291,6 +362,99 @@ func (s *SegmentBase) validate(buf *manifest.BufWrapper) {
 		if s.IndexRange == "" && s.IndexRangeExact {
 			buf.WriteString("SegmentBase element field IndexRangeExact must not be present if IndexRange isn't specified.\n")
 		}
+		validateAvailabilityTime(buf, "SegmentBase", s.AvTimeOffset, s.AvTimeComplete)
+	}
+}
+
+func (s *ServiceDescription) validate(buf *manifest.BufWrapper) {
+	for _, sc := range s.Scope {
+		sc.validate(buf, "Scope")
+	}
+	for _, l := range s.Latency {
+		if (l.Min != 0 && l.Target != 0 && l.Min > l.Target) || (l.Max != 0 && l.Target > l.Max) {
+			buf.WriteString("Latency field Target must be between Min and Max.\n")
+		}
+		if l.Min != 0 && l.Max != 0 && l.Min > l.Max {
+			buf.WriteString("Latency field Min must not be greater than Max.\n")
+		}
+	}
+	for _, pr := range s.PlaybackRate {
+		if pr.Min < 0 || pr.Max < 0 {
+			buf.WriteString("PlaybackRate fields Min and Max must not be negative.\n")
+		}
+		if pr.Max != 0 && pr.Min > pr.Max {
+			buf.WriteString("PlaybackRate field Min must not be greater than Max.\n")
+		}
+	}
+}
+
+func (p *ProducerReferenceTime) validate(buf *manifest.BufWrapper) {
+	if p.WallClockTime == "" {
+		buf.WriteString("ProducerReferenceTime field WallClockTime is required.\n")
+	}
+	switch p.Type {
+	case "", "encoder", "captured":
+	case "application":
+		if p.ApplicationScheme == "" {
+			buf.WriteString("ProducerReferenceTime field ApplicationScheme must be present when Type = 'application'.\n")
+		}
+	default:
+		buf.WriteString("Possible values for ProducerReferenceTime field Type are 'encoder', 'captured' and 'application'.\n")
+	}
+	if p.UTCTiming != nil {
+		p.UTCTiming.validate(buf, "UTCTiming")
+	}
+}
+
+func (r *Resync) validate(buf *manifest.BufWrapper) {
+	if r.Type < 0 || r.Type > 3 {
+		buf.WriteString("Resync field Type accepts values from 0 to 3.\n")
+	}
+	if r.DImax != 0 && r.DImin > r.DImax {
+		buf.WriteString("Resync field DImin must not be greater than DImax.\n")
+	}
+}
+
+//validateLowLatency checks the low latency elements of the MPD are consistent with each other:
+//ProducerReferenceTimes need the MPD to have the UTCTiming their wall-clock times refer to,
+//Latency must be measured against one of them, and Segments can only be available before
+//they are complete when Type = 'dynamic'.
+func (m *MPD) validateLowLatency(buf *manifest.BufWrapper) {
+	prts := make(map[int]*ProducerReferenceTime)
+	incomplete := avTimeIncomplete(m.BaseURL)
+	for _, p := range m.Periods {
+		incomplete = incomplete || avTimeIncomplete(p.BaseURL) || segmentsIncomplete(p.SegmentBase, p.SegmentTemplate, p.SegmentList)
+		for _, a := range p.AdaptationSets {
+			incomplete = incomplete || avTimeIncomplete(a.BaseURL) || segmentsIncomplete(a.SegmentBase, a.SegmentTemplate, a.SegmentList)
+			for _, prt := range a.ProducerReferenceTime {
+				prts[prt.ID] = prt
+			}
+			for _, r := range a.Representations {
+				incomplete = incomplete || avTimeIncomplete(r.BaseURL) || segmentsIncomplete(r.SegmentBase, r.SegmentTemplate, r.SegmentList)
+				for _, prt := range r.ProducerReferenceTime {
+					prts[prt.ID] = prt
+				}
+			}
+		}
+	}
+
+	if len(prts) > 0 && len(m.UTCTiming) == 0 {
+		buf.WriteString("MPD field UTCTiming must be present when ProducerReferenceTime is present.\n")
+	}
+	for _, prt := range prts {
+		if prt.UTCTiming != nil && len(m.UTCTiming) > 0 && !containsDescriptor(m.UTCTiming, prt.UTCTiming) {
+			buf.WriteString(fmt.Sprintf("ProducerReferenceTime %d field UTCTiming must be one of the MPD UTCTiming elements.\n", prt.ID))
+		}
+	}
+	for _, sd := range m.ServiceDescription {
+		for _, l := range sd.Latency {
+			if l.ReferenceID != nil && prts[*l.ReferenceID] == nil {
+				buf.WriteString(fmt.Sprintf("Latency field ReferenceID %d must be the ID of a ProducerReferenceTime.\n", *l.ReferenceID))
+			}
+		}
+	}
+	if incomplete && !strings.EqualFold(m.Type, "dynamic") {
+		buf.WriteString("AvTimeComplete must not be 'false' when Type = 'static'.\n")
 	}
 }
 
@@ -329,6 +493,51 @@ func validateScanType(buf *manifest.BufWrapper, element string, scanType string)
 	}
 }
 
+//validateAvailabilityTime checks the availabilityTimeOffset and availabilityTimeComplete of an element.
+//Segments that are available before they are complete are announced ahead of their availability
+//start time, so availabilityTimeOffset must be present with availabilityTimeComplete = 'false'.
+func validateAvailabilityTime(buf *manifest.BufWrapper, element string, offset float64, complete *bool) {
+	if offset < 0 {
+		buf.WriteString(fmt.Sprintf("%s field AvTimeOffset must not be negative.\n", element))
+	}
+	if complete != nil && !*complete && offset == 0 {
+		buf.WriteString(fmt.Sprintf("%s field AvTimeOffset must be present when AvTimeComplete = 'false'.\n", element))
+	}
+}
+
+//avTimeIncomplete reports whether any BaseURL has availabilityTimeComplete = 'false'.
+func avTimeIncomplete(baseURLs []*BaseURL) bool {
+	for _, b := range baseURLs {
+		if b.AvTimeComplete != nil && !*b.AvTimeComplete {
+			return true
+		}
+	}
+	return false
+}
+
+//segmentsIncomplete reports whether the segment information has availabilityTimeComplete = 'false'.
+func segmentsIncomplete(sb *SegmentBase, st *SegmentTemplate, sl *SegmentList) bool {
+	var complete *bool
+	switch {
+	case sb != nil:
+		complete = sb.AvTimeComplete
+	case st != nil:
+		complete = st.AvTimeComplete
+	case sl != nil:
+		complete = sl.AvTimeComplete
+	}
+	return complete != nil && !*complete
+}
+
+func containsDescriptor(descriptors []*Descriptor, d *Descriptor) bool {
+	for _, c := range descriptors {
+		if c.SchemeIDURI == d.SchemeIDURI && c.Value == d.Value {
+			return true
+		}
+	}
+	return false
+}
+
 //checks if only one out of SegmentBase, SegmentList and SegmentTemplate is present
 func validateSegmentPresence(sb *SegmentBase, st *SegmentTemplate, sl *SegmentList) bool {
 	var segment int
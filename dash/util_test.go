@@ -60,3 +60,40 @@ func TestDescriptor(t *testing.T) {
 		t.Error("Expecting 'SchemeIdURI is required' error")
 	}
 }
+
+func TestLowLatency(t *testing.T) {
+	complete := false
+	referenceID := 1
+	mpd := NewMPD("profile", time.Second)
+	mpd.ServiceDescription = []*ServiceDescription{&ServiceDescription{
+		Latency:      []*Latency{&Latency{ReferenceID: &referenceID, Target: 1000, Min: 2000, Max: 6000}},
+		PlaybackRate: []*PlaybackRate{&PlaybackRate{Min: 1.04, Max: 0.96}},
+	}}
+	mpd.Periods = Periods{&Period{
+		AdaptationSets: AdaptationSets{&AdaptationSet{
+			ProducerReferenceTime: []*ProducerReferenceTime{&ProducerReferenceTime{Type: "application"}},
+			Resync:                []*Resync{&Resync{Type: 4}},
+			SegmentTemplate:       &SegmentTemplate{AvTimeComplete: &complete},
+		}},
+	}}
+
+	_, err := mpd.Encode()
+	if err == nil {
+		t.Fatal("Expecting low latency errors")
+	}
+	for _, e := range []string{
+		"Latency field Target must be between Min and Max",
+		"PlaybackRate field Min must not be greater than Max",
+		"ProducerReferenceTime field WallClockTime is required",
+		"ProducerReferenceTime field ApplicationScheme must be present",
+		"Resync field Type accepts values from 0 to 3",
+		"SegmentTemplate field AvTimeOffset must be present when AvTimeComplete = 'false'",
+		"MPD field UTCTiming must be present when ProducerReferenceTime is present",
+		"Latency field ReferenceID 1 must be the ID of a ProducerReferenceTime",
+		"AvTimeComplete must not be 'false' when Type = 'static'",
+	} {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("Expecting '%s' error, but got %s", e, err.Error())
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package dash
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ingest/manifest/internal/source"
+)
+
+//ResolveToZero is the xlink:href value of a remote element that resolves to no element.
+//Elements referencing it are removed without fetching anything.
+const ResolveToZero = "urn:mpeg:dash:resolve-to-zero:2013"
+
+//DefaultXlinkDepth is the default maximum number of nested remote element entities.
+const DefaultXlinkDepth = 5
+
+//DefaultXlinkFetches is the default maximum number of remote element entities fetched by a call
+//to a Resolver.
+const DefaultXlinkFetches = 100
+
+//DefaultXlinkMaxBytes is the default maximum size of a remote element entity downloaded by HTTPFetcher.
+const DefaultXlinkMaxBytes = 1 << 20
+
+var (
+	//ErrXlinkCycle is returned when a remote element entity references itself, directly or
+	//through other remote entities.
+	ErrXlinkCycle = errors.New("xlink remote elements reference each other")
+	//ErrXlinkDepth is returned when remote element entities are nested deeper than the Resolver MaxDepth.
+	ErrXlinkDepth = errors.New("xlink remote elements exceed the maximum depth")
+	//ErrXlinkFetches is returned when resolving remote elements takes more fetches than the Resolver MaxFetches.
+	ErrXlinkFetches = errors.New("xlink remote elements exceed the maximum number of fetches")
+	//ErrXlinkSize is returned by HTTPFetcher when a remote element entity is larger than its maximum size.
+	ErrXlinkSize = errors.New("xlink remote element entity exceeds the maximum size")
+)
+
+//Fetcher retrieves the remote element entity referenced by a xlink:href.
+type Fetcher interface {
+	Fetch(ctx context.Context, uri string) ([]byte, error)
+}
+
+type httpFetcher struct {
+	Client   *http.Client
+	MaxBytes int64
+}
+
+//HTTPFetcher returns a Fetcher that downloads remote element entities using c, of up to
+//DefaultXlinkMaxBytes. By default uses the http.DefaultClient if a nil pointer is passed.
+func HTTPFetcher(c *http.Client) Fetcher {
+	return NewHTTPFetcher(c, DefaultXlinkMaxBytes)
+}
+
+//NewHTTPFetcher returns a Fetcher that downloads remote element entities using c, and returns
+//ErrXlinkSize for entities larger than maxBytes. By default uses the http.DefaultClient if a
+//nil pointer is passed.
+func NewHTTPFetcher(c *http.Client, maxBytes int64) Fetcher {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &httpFetcher{
+		Client:   c,
+		MaxBytes: maxBytes,
+	}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote element %s: %s", uri, res.Status)
+	}
+
+	//read one byte past the limit to tell a larger entity from one of exactly MaxBytes
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, f.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.MaxBytes {
+		return nil, ErrXlinkSize
+	}
+	return data, nil
+}
+
+//MemoryFetcher is a Fetcher of remote element entities keyed by their URI.
+type MemoryFetcher map[string][]byte
+
+//Fetch returns the remote element entity stored for uri.
+func (f MemoryFetcher) Fetch(ctx context.Context, uri string) ([]byte, error) {
+	data, ok := f[uri]
+	if !ok {
+		return nil, fmt.Errorf("remote element %s not found", uri)
+	}
+	return data, nil
+}
+
+//Resolver dereferences the xlink:href of remote Period, AdaptationSet, SegmentList and
+//EventStream elements, replacing them with the elements fetched from the remote entity.
+//
+//Resolve replaces the elements with XlinkActuate 'onLoad' when the MPD is loaded. Elements with
+//XlinkActuate 'onRequest', the default, are left in place and can be resolved when they are needed
+//with ResolvePeriod, ResolveAdaptationSet, ResolveSegmentList and ResolveEventStream.
+type Resolver struct {
+	Fetcher Fetcher
+	//BaseURL resolves relative xlink:href values, usually the URL the MPD was fetched from.
+	//Relative references inside a remote entity are resolved against the entity URI.
+	BaseURL string
+	//MaxDepth limits how many remote entities can be nested. Default: DefaultXlinkDepth.
+	MaxDepth int
+	//MaxFetches limits how many remote entities a call to Resolve, ResolvePeriod, ResolveAdaptationSet,
+	//ResolveEventStream or ResolveSegmentList fetches, nested ones included. Default: DefaultXlinkFetches.
+	MaxFetches int
+
+	fetches int //fetches made by the current call, counted on a copy of the Resolver
+}
+
+//NewResolver returns a Resolver that fetches remote elements with f.
+func NewResolver(f Fetcher) *Resolver {
+	return &Resolver{Fetcher: f, MaxDepth: DefaultXlinkDepth, MaxFetches: DefaultXlinkFetches}
+}
+
+//call returns a copy of r counting the fetches of a call, so concurrent calls have their own budget.
+func (r *Resolver) call() *Resolver {
+	c := *r
+	c.fetches = 0
+	return &c
+}
+
+//Resolve replaces every remote element with XlinkActuate 'onLoad' in m, including the ones
+//found in the fetched remote entities.
+func (r *Resolver) Resolve(ctx context.Context, m *MPD) (err error) {
+	m.Periods, err = r.call().loadPeriods(ctx, m.Periods, nil)
+	return
+}
+
+//ResolvePeriod returns the Periods replacing the remote Period p, whatever its XlinkActuate.
+//Remote elements with XlinkActuate 'onLoad' in the fetched Periods are resolved too.
+//A Period without xlink:href is returned as is.
+func (r *Resolver) ResolvePeriod(ctx context.Context, p *Period) (Periods, error) {
+	if p.XlinkHref == "" {
+		return Periods{p}, nil
+	}
+	return r.call().resolvePeriod(ctx, p, nil)
+}
+
+//ResolveAdaptationSet returns the AdaptationSets replacing the remote AdaptationSet as.
+//An AdaptationSet without xlink:href is returned as is.
+func (r *Resolver) ResolveAdaptationSet(ctx context.Context, as *AdaptationSet) (AdaptationSets, error) {
+	if as.XlinkHref == "" {
+		return AdaptationSets{as}, nil
+	}
+	return r.call().resolveAdaptationSet(ctx, as, nil)
+}
+
+//ResolveEventStream returns the EventStreams replacing the remote EventStream es.
+//An EventStream without xlink:href is returned as is.
+func (r *Resolver) ResolveEventStream(ctx context.Context, es *EventStream) ([]*EventStream, error) {
+	if es.XlinkHref == "" {
+		return []*EventStream{es}, nil
+	}
+	return r.call().resolveEventStream(ctx, es, nil)
+}
+
+//ResolveSegmentList returns the SegmentList replacing the remote SegmentList sl,
+//nil if it resolves to zero. A SegmentList without xlink:href is returned as is.
+func (r *Resolver) ResolveSegmentList(ctx context.Context, sl *SegmentList) (*SegmentList, error) {
+	if sl.XlinkHref == "" {
+		return sl, nil
+	}
+	return r.call().resolveSegmentList(ctx, sl, nil)
+}
+
+//fetch downloads the remote entity referenced by href. chain holds the URIs of the remote
+//entities being resolved, the last one being the entity href was found in.
+func (r *Resolver) fetch(ctx context.Context, href string, chain []string) ([]byte, []string, error) {
+	base := r.BaseURL
+	if len(chain) > 0 {
+		base = chain[len(chain)-1]
+	}
+	uri := href
+	if base != "" {
+		var err error
+		if uri, err = source.ResolveURL(base, href); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, c := range chain {
+		if c == uri {
+			return nil, nil, ErrXlinkCycle
+		}
+	}
+
+	maxDepth := r.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultXlinkDepth
+	}
+	if len(chain) >= maxDepth {
+		return nil, nil, ErrXlinkDepth
+	}
+
+	maxFetches := r.MaxFetches
+	if maxFetches <= 0 {
+		maxFetches = DefaultXlinkFetches
+	}
+	if r.fetches >= maxFetches {
+		return nil, nil, ErrXlinkFetches
+	}
+	r.fetches++
+
+	if r.Fetcher == nil {
+		return nil, nil, errors.New("Resolver must have a Fetcher")
+	}
+	data, err := r.Fetcher.Fetch(ctx, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, append(chain[:len(chain):len(chain)], uri), nil
+}
+
+//decodeRemote decodes every top level element of a remote entity, which must all be named name.
+func decodeRemote(data []byte, name string, decode func(d *xml.Decoder, start *xml.StartElement) error) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != name {
+				return fmt.Errorf("remote entity must contain %s elements, but got %s", name, start.Name.Local)
+			}
+			if err := decode(d, &start); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func onLoad(href, actuate string) bool {
+	return href != "" && actuate == "onLoad"
+}
+
+func (r *Resolver) resolvePeriod(ctx context.Context, p *Period, chain []string) (Periods, error) {
+	if p.XlinkHref == ResolveToZero {
+		return nil, nil
+	}
+	data, chain, err := r.fetch(ctx, p.XlinkHref, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods Periods
+	err = decodeRemote(data, "Period", func(d *xml.Decoder, start *xml.StartElement) error {
+		remote := &Period{}
+		periods = append(periods, remote)
+		return d.DecodeElement(remote, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadPeriods(ctx, periods, chain)
+}
+
+//loadPeriods resolves the onLoad remote Periods in the list and the onLoad remote elements in each Period.
+func (r *Resolver) loadPeriods(ctx context.Context, periods Periods, chain []string) (Periods, error) {
+	var loaded Periods
+	for _, p := range periods {
+		if onLoad(p.XlinkHref, p.XlinkActuate) {
+			remote, err := r.resolvePeriod(ctx, p, chain)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, remote...)
+			continue
+		}
+
+		var err error
+		if p.AdaptationSets, err = r.loadAdaptationSets(ctx, p.AdaptationSets, chain); err != nil {
+			return nil, err
+		}
+		if p.EventStream, err = r.loadEventStreams(ctx, p.EventStream, chain); err != nil {
+			return nil, err
+		}
+		if p.SegmentList, err = r.loadSegmentList(ctx, p.SegmentList, chain); err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, p)
+	}
+	return loaded, nil
+}
+
+func (r *Resolver) resolveAdaptationSet(ctx context.Context, as *AdaptationSet, chain []string) (AdaptationSets, error) {
+	if as.XlinkHref == ResolveToZero {
+		return nil, nil
+	}
+	data, chain, err := r.fetch(ctx, as.XlinkHref, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var sets AdaptationSets
+	err = decodeRemote(data, "AdaptationSet", func(d *xml.Decoder, start *xml.StartElement) error {
+		remote := &AdaptationSet{}
+		sets = append(sets, remote)
+		return d.DecodeElement(remote, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadAdaptationSets(ctx, sets, chain)
+}
+
+//loadAdaptationSets resolves the onLoad remote AdaptationSets in the list and the onLoad remote
+//SegmentLists of each AdaptationSet and its Representations.
+func (r *Resolver) loadAdaptationSets(ctx context.Context, sets AdaptationSets, chain []string) (AdaptationSets, error) {
+	var loaded AdaptationSets
+	for _, as := range sets {
+		if onLoad(as.XlinkHref, as.XlinkActuate) {
+			remote, err := r.resolveAdaptationSet(ctx, as, chain)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, remote...)
+			continue
+		}
+
+		var err error
+		if as.SegmentList, err = r.loadSegmentList(ctx, as.SegmentList, chain); err != nil {
+			return nil, err
+		}
+		for _, rep := range as.Representations {
+			if rep.SegmentList, err = r.loadSegmentList(ctx, rep.SegmentList, chain); err != nil {
+				return nil, err
+			}
+		}
+		loaded = append(loaded, as)
+	}
+	return loaded, nil
+}
+
+func (r *Resolver) resolveEventStream(ctx context.Context, es *EventStream, chain []string) ([]*EventStream, error) {
+	if es.XlinkHref == ResolveToZero {
+		return nil, nil
+	}
+	data, chain, err := r.fetch(ctx, es.XlinkHref, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []*EventStream
+	err = decodeRemote(data, "EventStream", func(d *xml.Decoder, start *xml.StartElement) error {
+		remote := &EventStream{}
+		streams = append(streams, remote)
+		return d.DecodeElement(remote, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadEventStreams(ctx, streams, chain)
+}
+
+//loadEventStreams resolves the onLoad remote EventStreams in the list.
+func (r *Resolver) loadEventStreams(ctx context.Context, streams []*EventStream, chain []string) ([]*EventStream, error) {
+	var loaded []*EventStream
+	for _, es := range streams {
+		if onLoad(es.XlinkHref, es.XlinkActuate) {
+			remote, err := r.resolveEventStream(ctx, es, chain)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, remote...)
+			continue
+		}
+		loaded = append(loaded, es)
+	}
+	return loaded, nil
+}
+
+func (r *Resolver) resolveSegmentList(ctx context.Context, sl *SegmentList, chain []string) (*SegmentList, error) {
+	if sl.XlinkHref == ResolveToZero {
+		return nil, nil
+	}
+	data, chain, err := r.fetch(ctx, sl.XlinkHref, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists []*SegmentList
+	err = decodeRemote(data, "SegmentList", func(d *xml.Decoder, start *xml.StartElement) error {
+		remote := &SegmentList{}
+		lists = append(lists, remote)
+		return d.DecodeElement(remote, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch len(lists) {
+	case 0:
+		return nil, nil
+	case 1:
+		return r.loadSegmentList(ctx, lists[0], chain)
+	default:
+		return nil, fmt.Errorf("remote entity %s must contain at most one SegmentList element", chain[len(chain)-1])
+	}
+}
+
+//loadSegmentList resolves sl if it's an onLoad remote SegmentList.
+func (r *Resolver) loadSegmentList(ctx context.Context, sl *SegmentList, chain []string) (*SegmentList, error) {
+	if sl == nil || !onLoad(sl.XlinkHref, sl.XlinkActuate) {
+		return sl, nil
+	}
+	return r.resolveSegmentList(ctx, sl, chain)
+}
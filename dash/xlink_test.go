@@ -0,0 +1,208 @@
+package dash
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const remoteBase = "http://dash.edgesuite.net/dash264/TestCases/5b/nomor/"
+
+var remotePeriod = []byte(`<Period id="1" duration="PT110S" xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:xlink="http://www.w3.org/1999/xlink">
+  <EventStream xlink:href="events.xml" xlink:actuate="onLoad"></EventStream>
+  <AdaptationSet xlink:href="video.xml" xlink:actuate="onLoad"></AdaptationSet>
+  <AdaptationSet xlink:href="urn:mpeg:dash:resolve-to-zero:2013" xlink:actuate="onLoad"></AdaptationSet>
+  <AdaptationSet id="3" xlink:href="audio.xml"></AdaptationSet>
+</Period>`)
+
+func getMemoryFetcher() MemoryFetcher {
+	return MemoryFetcher{
+		remoteBase + "4.period": remotePeriod,
+		remoteBase + "video.xml": []byte(`<AdaptationSet id="1" mimeType="video/mp4">
+  <Representation id="1" bandwidth="980104">
+    <SegmentList xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="segments.xml" xlink:actuate="onLoad"></SegmentList>
+  </Representation>
+</AdaptationSet>
+<AdaptationSet id="2" mimeType="video/mp4"></AdaptationSet>`),
+		remoteBase + "segments.xml": []byte(`<SegmentList duration="2"><SegmentURL media="seg1.mp4"></SegmentURL><SegmentURL media="seg2.mp4"></SegmentURL></SegmentList>`),
+		remoteBase + "events.xml":   []byte(`<EventStream schemeIdUri="urn:uuid:XYZY" timescale="1"><Event presentationTime="0" id="1"></Event></EventStream>`),
+		remoteBase + "audio.xml":    []byte(`<AdaptationSet id="3" mimeType="audio/mp4"></AdaptationSet>`),
+	}
+}
+
+func getXlinkMPD(t *testing.T) *MPD {
+	f, err := os.Open("./testdata/multipleperiods.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	mpd := &MPD{}
+	if err := mpd.Parse(f); err != nil {
+		t.Fatal(err)
+	}
+	return mpd
+}
+
+func TestResolve(t *testing.T) {
+	mpd := getXlinkMPD(t)
+	r := NewResolver(getMemoryFetcher())
+	if err := r.Resolve(context.Background(), mpd); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mpd.Periods) != 3 || mpd.Periods[1].ID != "1" {
+		t.Fatalf("Expected remote Period 1 to replace the xlink Period, but got %d Periods", len(mpd.Periods))
+	}
+
+	p := mpd.Periods[1]
+	if len(p.EventStream) != 1 || p.EventStream[0].SchemeIDURI != "urn:uuid:XYZY" {
+		t.Errorf("Expected onLoad EventStream to be resolved")
+	}
+	//video.xml resolves to 2 AdaptationSets, resolve-to-zero removes one, audio.xml is onRequest
+	if len(p.AdaptationSets) != 3 {
+		t.Fatalf("Expected 3 AdaptationSets, but got %d", len(p.AdaptationSets))
+	}
+	if p.AdaptationSets[0].ID != 1 || p.AdaptationSets[1].ID != 2 || p.AdaptationSets[2].XlinkHref != "audio.xml" {
+		t.Errorf("Expected AdaptationSets 1, 2 and the onRequest AdaptationSet, but got %d, %d and %s",
+			p.AdaptationSets[0].ID, p.AdaptationSets[1].ID, p.AdaptationSets[2].XlinkHref)
+	}
+	sl := p.AdaptationSets[0].Representations[0].SegmentList
+	if sl == nil || len(sl.SegmentURLs) != 2 || sl.XlinkHref != "" {
+		t.Errorf("Expected onLoad SegmentList to be resolved")
+	}
+
+	//onRequest elements are resolved relative to the remote entity they were found in
+	r.BaseURL = remoteBase + "4.period"
+	sets, err := r.ResolveAdaptationSet(context.Background(), p.AdaptationSets[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 1 || sets[0].MimeType != "audio/mp4" {
+		t.Errorf("Expected onRequest AdaptationSet to be resolved to the audio AdaptationSet")
+	}
+
+	if _, err := mpd.Encode(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResolveToZero(t *testing.T) {
+	r := NewResolver(MemoryFetcher{})
+	periods, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: ResolveToZero})
+	if err != nil || len(periods) != 0 {
+		t.Errorf("Expected Period to resolve to zero, but got %d Periods and error %v", len(periods), err)
+	}
+	sl, err := r.ResolveSegmentList(context.Background(), &SegmentList{XlinkHref: ResolveToZero, XlinkActuate: "onLoad"})
+	if err != nil || sl != nil {
+		t.Errorf("Expected SegmentList to resolve to zero, but got %v and error %v", sl, err)
+	}
+
+	inline := &EventStream{SchemeIDURI: "urn:uuid:XYZY"}
+	streams, err := r.ResolveEventStream(context.Background(), inline)
+	if err != nil || len(streams) != 1 || streams[0] != inline {
+		t.Error("Expected EventStream without xlink:href to be returned as is")
+	}
+}
+
+func TestResolveAbuse(t *testing.T) {
+	tests := []struct {
+		Case    string
+		Fetcher MemoryFetcher
+		Err     error
+	}{
+		{"self reference", MemoryFetcher{
+			"http://example.com/a": []byte(`<Period xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="a" xlink:actuate="onLoad"></Period>`),
+		}, ErrXlinkCycle},
+		{"cycle", MemoryFetcher{
+			"http://example.com/a": []byte(`<Period xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="b" xlink:actuate="onLoad"></Period>`),
+			"http://example.com/b": []byte(`<Period id="b"><AdaptationSet xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="a" xlink:actuate="onLoad"></AdaptationSet></Period>`),
+		}, ErrXlinkCycle},
+		{"depth", MemoryFetcher{
+			"http://example.com/a": []byte(`<Period xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="b" xlink:actuate="onLoad"></Period>`),
+			"http://example.com/b": []byte(`<Period xmlns:xlink="http://www.w3.org/1999/xlink" xlink:href="c" xlink:actuate="onLoad"></Period>`),
+			"http://example.com/c": []byte(`<Period id="c"></Period>`),
+		}, ErrXlinkDepth},
+	}
+
+	for _, tt := range tests {
+		r := NewResolver(tt.Fetcher)
+		r.BaseURL = "http://example.com/manifest.mpd"
+		r.MaxDepth = 2
+		mpd := &MPD{Periods: Periods{&Period{XlinkHref: "a", XlinkActuate: "onLoad"}}}
+		if err := r.Resolve(context.Background(), mpd); err != tt.Err {
+			t.Errorf("%s - expected error %v, but got %v", tt.Case, tt.Err, err)
+		}
+	}
+
+	//remote AdaptationSets listed side by side are within MaxDepth, but each takes a fetch
+	fanOut := MemoryFetcher{
+		"http://example.com/a": []byte(`<Period xmlns:xlink="http://www.w3.org/1999/xlink">` + strings.Repeat(`<AdaptationSet xlink:href="b" xlink:actuate="onLoad"></AdaptationSet>`, 3) + `</Period>`),
+		"http://example.com/b": []byte(`<AdaptationSet id="1"></AdaptationSet>`),
+	}
+	r := NewResolver(fanOut)
+	r.BaseURL = "http://example.com/manifest.mpd"
+	r.MaxFetches = 3
+	mpd := &MPD{Periods: Periods{&Period{XlinkHref: "a", XlinkActuate: "onLoad"}}}
+	if err := r.Resolve(context.Background(), mpd); err != ErrXlinkFetches {
+		t.Errorf("Expected error %v, but got %v", ErrXlinkFetches, err)
+	}
+	r.MaxFetches = 4
+	for i := 0; i < 2; i++ {
+		if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "a"}); err != nil {
+			t.Errorf("Expected every call to have its own fetch budget, but got %v", err)
+		}
+	}
+
+	r = NewResolver(MemoryFetcher{"http://example.com/a": []byte(`<AdaptationSet></AdaptationSet>`)})
+	if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "http://example.com/a"}); err == nil {
+		t.Error("Expected error when remote entity contains other elements")
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/4.period":
+			w.Write(remotePeriod)
+		case "/large.period":
+			w.Write([]byte(`<Period id="large">` + strings.Repeat(" ", 1024) + `</Period>`))
+		case "/ad.period":
+			w.Write([]byte(`<Period id="ad1"></Period><Period id="ad2"></Period>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	r := NewResolver(HTTPFetcher(nil))
+	r.BaseURL = ts.URL + "/manifest.mpd"
+
+	periods, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "ad.period"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 || periods[1].ID != "ad2" {
+		t.Errorf("Expected 2 remote Periods, but got %d", len(periods))
+	}
+
+	if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "missing.period"}); err == nil {
+		t.Error("Expected error on 404 response")
+	}
+
+	r.Fetcher = NewHTTPFetcher(nil, 1024)
+	if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "large.period"}); err != ErrXlinkSize {
+		t.Errorf("Expected error %v, but got %v", ErrXlinkSize, err)
+	}
+	if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "ad.period"}); err != nil {
+		t.Errorf("Expected remote entity within the size limit to be fetched, but got %v", err)
+	}
+
+	//the onLoad elements of the remote Period aren't on the server
+	if _, err := r.ResolvePeriod(context.Background(), &Period{XlinkHref: "4.period"}); err == nil {
+		t.Error("Expected error when remote elements of the remote Period aren't found")
+	}
+}
@@ -0,0 +1,99 @@
+package drm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ingest/manifest/dash"
+)
+
+//DASH ContentProtection schemes that aren't a system ID.
+const (
+	//MP4Protection signals the Common Encryption scheme of the content and its default KID.
+	MP4Protection = "urn:mpeg:dash:mp4protection:2011"
+	//dashIFClearKey is the ClearKey scheme of the DASH-IF interoperability guidelines.
+	dashIFClearKey = "urn:uuid:e2719d58-a985-b3c9-781a-b030af78d30e"
+)
+
+//ContentProtections returns the ContentProtection elements of content encrypted with scheme
+//("cenc" or "cbcs") using the key defaultKID: the mp4protection element followed by one element
+//per pssh box, in the order the DASH-IF interoperability guidelines recommend.
+func ContentProtections(scheme string, defaultKID KID, boxes ...*PSSH) (dash.CENCContentProtections, error) {
+	cps := dash.CENCContentProtections{dash.NewContentProtection(MP4Protection, scheme, defaultKID.String(), "", "")}
+	for _, p := range boxes {
+		cp, err := p.ContentProtection()
+		if err != nil {
+			return nil, err
+		}
+		cps = append(cps, cp)
+	}
+	return cps, nil
+}
+
+//ContentProtection returns the ContentProtection element of the DRM system of the box, with
+//the box in a cenc:pssh element. PlayReady elements also carry the PlayReady Object in an
+//element mspr:pro, for clients that don't support cenc:pssh.
+func (p *PSSH) ContentProtection() (*dash.CENCContentProtection, error) {
+	box, err := p.Base64()
+	if err != nil {
+		return nil, err
+	}
+
+	var value, pro string
+	switch p.SystemID {
+	case PlayReady:
+		value = "MSPR 2.0"
+		pro = base64.StdEncoding.EncodeToString(p.Data)
+	case ClearKey:
+		value = "ClearKey1.0"
+	}
+	return dash.NewContentProtection(p.SystemID.URN(), value, "", box, pro), nil
+}
+
+//FromContentProtection parses the pssh box of a ContentProtection element. PlayReady elements
+//without cenc:pssh get a box built from their mspr:pro element. It returns ErrNoPSSH if the
+//element carries neither, such as the mp4protection element.
+func FromContentProtection(cp *dash.CENCContentProtection) (*PSSH, error) {
+	if cp.Pssh != nil && strings.TrimSpace(cp.Pssh.Value) != "" {
+		return ParsePSSHBase64(cp.Pssh.Value)
+	}
+
+	if cp.Pro == nil || strings.TrimSpace(cp.Pro.Value) == "" {
+		return nil, ErrNoPSSH
+	}
+	if system, err := ContentProtectionSystem(cp); err != nil || system != PlayReady {
+		return nil, fmt.Errorf("mspr:pro in ContentProtection scheme %q isn't PlayReady", cp.SchemeIDURI)
+	}
+	pro, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cp.Pro.Value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 mspr:pro: %v", err)
+	}
+	h, err := ParsePlayReadyObject(pro)
+	if err != nil {
+		return nil, err
+	}
+	return NewPSSH(PlayReady, h.KIDs, pro), nil
+}
+
+//ContentProtectionSystem returns the DRM system signalled by a ContentProtection element.
+func ContentProtectionSystem(cp *dash.CENCContentProtection) (SystemID, error) {
+	if strings.EqualFold(cp.SchemeIDURI, dashIFClearKey) {
+		return ClearKey, nil
+	}
+	if !strings.HasPrefix(strings.ToLower(cp.SchemeIDURI), "urn:uuid:") {
+		return SystemID{}, fmt.Errorf("ContentProtection scheme %q isn't a DRM system", cp.SchemeIDURI)
+	}
+	return ParseSystemID(cp.SchemeIDURI)
+}
+
+//DefaultKID returns the default KID of the first mp4protection element of cps.
+func DefaultKID(cps dash.CENCContentProtections) (KID, bool) {
+	for _, cp := range cps {
+		if cp.SchemeIDURI == MP4Protection && cp.DefaultKID != "" {
+			kid, err := ParseKID(cp.DefaultKID)
+			return kid, err == nil
+		}
+	}
+	return KID{}, false
+}
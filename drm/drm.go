@@ -0,0 +1,108 @@
+//Package drm builds and parses the Protection System Specific Header (pssh) boxes of
+//ISO/IEC 23001-7 Common Encryption, and maps them to the DASH ContentProtection elements and
+//HLS EXT-X-KEY and EXT-X-SESSION-KEY tags that signal them to players.
+//
+//The Widevine, PlayReady, FairPlay and ClearKey systems are supported. Widevine and PlayReady
+//system data can be built with WidevineData and PlayReadyHeader, instead of hand-assembling
+//base64 blobs:
+//  kid, _ := drm.ParseKID("10000000-1000-1000-1000-100000000001")
+//  wv, _ := (&drm.WidevineData{KIDs: []drm.KID{kid}, Provider: "ingest"}).PSSH()
+//  pr, _ := (&drm.PlayReadyHeader{KIDs: []drm.KID{kid}, LAURL: "https://pr.example.com"}).PSSH()
+//  cps, _ := drm.ContentProtections("cenc", kid, wv, pr)
+//  adaptationSet.CENCContentProtections = cps
+package drm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//SystemID identifies a DRM system.
+type SystemID [16]byte
+
+//System IDs of the supported DRM systems, as registered on dashif.org.
+var (
+	Widevine  = SystemID{0xed, 0xef, 0x8b, 0xa9, 0x79, 0xd6, 0x4a, 0xce, 0xa3, 0xc8, 0x27, 0xdc, 0xd5, 0x1d, 0x21, 0xed}
+	PlayReady = SystemID{0x9a, 0x04, 0xf0, 0x79, 0x98, 0x40, 0x42, 0x86, 0xab, 0x92, 0xe6, 0x5b, 0xe0, 0x88, 0x5f, 0x95}
+	FairPlay  = SystemID{0x94, 0xce, 0x86, 0xfb, 0x07, 0xff, 0x4f, 0x43, 0xad, 0xb8, 0x93, 0xd2, 0xfa, 0x96, 0x8c, 0xa2}
+	//ClearKey is the W3C Common PSSH system, whose boxes only list KIDs.
+	ClearKey = SystemID{0x10, 0x77, 0xef, 0xec, 0xc0, 0xb2, 0x4d, 0x02, 0xac, 0xe3, 0x3c, 0x1e, 0x52, 0xe2, 0xfb, 0x4b}
+)
+
+//ErrNoPSSH is returned when a ContentProtection element or HLS key doesn't carry a pssh box.
+var ErrNoPSSH = errors.New("no pssh box")
+
+//ParseSystemID parses a system ID formatted as a UUID, with or without the urn:uuid: prefix
+//used by DASH schemeIdUri.
+func ParseSystemID(s string) (SystemID, error) {
+	id, err := parseUUID(s)
+	if err != nil {
+		return SystemID{}, fmt.Errorf("invalid system ID: %v", err)
+	}
+	return SystemID(id), nil
+}
+
+//String returns the system ID formatted as a UUID.
+func (s SystemID) String() string {
+	return formatUUID(s)
+}
+
+//URN returns the system ID as a URN, the schemeIdUri of its ContentProtection elements.
+func (s SystemID) URN() string {
+	return "urn:uuid:" + s.String()
+}
+
+//Name returns the name of the DRM system, or an empty string if it isn't supported.
+func (s SystemID) Name() string {
+	switch s {
+	case Widevine:
+		return "Widevine"
+	case PlayReady:
+		return "PlayReady"
+	case FairPlay:
+		return "FairPlay"
+	case ClearKey:
+		return "ClearKey"
+	}
+	return ""
+}
+
+//KID is the ID of a content key.
+type KID [16]byte
+
+//ParseKID parses a KID formatted as a UUID, as in cenc:default_KID, or as 32 hexadecimal digits.
+func ParseKID(s string) (KID, error) {
+	id, err := parseUUID(s)
+	if err != nil {
+		return KID{}, fmt.Errorf("invalid KID: %v", err)
+	}
+	return KID(id), nil
+}
+
+//String returns the KID formatted as a UUID, as in cenc:default_KID.
+func (k KID) String() string {
+	return formatUUID(k)
+}
+
+func parseUUID(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) > 9 && strings.EqualFold(s[:9], "urn:uuid:") {
+		s = s[9:]
+	}
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil {
+		return id, err
+	}
+	if len(b) != len(id) {
+		return id, fmt.Errorf("%q isn't 16 bytes long", s)
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+func formatUUID(id [16]byte) string {
+	h := hex.EncodeToString(id[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
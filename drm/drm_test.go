@@ -0,0 +1,257 @@
+package drm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest/dash"
+	"github.com/ingest/manifest/hls"
+)
+
+//clearKeyPSSH is the example pssh box of the W3C Common PSSH Box Format.
+const clearKeyPSSH = "000000347073736801000000" + "1077efecc0b24d02ace33c1e52e2fb4b" +
+	"00000001" + "0123456789abcdef0123456789abcdef" + "00000000"
+
+func mustKID(t *testing.T, s string) KID {
+	kid, err := ParseKID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kid
+}
+
+func TestPSSH(t *testing.T) {
+	b, _ := hex.DecodeString(clearKeyPSSH)
+	p, err := ParsePSSH(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := mustKID(t, "0123456789abcdef0123456789abcdef")
+	if p.Version != 1 || p.SystemID != ClearKey || !reflect.DeepEqual(p.KIDs, []KID{kid}) || p.Data != nil {
+		t.Errorf("Expected version 1 ClearKey box with KID %s, but got %v", kid, p)
+	}
+	out, err := NewPSSH(ClearKey, []KID{kid}, nil).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, b) {
+		t.Errorf("Expected box %s, but got %x", clearKeyPSSH, out)
+	}
+
+	v0 := NewPSSH(Widevine, nil, []byte("data"))
+	out, err = v0.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes, err := ParseAll(append(out, b...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boxes) != 2 || !reflect.DeepEqual(boxes[0], v0) || boxes[1].SystemID != ClearKey {
+		t.Errorf("Expected Widevine and ClearKey boxes, but got %v", boxes)
+	}
+
+	for _, invalid := range [][]byte{b[:20], b[:len(b)-1], append([]byte{0, 0, 0, 8}, []byte("moov")...)} {
+		if _, err := ParsePSSH(invalid); err == nil {
+			t.Errorf("Expected error parsing %x", invalid)
+		}
+	}
+	if _, err := (&PSSH{Version: 0, KIDs: []KID{kid}}).MarshalBinary(); err == nil {
+		t.Error("Expected error on version 0 box with KIDs")
+	}
+}
+
+func TestWidevineData(t *testing.T) {
+	kid := mustKID(t, "10000000-1000-1000-1000-100000000001")
+	w := &WidevineData{KIDs: []KID{kid}, Provider: "ingest", ContentID: []byte("movie"), ProtectionScheme: "cbcs"}
+	b, err := w.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := append([]byte{0x12, 0x10}, kid[:]...)
+	expect = append(expect, 0x1a, 0x06, 'i', 'n', 'g', 'e', 's', 't')
+	expect = append(expect, 0x22, 0x05, 'm', 'o', 'v', 'i', 'e')
+	expect = append(expect, 0x48, 0xf3, 0xc6, 0x89, 0x9b, 0x06)
+	if !bytes.Equal(b, expect) {
+		t.Errorf("Expected Widevine data %x, but got %x", expect, b)
+	}
+
+	//unknown fields, such as the deprecated algorithm, are skipped
+	parsed, err := ParseWidevineData(append([]byte{0x08, 0x01}, b...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, w) {
+		t.Errorf("Expected %v, but got %v", w, parsed)
+	}
+
+	if _, err := ParseWidevineData([]byte{0x12, 0x10, 0x01}); err == nil {
+		t.Error("Expected error on truncated data")
+	}
+}
+
+func TestPlayReadyHeader(t *testing.T) {
+	kid := mustKID(t, "10000000-1000-1000-1000-100000000001")
+	h := &PlayReadyHeader{KIDs: []KID{kid}, LAURL: "https://pr.example.com/rightsmanager.asmx?a=1&b=2"}
+	header, err := h.WRMHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.0.0.0"><DATA>` +
+		`<PROTECTINFO><KEYLEN>16</KEYLEN><ALGID>AESCTR</ALGID></PROTECTINFO><KID>AAAAEAAQABAQABAAAAAAAQ==</KID>` +
+		`<LA_URL>https://pr.example.com/rightsmanager.asmx?a=1&amp;b=2</LA_URL></DATA></WRMHEADER>`
+	if header != expect {
+		t.Errorf("Expected WRMHEADER:\n%s\nbut got:\n%s", expect, header)
+	}
+
+	p, err := h.PSSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePlayReadyObject(p.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Algorithm = "AESCTR"
+	if !reflect.DeepEqual(parsed, h) {
+		t.Errorf("Expected %v, but got %v", h, parsed)
+	}
+
+	multi := &PlayReadyHeader{KIDs: []KID{kid, mustKID(t, "0123456789abcdef0123456789abcdef")}, Algorithm: "AESCBC"}
+	pro, err := multi.PlayReadyObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = ParsePlayReadyObject(pro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, multi) {
+		t.Errorf("Expected %v, but got %v", multi, parsed)
+	}
+}
+
+func getProtection(t *testing.T) (KID, []*PSSH) {
+	kid := mustKID(t, "10000000-1000-1000-1000-100000000001")
+	wv, err := (&WidevineData{KIDs: []KID{kid}, Provider: "ingest"}).PSSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := (&PlayReadyHeader{KIDs: []KID{kid}, LAURL: "https://pr.example.com"}).PSSH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kid, []*PSSH{wv, pr, NewPSSH(ClearKey, []KID{kid}, nil)}
+}
+
+func TestContentProtection(t *testing.T) {
+	kid, boxes := getProtection(t)
+	cps, err := ContentProtections("cenc", kid, boxes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mpd := dash.NewMPD("urn:mpeg:dash:profile:isoff-live:2011", 2*time.Second)
+	mpd.Periods = dash.Periods{&dash.Period{AdaptationSets: dash.AdaptationSets{&dash.AdaptationSet{
+		CENCContentProtections: cps,
+		Representations:        dash.Representations{&dash.Representation{ID: "1", Bandwidth: 980104}},
+	}}}}
+	r, err := mpd.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := &dash.MPD{}
+	if err := parsed.Parse(r); err != nil {
+		t.Fatal(err)
+	}
+
+	cps = parsed.Periods[0].AdaptationSets[0].CENCContentProtections
+	if len(cps) != 4 {
+		t.Fatalf("Expected 4 ContentProtection elements, but got %d", len(cps))
+	}
+	if defaultKID, ok := DefaultKID(cps); !ok || defaultKID != kid {
+		t.Errorf("Expected default KID %s, but got %s", kid, defaultKID)
+	}
+	if _, err := FromContentProtection(cps[0]); err != ErrNoPSSH {
+		t.Errorf("Expected ErrNoPSSH for mp4protection element, but got %v", err)
+	}
+	for i, p := range boxes {
+		got, err := FromContentProtection(cps[i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("Expected %s box %v, but got %v", p.SystemID.Name(), p, got)
+		}
+	}
+	if cps[2].SchemeIDURI != "urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95" || cps[2].Value != "MSPR 2.0" || cps[2].Pro == nil {
+		t.Errorf("Expected PlayReady element with mspr:pro, but got %s %s", cps[2].SchemeIDURI, cps[2].Value)
+	}
+
+	//PlayReady elements that predate cenc:pssh only have mspr:pro
+	cps[2].Pssh = nil
+	got, err := FromContentProtection(cps[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, boxes[1]) {
+		t.Errorf("Expected PlayReady box from mspr:pro %v, but got %v", boxes[1], got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	_, boxes := getProtection(t)
+	p := hls.NewMasterPlaylist(7)
+	p.Variants = append(p.Variants, &hls.Variant{URI: "video.m3u8", Bandwidth: 980104})
+	for _, box := range boxes {
+		k, err := box.SessionKey("SAMPLE-AES-CTR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.SessionKeys = append(p.SessionKeys, k)
+	}
+	fairPlay := FairPlayKey("skd://movie")
+	fairPlay.IsSession = true
+	p.SessionKeys = append(p.SessionKeys, fairPlay)
+	if _, err := NewPSSH(FairPlay, nil, nil).Key("SAMPLE-AES"); err == nil {
+		t.Error("Expected error on FairPlay pssh key")
+	}
+
+	r, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(r)
+	for _, format := range []string{`KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"`, `KEYFORMAT="com.microsoft.playready"`,
+		`KEYFORMAT="urn:uuid:1077efec-c0b2-4d02-ace3-3c1e52e2fb4b"`, `KEYFORMAT="com.apple.streamingkeydelivery"`} {
+		if !strings.Contains(buf.String(), format) {
+			t.Errorf("Expected %s in:\n%s", format, buf.String())
+		}
+	}
+
+	parsed := &hls.MasterPlaylist{}
+	if err := parsed.Parse(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.SessionKeys) != 4 {
+		t.Fatalf("Expected 4 session keys, but got %d", len(parsed.SessionKeys))
+	}
+	for i, box := range boxes {
+		got, err := FromKey(parsed.SessionKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, box) {
+			t.Errorf("Expected %s box %v, but got %v", box.SystemID.Name(), box, got)
+		}
+	}
+	if _, err := FromKey(parsed.SessionKeys[3]); err != ErrNoPSSH {
+		t.Errorf("Expected ErrNoPSSH for FairPlay key, but got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package drm
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ingest/manifest/hls"
+)
+
+//HLS KEYFORMATs of the DRM systems that don't use their system ID URN.
+const (
+	KeyFormatPlayReady = "com.microsoft.playready"
+	KeyFormatFairPlay  = "com.apple.streamingkeydelivery"
+)
+
+const (
+	dataURIPrefix      = "data:text/plain;base64,"
+	utf16DataURIPrefix = "data:text/plain;charset=UTF-16;base64,"
+)
+
+//KeyFormat returns the HLS KEYFORMAT of the DRM system.
+func (s SystemID) KeyFormat() string {
+	switch s {
+	case PlayReady:
+		return KeyFormatPlayReady
+	case FairPlay:
+		return KeyFormatFairPlay
+	}
+	return s.URN()
+}
+
+//Key returns the EXT-X-KEY of the box, for segments encrypted with method: "SAMPLE-AES" for cbcs
+//or "SAMPLE-AES-CTR" for cenc. Widevine and ClearKey keys carry the box in a data URI,
+//PlayReady keys carry its PlayReady Object. FairPlay keys identify the key with a skd URI
+//instead, see FairPlayKey.
+func (p *PSSH) Key(method string) (*hls.Key, error) {
+	if p.SystemID == FairPlay {
+		return nil, errors.New("FairPlay keys don't carry a pssh box, use FairPlayKey")
+	}
+
+	k := &hls.Key{Method: method, Keyformat: p.SystemID.KeyFormat(), Keyformatversions: "1"}
+	if p.SystemID == PlayReady {
+		k.URI = utf16DataURIPrefix + base64.StdEncoding.EncodeToString(p.Data)
+		return k, nil
+	}
+	box, err := p.Base64()
+	if err != nil {
+		return nil, err
+	}
+	k.URI = dataURIPrefix + box
+	return k, nil
+}
+
+//SessionKey returns the EXT-X-SESSION-KEY of the box, which lets clients request the keys
+//before loading a Media Playlist. See Key.
+func (p *PSSH) SessionKey(method string) (*hls.Key, error) {
+	k, err := p.Key(method)
+	if err != nil {
+		return nil, err
+	}
+	k.IsSession = true
+	return k, nil
+}
+
+//FairPlayKey returns the EXT-X-KEY of FairPlay content encrypted with cbcs, whose key is
+//requested with uri, usually a skd:// URI holding the asset ID.
+func FairPlayKey(uri string) *hls.Key {
+	return &hls.Key{Method: "SAMPLE-AES", URI: uri, Keyformat: KeyFormatFairPlay, Keyformatversions: "1"}
+}
+
+//FromKey parses the pssh box carried by the data URI of a EXT-X-KEY or EXT-X-SESSION-KEY.
+//PlayReady keys get a box built from their PlayReady Object. It returns ErrNoPSSH for keys
+//that don't carry one, such as AES-128 and FairPlay keys.
+func FromKey(k *hls.Key) (*PSSH, error) {
+	switch {
+	case k.Keyformat == KeyFormatPlayReady && strings.HasPrefix(k.URI, utf16DataURIPrefix):
+		pro, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(k.URI, utf16DataURIPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 PlayReady Object: %v", err)
+		}
+		h, err := ParsePlayReadyObject(pro)
+		if err != nil {
+			return nil, err
+		}
+		return NewPSSH(PlayReady, h.KIDs, pro), nil
+	case strings.HasPrefix(strings.ToLower(k.Keyformat), "urn:uuid:") && strings.HasPrefix(k.URI, dataURIPrefix):
+		p, err := ParsePSSHBase64(strings.TrimPrefix(k.URI, dataURIPrefix))
+		if err != nil {
+			return nil, err
+		}
+		if p.SystemID.URN() != strings.ToLower(k.Keyformat) {
+			return nil, fmt.Errorf("pssh box of system %s doesn't match KEYFORMAT %s", p.SystemID, k.Keyformat)
+		}
+		return p, nil
+	}
+	return nil, ErrNoPSSH
+}
@@ -0,0 +1,221 @@
+package drm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+const (
+	playReadyHeaderNS = "http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader"
+	//rightsManagementHeader is the type of PlayReady Object records holding a WRMHEADER
+	rightsManagementHeader = 1
+)
+
+//PlayReadyHeader is the PlayReady Header (WRMHEADER) of content, carried by PlayReady Objects
+//in the system data of PlayReady pssh boxes and in mspr:pro elements.
+type PlayReadyHeader struct {
+	KIDs      []KID
+	Algorithm string //Optional. Possible Values: AESCTR (cenc), AESCBC (cbcs). Default: AESCTR.
+	LAURL     string //Optional. License acquisition URL.
+	LUIURL    string //Optional. License acquisition user interface URL.
+}
+
+//wrmHeader is the WRMHEADER XML, version 4.0.0.0 to 4.3.0.0.
+type wrmHeader struct {
+	XMLName xml.Name `xml:"WRMHEADER"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Data    wrmData  `xml:"DATA"`
+}
+
+type wrmData struct {
+	ProtectInfo *wrmProtectInfo `xml:"PROTECTINFO"`
+	KID         string          `xml:"KID,omitempty"` //4.0.0.0 only.
+	LAURL       string          `xml:"LA_URL,omitempty"`
+	LUIURL      string          `xml:"LUI_URL,omitempty"`
+}
+
+type wrmProtectInfo struct {
+	KeyLen int      `xml:"KEYLEN,omitempty"` //4.0.0.0 only.
+	AlgID  string   `xml:"ALGID,omitempty"`  //4.0.0.0 only.
+	KID    *wrmKID  `xml:"KID"`              //4.1.0.0 only.
+	KIDs   *wrmKIDs `xml:"KIDS"`             //4.2.0.0 and later.
+}
+
+type wrmKIDs struct {
+	KID []*wrmKID `xml:"KID"`
+}
+
+type wrmKID struct {
+	AlgID string `xml:"ALGID,attr,omitempty"`
+	Value string `xml:"VALUE,attr"`
+}
+
+//ParsePlayReadyObject parses the WRMHEADER of a PlayReady Object.
+func ParsePlayReadyObject(b []byte) (*PlayReadyHeader, error) {
+	if len(b) < 6 {
+		return nil, errors.New("PlayReady Object too short")
+	}
+	if int(binary.LittleEndian.Uint32(b)) != len(b) {
+		return nil, errors.New("PlayReady Object length doesn't match data length")
+	}
+	count := int(binary.LittleEndian.Uint16(b[4:]))
+	records := b[6:]
+	for i := 0; i < count; i++ {
+		if len(records) < 4 {
+			return nil, errors.New("PlayReady Object too short")
+		}
+		typ, length := binary.LittleEndian.Uint16(records), int(binary.LittleEndian.Uint16(records[2:]))
+		if length > len(records)-4 {
+			return nil, errors.New("PlayReady Object record too long")
+		}
+		value := records[4 : 4+length]
+		records = records[4+length:]
+		if typ == rightsManagementHeader {
+			return ParseWRMHeader(decodeUTF16(value))
+		}
+	}
+	return nil, errors.New("PlayReady Object has no WRMHEADER")
+}
+
+//ParseWRMHeader parses a WRMHEADER XML document.
+func ParseWRMHeader(s string) (*PlayReadyHeader, error) {
+	w := &wrmHeader{}
+	if err := xml.Unmarshal([]byte(s), w); err != nil {
+		return nil, fmt.Errorf("invalid WRMHEADER: %v", err)
+	}
+
+	h := &PlayReadyHeader{LAURL: w.Data.LAURL, LUIURL: w.Data.LUIURL}
+	var kids []*wrmKID
+	if w.Data.KID != "" {
+		kids = append(kids, &wrmKID{Value: w.Data.KID})
+	}
+	if pi := w.Data.ProtectInfo; pi != nil {
+		h.Algorithm = pi.AlgID
+		if pi.KID != nil {
+			kids = append(kids, pi.KID)
+		}
+		if pi.KIDs != nil {
+			kids = append(kids, pi.KIDs.KID...)
+		}
+	}
+	for _, k := range kids {
+		kid, err := decodePlayReadyKID(k.Value)
+		if err != nil {
+			return nil, err
+		}
+		h.KIDs = append(h.KIDs, kid)
+		if k.AlgID != "" {
+			h.Algorithm = k.AlgID
+		}
+	}
+	return h, nil
+}
+
+//WRMHeader returns the WRMHEADER XML document. Headers of a single AESCTR key are version
+//4.0.0.0, which all PlayReady clients support, others are version 4.3.0.0.
+func (h *PlayReadyHeader) WRMHeader() (string, error) {
+	alg := h.Algorithm
+	if alg == "" {
+		alg = "AESCTR"
+	}
+	if alg != "AESCTR" && alg != "AESCBC" {
+		return "", fmt.Errorf("unsupported PlayReady algorithm %q", alg)
+	}
+
+	w := &wrmHeader{XMLNS: playReadyHeaderNS, Data: wrmData{LAURL: h.LAURL, LUIURL: h.LUIURL}}
+	if len(h.KIDs) == 1 && alg == "AESCTR" {
+		w.Version = "4.0.0.0"
+		w.Data.ProtectInfo = &wrmProtectInfo{KeyLen: 16, AlgID: alg}
+		w.Data.KID = encodePlayReadyKID(h.KIDs[0])
+	} else {
+		w.Version = "4.3.0.0"
+		kids := &wrmKIDs{}
+		for _, kid := range h.KIDs {
+			kids.KID = append(kids.KID, &wrmKID{AlgID: alg, Value: encodePlayReadyKID(kid)})
+		}
+		w.Data.ProtectInfo = &wrmProtectInfo{KIDs: kids}
+	}
+
+	b, err := xml.Marshal(w)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+//PlayReadyObject returns the PlayReady Object holding the WRMHEADER, as carried by mspr:pro
+//elements and the system data of PlayReady pssh boxes.
+func (h *PlayReadyHeader) PlayReadyObject() ([]byte, error) {
+	header, err := h.WRMHeader()
+	if err != nil {
+		return nil, err
+	}
+	record := encodeUTF16(header)
+	if len(record) > 0xffff {
+		return nil, errors.New("WRMHEADER too long")
+	}
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint32(10+len(record)))
+	binary.Write(buf, binary.LittleEndian, uint16(1))
+	binary.Write(buf, binary.LittleEndian, uint16(rightsManagementHeader))
+	binary.Write(buf, binary.LittleEndian, uint16(len(record)))
+	buf.Write(record)
+	return buf.Bytes(), nil
+}
+
+//PSSH returns the version 1 PlayReady pssh box of the header.
+func (h *PlayReadyHeader) PSSH() (*PSSH, error) {
+	pro, err := h.PlayReadyObject()
+	if err != nil {
+		return nil, err
+	}
+	return NewPSSH(PlayReady, h.KIDs, pro), nil
+}
+
+//encodePlayReadyKID returns the base64 encoded KID in the GUID byte order PlayReady uses,
+//where the first three groups are little endian.
+func encodePlayReadyKID(kid KID) string {
+	return base64.StdEncoding.EncodeToString(swapGUID(kid[:]))
+}
+
+func decodePlayReadyKID(s string) (KID, error) {
+	var kid KID
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != len(kid) {
+		return kid, fmt.Errorf("invalid PlayReady KID %q", s)
+	}
+	copy(kid[:], swapGUID(b))
+	return kid, nil
+}
+
+func swapGUID(b []byte) []byte {
+	s := append([]byte(nil), b...)
+	s[0], s[1], s[2], s[3] = s[3], s[2], s[1], s[0]
+	s[4], s[5] = s[5], s[4]
+	s[6], s[7] = s[7], s[6]
+	return s
+}
+
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,180 @@
+package drm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+//PSSH is a Protection System Specific Header box, which carries the data a DRM system needs
+//to request the content keys. Version 1 boxes also list the KIDs of the keys the data applies to.
+type PSSH struct {
+	Version  uint8 //0 or 1.
+	SystemID SystemID
+	KIDs     []KID //Version 1 only.
+	Data     []byte
+}
+
+//NewPSSH returns the pssh box of system with data. The box is version 1 if kids isn't empty,
+//and version 0 otherwise.
+func NewPSSH(system SystemID, kids []KID, data []byte) *PSSH {
+	p := &PSSH{SystemID: system, KIDs: kids, Data: data}
+	if len(kids) > 0 {
+		p.Version = 1
+	}
+	return p
+}
+
+//ParsePSSH parses a pssh box.
+func ParsePSSH(b []byte) (*PSSH, error) {
+	p := &PSSH{}
+	if err := p.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//ParsePSSHBase64 parses a base64 encoded pssh box, as found in cenc:pssh elements.
+func ParsePSSHBase64(s string) (*PSSH, error) {
+	b, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace([]byte(s))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 pssh box: %v", err)
+	}
+	return ParsePSSH(b)
+}
+
+//ParseAll parses concatenated pssh boxes, usually one per DRM system.
+func ParseAll(b []byte) ([]*PSSH, error) {
+	var boxes []*PSSH
+	for len(b) > 0 {
+		size, _, err := boxHeader(b)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParsePSSH(b[:size])
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, p)
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+//MarshalBinary implements encoding.BinaryMarshaler interface for PSSH.
+func (p *PSSH) MarshalBinary() ([]byte, error) {
+	if p.Version > 1 {
+		return nil, fmt.Errorf("unsupported pssh version %d", p.Version)
+	}
+	if p.Version == 0 && len(p.KIDs) > 0 {
+		return nil, errors.New("version 0 pssh box can't list KIDs")
+	}
+
+	size := 32 + len(p.Data)
+	if p.Version == 1 {
+		size += 4 + 16*len(p.KIDs)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	binary.Write(buf, binary.BigEndian, uint32(size))
+	buf.WriteString("pssh")
+	//version and 24 bits of flags, which are always 0
+	binary.Write(buf, binary.BigEndian, uint32(p.Version)<<24)
+	buf.Write(p.SystemID[:])
+	if p.Version == 1 {
+		binary.Write(buf, binary.BigEndian, uint32(len(p.KIDs)))
+		for _, kid := range p.KIDs {
+			buf.Write(kid[:])
+		}
+	}
+	binary.Write(buf, binary.BigEndian, uint32(len(p.Data)))
+	buf.Write(p.Data)
+	return buf.Bytes(), nil
+}
+
+//UnmarshalBinary implements encoding.BinaryUnmarshaler interface for PSSH.
+func (p *PSSH) UnmarshalBinary(b []byte) error {
+	size, header, err := boxHeader(b)
+	if err != nil {
+		return err
+	}
+	if string(b[4:8]) != "pssh" {
+		return fmt.Errorf("expected pssh box, but got %q", b[4:8])
+	}
+	if size != len(b) {
+		return fmt.Errorf("pssh box size %d doesn't match data length %d", size, len(b))
+	}
+
+	body := b[header:]
+	if len(body) < 24 {
+		return errors.New("pssh box too short")
+	}
+	*p = PSSH{Version: body[0]}
+	if p.Version > 1 {
+		return fmt.Errorf("unsupported pssh version %d", p.Version)
+	}
+	copy(p.SystemID[:], body[4:20])
+	body = body[20:]
+
+	if p.Version == 1 {
+		count := int(binary.BigEndian.Uint32(body))
+		body = body[4:]
+		if count > len(body)/16 {
+			return fmt.Errorf("pssh box too short for %d KIDs", count)
+		}
+		p.KIDs = make([]KID, count)
+		for i := range p.KIDs {
+			copy(p.KIDs[i][:], body[:16])
+			body = body[16:]
+		}
+	}
+
+	if len(body) < 4 {
+		return errors.New("pssh box too short")
+	}
+	n := binary.BigEndian.Uint32(body)
+	body = body[4:]
+	if uint64(n) != uint64(len(body)) {
+		return fmt.Errorf("pssh data size %d doesn't match box size", n)
+	}
+	if n > 0 {
+		p.Data = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+//Base64 returns the base64 encoded box, as carried by cenc:pssh elements and HLS key URIs.
+func (p *PSSH) Base64() (string, error) {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+//boxHeader returns the size of the ISO BMFF box at the start of b, and the length of its header.
+func boxHeader(b []byte) (size int, header int, err error) {
+	if len(b) < 8 {
+		return 0, 0, errors.New("box too short")
+	}
+	size, header = int(binary.BigEndian.Uint32(b)), 8
+	switch size {
+	case 0:
+		//the box extends to the end of the data
+		size = len(b)
+	case 1:
+		if len(b) < 16 {
+			return 0, 0, errors.New("box too short")
+		}
+		large := binary.BigEndian.Uint64(b[8:])
+		if large > uint64(len(b)) {
+			return 0, 0, fmt.Errorf("box size %d exceeds data length %d", large, len(b))
+		}
+		size, header = int(large), 16
+	}
+	if size < header || size > len(b) {
+		return 0, 0, fmt.Errorf("invalid box size %d", size)
+	}
+	return size, header, nil
+}
@@ -0,0 +1,144 @@
+package drm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+//WidevineData is the system data of Widevine pssh boxes, a WidevinePsshData protocol buffers message.
+type WidevineData struct {
+	KIDs             []KID
+	Provider         string //Optional. Name of the content provider registered with Widevine.
+	ContentID        []byte //Optional. Identifies the content with the provider.
+	ProtectionScheme string //Optional. Possible Values: cenc, cbc1, cens, cbcs. Default: cenc.
+}
+
+//field numbers of the WidevinePsshData message
+const (
+	wvKeyID            = 2
+	wvProvider         = 3
+	wvContentID        = 4
+	wvProtectionScheme = 9
+)
+
+//protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+//ParseWidevineData parses the system data of a Widevine pssh box.
+func ParseWidevineData(b []byte) (*WidevineData, error) {
+	w := &WidevineData{}
+	if err := w.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+//PSSH returns the version 0 Widevine pssh box of the data. The KIDs are already in the data,
+//and some Widevine clients don't support version 1 boxes.
+func (w *WidevineData) PSSH() (*PSSH, error) {
+	data, err := w.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return NewPSSH(Widevine, nil, data), nil
+}
+
+//MarshalBinary implements encoding.BinaryMarshaler interface for WidevineData.
+func (w *WidevineData) MarshalBinary() ([]byte, error) {
+	var b []byte
+	for _, kid := range w.KIDs {
+		b = appendBytesField(b, wvKeyID, kid[:])
+	}
+	if w.Provider != "" {
+		b = appendBytesField(b, wvProvider, []byte(w.Provider))
+	}
+	if len(w.ContentID) > 0 {
+		b = appendBytesField(b, wvContentID, w.ContentID)
+	}
+	if w.ProtectionScheme != "" {
+		if len(w.ProtectionScheme) != 4 {
+			return nil, fmt.Errorf("invalid protection scheme %q", w.ProtectionScheme)
+		}
+		b = appendVarint(b, wvProtectionScheme<<3|wireVarint)
+		b = appendVarint(b, uint64(binary.BigEndian.Uint32([]byte(w.ProtectionScheme))))
+	}
+	return b, nil
+}
+
+//UnmarshalBinary implements encoding.BinaryUnmarshaler interface for WidevineData.
+//Fields that aren't modelled are skipped.
+func (w *WidevineData) UnmarshalBinary(b []byte) error {
+	*w = WidevineData{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid Widevine pssh data")
+		}
+		b = b[n:]
+
+		field, wire := key>>3, key&7
+		var value []byte
+		var number uint64
+		switch wire {
+		case wireVarint:
+			number, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("invalid Widevine pssh data")
+			}
+			b = b[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return errors.New("invalid Widevine pssh data")
+			}
+			value, b = b[n:n+int(length)], b[n+int(length):]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if wire == wireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errors.New("invalid Widevine pssh data")
+			}
+			b = b[size:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in Widevine pssh data", wire)
+		}
+
+		switch {
+		case field == wvKeyID && wire == wireBytes:
+			if len(value) != 16 {
+				return fmt.Errorf("invalid Widevine key ID length %d", len(value))
+			}
+			var kid KID
+			copy(kid[:], value)
+			w.KIDs = append(w.KIDs, kid)
+		case field == wvProvider && wire == wireBytes:
+			w.Provider = string(value)
+		case field == wvContentID && wire == wireBytes:
+			w.ContentID = append([]byte(nil), value...)
+		case field == wvProtectionScheme && wire == wireVarint:
+			scheme := make([]byte, 4)
+			binary.BigEndian.PutUint32(scheme, uint32(number))
+			w.ProtectionScheme = string(scheme)
+		}
+	}
+	return nil
+}
+
+func appendBytesField(b []byte, field uint64, value []byte) []byte {
+	b = appendVarint(b, field<<3|wireBytes)
+	b = appendVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
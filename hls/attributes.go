@@ -0,0 +1,156 @@
+package hls
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//ClientAttributes are the client-defined attributes of an EXT-X-DATERANGE, eg. X-COM-EXAMPLE-AD-ID="XYZ123",
+//keyed by name in the order they were set. Each value has one of the types of the HLS spec: a quoted-string,
+//a hexadecimal-sequence or a decimal-floating-point. Names are uppercase and start with X-.
+//Parsed values of none of these types are kept as written, and fail to encode until they're set again.
+//The zero value is empty and ready to use.
+type ClientAttributes struct {
+	names  []string
+	values map[string]interface{} //string, []byte, float64 or rawValue
+}
+
+//rawValue is a parsed attribute value that isn't a quoted-string, hexadecimal-sequence or decimal-floating-point.
+type rawValue string
+
+//Len returns the number of attributes.
+func (a *ClientAttributes) Len() int {
+	return len(a.names)
+}
+
+//Names returns the names of the attributes, in order.
+func (a *ClientAttributes) Names() []string {
+	return append([]string(nil), a.names...)
+}
+
+//Get returns the value of the attribute name: a string for a quoted-string, []byte for a hexadecimal-sequence
+//or float64 for a decimal-floating-point. Values of none of these types are returned as their unexported raw type.
+func (a *ClientAttributes) Get(name string) (interface{}, bool) {
+	v, ok := a.values[strings.ToUpper(name)]
+	return v, ok
+}
+
+//String returns the value of the attribute name, if it's a quoted-string.
+func (a *ClientAttributes) String(name string) (string, bool) {
+	v, ok := a.values[strings.ToUpper(name)].(string)
+	return v, ok
+}
+
+//Hex returns the value of the attribute name, if it's a hexadecimal-sequence.
+func (a *ClientAttributes) Hex(name string) ([]byte, bool) {
+	v, ok := a.values[strings.ToUpper(name)].([]byte)
+	return v, ok
+}
+
+//Float returns the value of the attribute name, if it's a decimal-floating-point.
+func (a *ClientAttributes) Float(name string) (float64, bool) {
+	v, ok := a.values[strings.ToUpper(name)].(float64)
+	return v, ok
+}
+
+//SetString sets the attribute name to the quoted-string value. An attribute that's already set keeps its position.
+func (a *ClientAttributes) SetString(name string, value string) {
+	a.set(name, value)
+}
+
+//SetHex sets the attribute name to the hexadecimal-sequence value. An attribute that's already set keeps its position.
+func (a *ClientAttributes) SetHex(name string, value []byte) {
+	a.set(name, value)
+}
+
+//SetFloat sets the attribute name to the decimal-floating-point value. An attribute that's already set keeps its position.
+func (a *ClientAttributes) SetFloat(name string, value float64) {
+	a.set(name, value)
+}
+
+//Delete removes the attribute name.
+func (a *ClientAttributes) Delete(name string) {
+	name = strings.ToUpper(name)
+	if _, ok := a.values[name]; !ok {
+		return
+	}
+	delete(a.values, name)
+	for i, n := range a.names {
+		if n == name {
+			a.names = append(a.names[:i], a.names[i+1:]...)
+			break
+		}
+	}
+}
+
+func (a *ClientAttributes) set(name string, value interface{}) {
+	name = strings.ToUpper(name)
+	if a.values == nil {
+		a.values = make(map[string]interface{})
+	}
+	if _, ok := a.values[name]; !ok {
+		a.names = append(a.names, name)
+	}
+	a.values[name] = value
+}
+
+//decode sets the attribute name from its raw value: a quoted-string, a hexadecimal-sequence
+//starting with 0x or 0X, or a decimal-floating-point. Other values are kept as written, so
+//playlists with them still parse, and are rejected by encode.
+func (a *ClientAttributes) decode(name string, raw string) {
+	switch {
+	case len(raw) >= 2 && strings.HasPrefix(raw, "\"") && strings.HasSuffix(raw, "\""):
+		a.SetString(name, raw[1:len(raw)-1])
+		return
+	case strings.HasPrefix(raw, "0x") || strings.HasPrefix(raw, "0X"):
+		digits := raw[2:]
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		if b, err := hex.DecodeString(digits); err == nil && len(b) > 0 {
+			a.SetHex(name, b)
+			return
+		}
+	default:
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			a.SetFloat(name, f)
+			return
+		}
+	}
+	a.set(name, rawValue(raw))
+}
+
+//encode returns the attributes as an attribute list, starting with a comma, with each value formatted by its type.
+func (a *ClientAttributes) encode() (string, error) {
+	var list []string
+	for _, name := range a.names {
+		if !strings.HasPrefix(name, "X-") {
+			return "", fmt.Errorf("EXT-X-DATERANGE client-defined attribute %s must start with X-", name)
+		}
+		for _, r := range name {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return "", fmt.Errorf("EXT-X-DATERANGE client-defined attribute %s must only contain A-Z, 0-9 and -", name)
+			}
+		}
+
+		switch v := a.values[name].(type) {
+		case string:
+			if strings.ContainsAny(v, "\"\r\n") {
+				return "", fmt.Errorf("quoted-string of %s must not contain double quotes or line breaks", name)
+			}
+			list = append(list, fmt.Sprintf("%s=\"%s\"", name, v))
+		case []byte:
+			list = append(list, fmt.Sprintf("%s=0x%X", name, v))
+		case float64:
+			list = append(list, fmt.Sprintf("%s=%s", name, strconv.FormatFloat(v, 'f', -1, 64)))
+		case rawValue:
+			return "", fmt.Errorf("%s value %s must be a quoted-string, hexadecimal-sequence or decimal-floating-point", name, v)
+		}
+	}
+	if len(list) == 0 {
+		return "", nil
+	}
+	return "," + strings.Join(list, ","), nil
+}
@@ -0,0 +1,108 @@
+package hls
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest"
+)
+
+func TestClientAttributes(t *testing.T) {
+	line := `#EXT-X-DATERANGE:ID="ad",START-DATE="2020-01-01T00:00:00Z",X-COM-EXAMPLE-AD-ID="Ad-Id/1,a",X-COM-EXAMPLE-CUE=0x0aFF,x-com-example-offset=12.5`
+	d, err := decodeDateRange(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := &d.ClientAttributes
+	if names := attrs.Names(); !reflect.DeepEqual(names, []string{"X-COM-EXAMPLE-AD-ID", "X-COM-EXAMPLE-CUE", "X-COM-EXAMPLE-OFFSET"}) {
+		t.Errorf("Expected client attributes in order, but got %v", names)
+	}
+	if v, ok := attrs.String("X-COM-EXAMPLE-AD-ID"); !ok || v != "Ad-Id/1,a" {
+		t.Errorf("Expected X-COM-EXAMPLE-AD-ID to be Ad-Id/1,a, but got %q", v)
+	}
+	if v, ok := attrs.Hex("X-COM-EXAMPLE-CUE"); !ok || !bytes.Equal(v, []byte{0x0a, 0xff}) {
+		t.Errorf("Expected X-COM-EXAMPLE-CUE to be 0x0AFF, but got %x", v)
+	}
+	if v, ok := attrs.Float("x-com-example-offset"); !ok || v != 12.5 {
+		t.Errorf("Expected X-COM-EXAMPLE-OFFSET to be 12.5, but got %v", v)
+	}
+	if _, ok := attrs.Float("X-COM-EXAMPLE-AD-ID"); ok {
+		t.Errorf("Expected X-COM-EXAMPLE-AD-ID not to be a decimal-floating-point")
+	}
+	if _, ok := attrs.Get("X-COM-EXAMPLE-MISSING"); ok {
+		t.Errorf("Expected X-COM-EXAMPLE-MISSING not to be set")
+	}
+
+	buf := manifest.NewBufWrapper()
+	d.writeDateRange(buf)
+	if buf.Err != nil {
+		t.Fatal(buf.Err)
+	}
+	if !strings.Contains(buf.Buf.String(), `,X-COM-EXAMPLE-AD-ID="Ad-Id/1,a",X-COM-EXAMPLE-CUE=0x0AFF,X-COM-EXAMPLE-OFFSET=12.5`) {
+		t.Errorf("Expected typed client attributes in order, but got %s", buf.Buf.String())
+	}
+
+	attrs.Delete("X-COM-EXAMPLE-CUE")
+	attrs.SetString("X-COM-EXAMPLE-AD-ID", "2")
+	if names := attrs.Names(); !reflect.DeepEqual(names, []string{"X-COM-EXAMPLE-AD-ID", "X-COM-EXAMPLE-OFFSET"}) || attrs.Len() != 2 {
+		t.Errorf("Expected X-COM-EXAMPLE-CUE deleted and X-COM-EXAMPLE-AD-ID kept in place, but got %v", names)
+	}
+
+}
+
+func TestClientAttributesUntyped(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	err := p.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="ad",START-DATE="2020-01-01T00:00:00Z",X-COM-FOO=bar,X-COM-CUE=0xZZ
+#EXTINF:6.000,
+0.ts
+`))
+	if err != nil {
+		t.Fatalf("Expected untyped client attributes to parse, but got %s", err)
+	}
+
+	attrs := &p.Segments[0].DateRange.ClientAttributes
+	if names := attrs.Names(); !reflect.DeepEqual(names, []string{"X-COM-FOO", "X-COM-CUE"}) {
+		t.Errorf("Expected untyped client attributes to be kept, but got %v", names)
+	}
+	if _, ok := attrs.String("X-COM-FOO"); ok {
+		t.Errorf("Expected X-COM-FOO not to be a quoted-string")
+	}
+	if _, err := p.Encode(); err == nil {
+		t.Errorf("Expected error encoding an unquoted X-COM-FOO value")
+	}
+
+	attrs.SetString("X-COM-FOO", "bar")
+	attrs.Delete("X-COM-CUE")
+	if _, err := p.Encode(); err != nil {
+		t.Errorf("Expected X-COM-FOO to encode once set, but got %s", err)
+	}
+}
+
+func TestClientAttributesEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"X-COM-EXAMPLE", `say "hi"`},
+		{"X-COM-EXAMPLE", "two\nlines"},
+		{"COM-EXAMPLE", "no prefix"},
+		{"X-COM_EXAMPLE", "underscore"},
+	}
+	for _, tt := range tests {
+		d := &DateRange{ID: "ad", StartDate: time.Now()}
+		d.ClientAttributes.SetString(tt.name, tt.value)
+		buf := manifest.NewBufWrapper()
+		d.writeDateRange(buf)
+		if buf.Err == nil {
+			t.Errorf("Expected error encoding %s=%q", tt.name, tt.value)
+		}
+	}
+}
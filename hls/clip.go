@@ -0,0 +1,186 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+)
+
+//Clip returns a VOD playlist of the segments of p playing from start to end, in seconds from the start of
+//the playlist. Segments can't be cut, so the clip starts with the segment playing at start, with an EXT-X-START
+//at start for a frame-accurate start, and ends with the segment playing at end.
+//
+//The first segment of the clip lists the keys and map that apply to it and its EXT-X-PROGRAM-DATE-TIME,
+//and the first segment of each resource gets an explicit EXT-X-BYTERANGE offset.
+//EXT-X-DATERANGE tags of the clip are kept, and date ranges started before the clip that still cover its
+//segments are added to the first segment of the clip.
+//p is left unchanged.
+func (p *MediaPlaylist) Clip(start float64, end float64) (*MediaPlaylist, error) {
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("clip from %v to %v must start at or after 0 and end after its start", start, end)
+	}
+	first := p.indexAtOffset(start)
+	if first == -1 {
+		return nil, fmt.Errorf("clip start %v is outside the playlist", start)
+	}
+
+	var firstStart float64
+	discontinuities := 0
+	for _, s := range p.Segments[:first] {
+		firstStart += s.duration()
+		if s.Discontinuity {
+			discontinuities++
+		}
+	}
+	last := first
+	for offset := firstStart + p.Segments[first].duration(); offset < end && last+1 < len(p.Segments); last++ {
+		offset += p.Segments[last+1].duration()
+	}
+
+	c := &MediaPlaylist{
+		Variant:               p.Variant,
+		Version:               p.Version,
+		TargetDuration:        p.TargetDuration,
+		MediaSequence:         p.MediaSequence + first,
+		DiscontinuitySequence: p.DiscontinuitySequence + discontinuities,
+		EndList:               true,
+		Type:                  "VOD",
+		IFramesOnly:           p.IFramesOnly,
+		AllowCache:            p.AllowCache,
+		IndependentSegments:   p.IndependentSegments,
+	}
+	if offset := start - firstStart; offset > 0 {
+		c.StartPoint = &StartPoint{TimeOffset: offset, Precise: true}
+	}
+	for _, s := range p.Segments[first : last+1] {
+		copied := *s
+		c.Segments = append(c.Segments, &copied)
+	}
+	explicitOffsets(c.Segments, byteOffsets(p.Segments)[first:last+1])
+
+	//Encode only writes the keys and map where they change, so the first segment must list them
+	head := c.Segments[0]
+	for i := first; i >= 0 && (len(head.Keys) == 0 || head.Map == nil); i-- {
+		if len(head.Keys) == 0 {
+			head.Keys = p.Segments[i].Keys
+		}
+		if head.Map == nil {
+			head.Map = p.Segments[i].Map
+		}
+	}
+
+	dates := p.ProgramDateTimes()
+	if !dated(dates) {
+		return c, nil
+	}
+	if head.ProgramDateTime.IsZero() {
+		head.ProgramDateTime = dates[first]
+	}
+	if err := p.carryDateRanges(c, first, last); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//carryDateRanges adds the date ranges of p first tagged before the segments first to last, and covering them,
+//to the clip c of these segments.
+func (p *MediaPlaylist) carryDateRanges(c *MediaPlaylist, first int, last int) error {
+	t, err := p.Timeline()
+	if err != nil {
+		return err
+	}
+
+	clipped := make(map[*Segment]bool)
+	tagged := make(map[*DateRange]bool)
+	for _, s := range p.Segments[first : last+1] {
+		clipped[s] = true
+		for _, d := range s.dateRanges() {
+			tagged[d] = true
+		}
+	}
+
+	for _, r := range t.Ranges {
+		if tagged[r.Tags[0]] || !coversAny(r.Segments, clipped) {
+			continue
+		}
+		d := *r.DateRange
+		d.SCTE35 = r.Tags[0].SCTE35
+		c.Segments[0].addDateRange(&d)
+	}
+	return nil
+}
+
+func coversAny(segments []*Segment, clipped map[*Segment]bool) bool {
+	for _, s := range segments {
+		if clipped[s] {
+			return true
+		}
+	}
+	return false
+}
+
+//byteOffsets returns the offset of the byte range of each of segments, resolving the offsets left implicit,
+//which start after the byte range of the previous segment of the same resource. Segments without a byte range
+//have an offset of 0.
+func byteOffsets(segments []*Segment) []int64 {
+	offsets := make([]int64, len(segments))
+	next := make(map[string]int64)
+	for i, s := range segments {
+		if s.Byterange == nil {
+			continue
+		}
+		offsets[i] = next[s.URI]
+		if s.Byterange.Offset != nil {
+			offsets[i] = *s.Byterange.Offset
+		}
+		next[s.URI] = offsets[i] + s.Byterange.Length
+	}
+	return offsets
+}
+
+//explicitOffsets sets the offsets, as returned by byteOffsets, on the byte ranges of segments with an implicit
+//offset that don't follow a segment of the same resource, as their offset would otherwise follow another
+//resource. The byte ranges are replaced rather than changed, so they can be shared with another playlist.
+func explicitOffsets(segments []*Segment, offsets []int64) {
+	for i, s := range segments {
+		if s.Byterange == nil || s.Byterange.Offset != nil || (i > 0 && segments[i-1].URI == s.URI) {
+			continue
+		}
+		offset := offsets[i]
+		s.Byterange = &Byterange{Length: s.Byterange.Length, Offset: &offset}
+	}
+}
+
+//Clip reads the Media Playlist of every variant, I-frame playlist and rendition of m from src, and clips them
+//from start to end, in seconds, with MediaPlaylist.Clip. The clipped playlists are returned by their URI as
+//listed in the returned copy of m, which has no EXT-X-START as each clip has its own.
+func (m *MasterPlaylist) Clip(ctx context.Context, src Source, start float64, end float64) (*MasterPlaylist, map[string]*MediaPlaylist, error) {
+	clips := make(map[string]*MediaPlaylist)
+	for _, v := range m.Variants {
+		if _, ok := clips[v.URI]; ok {
+			continue
+		}
+		p, err := src.Media(ctx, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		if clips[v.URI], err = p.Clip(start, end); err != nil {
+			return nil, nil, fmt.Errorf("failed to clip %s: %v", v.URI, err)
+		}
+	}
+	for _, r := range m.Renditions {
+		if _, ok := clips[r.URI]; ok || r.URI == "" {
+			continue
+		}
+		p, err := r.MediaPlaylist(ctx, src)
+		if err != nil {
+			return nil, nil, err
+		}
+		if clips[r.URI], err = p.Clip(start, end); err != nil {
+			return nil, nil, fmt.Errorf("failed to clip %s: %v", r.URI, err)
+		}
+	}
+
+	c := *m
+	c.StartPoint = nil
+	return &c, clips, nil
+}
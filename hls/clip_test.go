@@ -0,0 +1,193 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const clipPlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="ad",START-DATE="2020-01-01T00:00:00Z",DURATION=12,SCTE35-OUT=0xFC30
+#EXTINF:6.000,
+0.mp4
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:06Z
+#EXTINF:6.000,
+1.mp4
+#EXT-X-DATERANGE:ID="chapter",START-DATE="2020-01-01T00:00:12Z"
+#EXTINF:6.000,
+2.mp4
+#EXTINF:6.000,
+3.mp4
+#EXTINF:6.000,
+4.mp4
+#EXT-X-ENDLIST
+`
+
+func TestMediaPlaylistClip(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(clipPlaylist)); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := p.Clip(7, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentURIs(c.Segments); got != "1.mp4,2.mp4,3.mp4" {
+		t.Errorf("Expected segments 1.mp4,2.mp4,3.mp4, but got %s", got)
+	}
+	if c.MediaSequence != 11 || c.DiscontinuitySequence != 0 || c.Type != "VOD" || !c.EndList {
+		t.Errorf("Expected VOD clip from Media Sequence 11, but got %+v", c)
+	}
+	if c.StartPoint == nil || c.StartPoint.TimeOffset != 1 || !c.StartPoint.Precise {
+		t.Errorf("Expected EXT-X-START:TIME-OFFSET=1,PRECISE=YES, but got %+v", c.StartPoint)
+	}
+
+	head := c.Segments[0]
+	if head.DateRange == nil || head.DateRange.ID != "ad" || head.DateRange.SCTE35 == nil || head.DateRange.SCTE35.Value != "0xFC30" {
+		t.Errorf("Expected the ad date range to be carried to the clip, but got %+v", head.DateRange)
+	}
+	if p.Segments[1].DateRange != nil {
+		t.Errorf("Expected the playlist to be left unchanged")
+	}
+
+	encoded := encodeString(t, c)
+	for _, tag := range []string{`#EXT-X-KEY:METHOD=AES-128,URI="key.bin"`, `#EXT-X-MAP:URI="init.mp4"`, "#EXT-X-START:TIME-OFFSET=1.000,PRECISE=YES", "#EXT-X-MEDIA-SEQUENCE:11", "ID=chapter"} {
+		if !strings.Contains(encoded, tag) {
+			t.Errorf("Expected clip to contain %s, but got\n%s", tag, encoded)
+		}
+	}
+	if strings.Contains(encoded, "0.mp4") || strings.Contains(encoded, "4.mp4") {
+		t.Errorf("Expected clip not to contain segments outside the range, but got\n%s", encoded)
+	}
+
+	c, err = p.Clip(12, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentURIs(c.Segments); got != "2.mp4,3.mp4,4.mp4" || c.StartPoint != nil || c.DiscontinuitySequence != 1 {
+		t.Errorf("Expected segments 2.mp4,3.mp4,4.mp4 after a discontinuity without EXT-X-START, but got %s %+v", got, c)
+	}
+	if d := c.Segments[0].ProgramDateTime; !d.Equal(time.Date(2020, 1, 1, 0, 0, 12, 0, time.UTC)) {
+		t.Errorf("Expected the first segment of the clip to be dated, but got %v", d)
+	}
+
+	for _, r := range [][2]float64{{-1, 6}, {6, 6}, {30, 40}} {
+		if _, err := p.Clip(r[0], r[1]); err == nil {
+			t.Errorf("Expected error clipping from %v to %v", r[0], r[1])
+		}
+	}
+}
+
+func TestMediaPlaylistClipByterange(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	err := p.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-VERSION:4
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.000,
+#EXT-X-BYTERANGE:1000@0
+main.ts
+#EXTINF:6.000,
+#EXT-X-BYTERANGE:1200
+main.ts
+#EXTINF:6.000,
+#EXT-X-BYTERANGE:800
+main.ts
+#EXTINF:6.000,
+#EXT-X-BYTERANGE:500@100
+other.ts
+#EXTINF:6.000,
+#EXT-X-BYTERANGE:500
+other.ts
+#EXT-X-ENDLIST
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := p.Clip(6, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[1].Byterange.Offset != nil {
+		t.Errorf("Expected the playlist to be left unchanged")
+	}
+	//only the first segment of each resource needs an explicit offset
+	for i, expected := range []string{"1200@1000", "800", "500@100", "500"} {
+		if got := byterangeString(c.Segments[i].Byterange); got != expected {
+			t.Errorf("Expected byte range %s for segment %d, but got %s", expected, i, got)
+		}
+	}
+}
+
+//byterangeString formats b as the value of EXT-X-BYTERANGE.
+func byterangeString(b *Byterange) string {
+	if b.Offset == nil {
+		return fmt.Sprint(b.Length)
+	}
+	return fmt.Sprintf("%d@%d", b.Length, *b.Offset)
+}
+
+//clipSource is a Source parsing the Media Playlists of variants from memory.
+type clipSource struct {
+	memSource
+}
+
+func (s *clipSource) Media(ctx context.Context, variant *Variant) (*MediaPlaylist, error) {
+	uri, err := variant.AbsoluteURL()
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	p := NewMediaPlaylist(0).WithVariant(variant)
+	return p, p.Parse(body)
+}
+
+func TestMasterPlaylistClip(t *testing.T) {
+	src := &clipSource{memSource{
+		resources: map[string][]byte{"https://example.com/video.m3u8": []byte(clipPlaylist), "https://example.com/audio.m3u8": []byte(clipPlaylist)},
+		requests:  map[string]int{},
+	}}
+	m := NewMasterPlaylist(7)
+	m.URI = "https://example.com/master.m3u8"
+	m.StartPoint = &StartPoint{TimeOffset: 3}
+	m.Variants = []*Variant{{URI: "video.m3u8", Bandwidth: 800000}}
+	m.Renditions = []*Rendition{{Type: "AUDIO", GroupID: "aud", Name: "en", URI: "audio.m3u8"}, {Type: "CLOSED-CAPTIONS", GroupID: "cc", Name: "cc", InstreamID: "CC1"}}
+	for _, v := range m.Variants {
+		v.masterPlaylist = m
+	}
+	for _, r := range m.Renditions {
+		r.masterPlaylist = m
+	}
+
+	c, clips, err := m.Clip(context.Background(), src, 7, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.StartPoint != nil || m.StartPoint == nil {
+		t.Errorf("Expected the clip of the master playlist to have no EXT-X-START")
+	}
+	if len(clips) != 2 || clips["video.m3u8"] == nil || clips["audio.m3u8"] == nil {
+		t.Fatalf("Expected clips of video.m3u8 and audio.m3u8, but got %v", clips)
+	}
+	if got := segmentURIs(clips["audio.m3u8"].Segments); got != "1.mp4,2.mp4,3.mp4" {
+		t.Errorf("Expected rendition segments 1.mp4,2.mp4,3.mp4, but got %s", got)
+	}
+	if uri, _ := clips["audio.m3u8"].Segments[0].AbsoluteURL(); uri != "https://example.com/1.mp4" {
+		t.Errorf("Expected rendition segments to resolve against the rendition, but got %s", uri)
+	}
+}
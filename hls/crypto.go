@@ -0,0 +1,289 @@
+package hls
+
+import (
+	"context"
+	aescipher "crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+//identity is the KEYFORMAT of keys that are the 16 byte AES key itself. It's the default KEYFORMAT.
+const identity = "identity"
+
+//ErrInvalidPadding is returned when the PKCS7 padding of a decrypted segment is invalid, usually
+//because the key or IV is wrong.
+var ErrInvalidPadding = errors.New("invalid PKCS7 padding of AES-128 segment")
+
+//SegmentIV returns the IV used to encrypt the segment of media sequence number sequence with the
+//key: the IV attribute if present, else the sequence number as a big-endian 128-bit integer.
+func (k *Key) SegmentIV(sequence int) ([]byte, error) {
+	iv := make([]byte, aescipher.BlockSize)
+	if k.IV == "" {
+		binary.BigEndian.PutUint64(iv[8:], uint64(sequence))
+		return iv, nil
+	}
+
+	s := k.IV
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if len(s) > 2*aescipher.BlockSize {
+		return nil, fmt.Errorf("IV %s is longer than 128 bits", k.IV)
+	}
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IV %s: %v", k.IV, err)
+	}
+	copy(iv[len(iv)-len(b):], b)
+	return iv, nil
+}
+
+//NewDecrypter returns a Reader that decrypts the AES-128 segment read from r, encrypted with
+//key and iv using AES-128-CBC and PKCS7 padding.
+func NewDecrypter(r io.Reader, key, iv []byte) (io.Reader, error) {
+	mode, err := newBlockMode(key, iv, cipher.NewCBCDecrypter)
+	if err != nil {
+		return nil, err
+	}
+	return &decrypter{r: r, mode: mode, chunk: make([]byte, 32*1024)}, nil
+}
+
+//NewEncrypter returns a WriteCloser that encrypts the segment written to it with key and iv,
+//using AES-128-CBC and PKCS7 padding, and writes it to w. Close must be called to write the
+//padded last block, it doesn't close w.
+func NewEncrypter(w io.Writer, key, iv []byte) (io.WriteCloser, error) {
+	mode, err := newBlockMode(key, iv, cipher.NewCBCEncrypter)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypter{w: w, mode: mode}, nil
+}
+
+func newBlockMode(key, iv []byte, mode func(cipher.Block, []byte) cipher.BlockMode) (cipher.BlockMode, error) {
+	if len(key) != 16 {
+		return nil, fmt.Errorf("AES-128 key must be 16 bytes, but got %d", len(key))
+	}
+	if len(iv) != aescipher.BlockSize {
+		return nil, fmt.Errorf("AES-128 IV must be 16 bytes, but got %d", len(iv))
+	}
+	block, err := aescipher.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return mode(block, iv), nil
+}
+
+type decrypter struct {
+	r     io.Reader
+	mode  cipher.BlockMode
+	chunk []byte
+	in    []byte //ciphertext not decrypted yet
+	out   []byte //plaintext not read yet
+	read  int
+	err   error
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		n, err := d.r.Read(d.chunk)
+		d.in = append(d.in, d.chunk[:n]...)
+		d.read += n
+
+		switch {
+		case err == io.EOF:
+			d.err = d.finish()
+			if len(d.out) == 0 {
+				return 0, d.err
+			}
+		case err != nil:
+			d.err = err
+		default:
+			//the last block is kept until EOF, as it holds the padding
+			if blocks := len(d.in)/aescipher.BlockSize*aescipher.BlockSize - aescipher.BlockSize; blocks > 0 {
+				d.decrypt(blocks)
+			}
+		}
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+//finish decrypts the last block and removes the padding, returning io.EOF if the segment is valid.
+func (d *decrypter) finish() error {
+	if d.read == 0 || len(d.in)%aescipher.BlockSize != 0 {
+		return fmt.Errorf("AES-128 segment length %d isn't a multiple of the block size", d.read)
+	}
+	d.decrypt(len(d.in))
+	pad := int(d.out[len(d.out)-1])
+	if pad == 0 || pad > aescipher.BlockSize || pad > len(d.out) {
+		d.out = nil
+		return ErrInvalidPadding
+	}
+	for _, b := range d.out[len(d.out)-pad:] {
+		if int(b) != pad {
+			d.out = nil
+			return ErrInvalidPadding
+		}
+	}
+	d.out = d.out[:len(d.out)-pad]
+	return io.EOF
+}
+
+//decrypt decrypts the first n bytes of in to out.
+func (d *decrypter) decrypt(n int) {
+	out := make([]byte, n)
+	d.mode.CryptBlocks(out, d.in[:n])
+	d.out = append(d.out, out...)
+	d.in = append(d.in[:0], d.in[n:]...)
+}
+
+type encrypter struct {
+	w      io.Writer
+	mode   cipher.BlockMode
+	buf    []byte
+	closed bool
+}
+
+func (e *encrypter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed AES-128 encrypter")
+	}
+	e.buf = append(e.buf, p...)
+	if n := len(e.buf) / aescipher.BlockSize * aescipher.BlockSize; n > 0 {
+		if err := e.encrypt(n); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (e *encrypter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	pad := aescipher.BlockSize - len(e.buf)%aescipher.BlockSize
+	for i := 0; i < pad; i++ {
+		e.buf = append(e.buf, byte(pad))
+	}
+	return e.encrypt(len(e.buf))
+}
+
+//encrypt encrypts and writes the first n bytes of buf.
+func (e *encrypter) encrypt(n int) error {
+	out := make([]byte, n)
+	e.mode.CryptBlocks(out, e.buf[:n])
+	e.buf = append(e.buf[:0], e.buf[n:]...)
+	_, err := e.w.Write(out)
+	return err
+}
+
+//KeyCache fetches AES-128 keys through a Source, keeping each key by its URI so segments
+//sharing a key only fetch it once. It's safe for concurrent use.
+type KeyCache struct {
+	src  Source
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+//NewKeyCache returns a KeyCache fetching keys from src.
+func NewKeyCache(src Source) *KeyCache {
+	return &KeyCache{src: src, keys: make(map[string][]byte)}
+}
+
+//Key returns the 16 byte AES-128 key of k, fetching it if it isn't cached yet.
+func (c *KeyCache) Key(ctx context.Context, k *Key) ([]byte, error) {
+	if k.Keyformat != "" && k.Keyformat != identity {
+		return nil, fmt.Errorf("unsupported KEYFORMAT %s", k.Keyformat)
+	}
+	uri, err := k.resourceURL()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	key, ok := c.keys[uri]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	body, err := c.src.Resource(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key %s: %v", uri, err)
+	}
+	defer body.Close()
+	key, err = ioutil.ReadAll(io.LimitReader(body, 17))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key %s: %v", uri, err)
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("key %s isn't 16 bytes long", uri)
+	}
+
+	c.mu.Lock()
+	c.keys[uri] = key
+	c.mu.Unlock()
+	return key, nil
+}
+
+//Decrypt returns a Reader of the decrypted content of segment s read from r. Segments that
+//aren't encrypted are returned as is, and segments encrypted with a method other than AES-128
+//return an error.
+func (c *KeyCache) Decrypt(ctx context.Context, s *Segment, r io.Reader) (io.Reader, error) {
+	var key *Key
+	encrypted := false
+	for _, k := range s.Keys {
+		switch strings.ToUpper(k.Method) {
+		case none:
+			continue
+		case aes:
+			encrypted = true
+		default:
+			return nil, fmt.Errorf("unsupported segment encryption method %s", k.Method)
+		}
+		if k.Keyformat == "" || k.Keyformat == identity {
+			key = k
+			break
+		}
+	}
+	if !encrypted {
+		return r, nil
+	}
+	if key == nil {
+		return nil, errors.New("segment has no AES-128 key of KEYFORMAT identity")
+	}
+
+	value, err := c.Key(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := key.SegmentIV(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	return NewDecrypter(r, value, iv)
+}
+
+//resourceURL returns the URI of the key, resolved against its playlist if it has one.
+func (k *Key) resourceURL() (string, error) {
+	if (k.IsSession && k.masterPlaylist == nil) || (!k.IsSession && k.mediaPlaylist == nil) {
+		return k.URI, nil
+	}
+	return k.AbsoluteURL()
+}
@@ -0,0 +1,202 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	aescipher "crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var (
+	testKey = []byte("0123456789abcdef")
+	testIV  = []byte("fedcba9876543210")
+)
+
+//memSource is a Source serving resources from memory, counting the requests of each.
+type memSource struct {
+	resources map[string][]byte
+	requests  map[string]int
+}
+
+func (s *memSource) Master(ctx context.Context, uri string) (*MasterPlaylist, error) {
+	return nil, fmt.Errorf("%s not found", uri)
+}
+
+func (s *memSource) Media(ctx context.Context, variant *Variant) (*MediaPlaylist, error) {
+	return nil, fmt.Errorf("%s not found", variant.URI)
+}
+
+func (s *memSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	s.requests[uri]++
+	b, ok := s.resources[uri]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", uri)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func encrypt(t *testing.T, plain, key, iv []byte) []byte {
+	buf := new(bytes.Buffer)
+	w, err := NewEncrypter(buf, key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//write in uneven pieces to exercise the partial block buffering
+	for len(plain) > 0 {
+		n := 7
+		if n > len(plain) {
+			n = len(plain)
+		}
+		if _, err := w.Write(plain[:n]); err != nil {
+			t.Fatal(err)
+		}
+		plain = plain[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, size := range []int{0, 1, 15, 16, 17, 188 * 7, 100000} {
+		plain := bytes.Repeat([]byte{0x47}, size)
+		for i := range plain {
+			plain[i] = byte(i)
+		}
+		encrypted := encrypt(t, plain, testKey, testIV)
+
+		//compare with a single pass CBC encryption of the padded segment
+		pad := aescipher.BlockSize - size%aescipher.BlockSize
+		padded := append(append([]byte(nil), plain...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+		block, _ := aescipher.NewCipher(testKey)
+		expected := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, testIV).CryptBlocks(expected, padded)
+		if !bytes.Equal(encrypted, expected) {
+			t.Errorf("Expected %d byte segment to encrypt to %d bytes of CBC, but got %d bytes", size, len(expected), len(encrypted))
+		}
+
+		r, err := NewDecrypter(iotest.OneByteReader(bytes.NewReader(encrypted)), testKey, testIV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, plain) {
+			t.Errorf("Expected %d byte segment to decrypt, but got %d bytes", size, len(decrypted))
+		}
+	}
+
+	encrypted := encrypt(t, []byte("segment"), testKey, testIV)
+	r, _ := NewDecrypter(bytes.NewReader(encrypted), []byte("0000000000000000"), testIV)
+	if _, err := ioutil.ReadAll(r); err != ErrInvalidPadding {
+		t.Errorf("Expected ErrInvalidPadding for the wrong key, but got %v", err)
+	}
+	r, _ = NewDecrypter(bytes.NewReader(encrypted[:10]), testKey, testIV)
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("Expected error for truncated segment")
+	}
+	if _, err := NewDecrypter(bytes.NewReader(encrypted), testKey[:8], testIV); err == nil {
+		t.Error("Expected error for 8 byte key")
+	}
+}
+
+func TestSegmentIV(t *testing.T) {
+	tests := []struct {
+		key      *Key
+		sequence int
+		expected string
+	}{
+		{&Key{Method: "AES-128", URI: "key"}, 0, "00000000000000000000000000000000"},
+		{&Key{Method: "AES-128", URI: "key"}, 300, "0000000000000000000000000000012c"},
+		{&Key{Method: "AES-128", URI: "key", IV: "0x0102030405060708090A0B0C0D0E0F10"}, 300, "0102030405060708090a0b0c0d0e0f10"},
+		{&Key{Method: "AES-128", URI: "key", IV: "0X1f"}, 0, "0000000000000000000000000000001f"},
+	}
+	for _, tt := range tests {
+		iv, err := tt.key.SegmentIV(tt.sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprintf("%x", iv) != tt.expected {
+			t.Errorf("Expected IV %s, but got %x", tt.expected, iv)
+		}
+	}
+
+	if _, err := (&Key{IV: "0xzz"}).SegmentIV(0); err == nil {
+		t.Error("Expected error for invalid IV")
+	}
+}
+
+func TestKeyCacheDecrypt(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:41
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXTINF:6.0,
+41.ts
+#EXTINF:6.0,
+42.ts
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x66656463626139383736353433323130
+#EXTINF:6.0,
+43.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:6.0,
+44.ts
+#EXT-X-ENDLIST
+`
+	p := NewMediaPlaylist(3).WithVariant(&Variant{URI: "https://example.com/hls/media.m3u8"})
+	if err := p.Parse(strings.NewReader(playlist)); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &memSource{
+		resources: map[string][]byte{"https://example.com/hls/key.bin": testKey},
+		requests:  map[string]int{},
+	}
+	cache := NewKeyCache(src)
+	for _, s := range p.Segments {
+		plain := []byte("segment " + s.URI)
+		var segment []byte
+		switch s.ID {
+		case 41, 42:
+			iv, _ := (&Key{}).SegmentIV(s.ID)
+			segment = encrypt(t, plain, testKey, iv)
+		case 43:
+			segment = encrypt(t, plain, testKey, testIV)
+		default:
+			segment = plain
+		}
+
+		r, err := cache.Decrypt(context.Background(), s, bytes.NewReader(segment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", s.URI, err)
+		}
+		if !bytes.Equal(decrypted, plain) {
+			t.Errorf("Expected %q, but got %q", plain, decrypted)
+		}
+	}
+	if n := src.requests["https://example.com/hls/key.bin"]; n != 1 {
+		t.Errorf("Expected key to be fetched once, but got %d requests", n)
+	}
+
+	s := &Segment{Keys: []*Key{{Method: "SAMPLE-AES", URI: "key.bin"}}}
+	if _, err := cache.Decrypt(context.Background(), s, bytes.NewReader(nil)); err == nil {
+		t.Error("Expected error for SAMPLE-AES segment")
+	}
+	if _, err := cache.Key(context.Background(), &Key{Method: "AES-128", URI: "https://example.com/missing"}); err == nil {
+		t.Error("Expected error for missing key")
+	}
+}
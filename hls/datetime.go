@@ -0,0 +1,105 @@
+package hls
+
+import "time"
+
+//Duration returns the total duration of the segments of the playlist, in seconds. Segments without
+//EXTINF count as zero.
+func (p *MediaPlaylist) Duration() float64 {
+	var d float64
+	for _, s := range p.Segments {
+		d += s.duration()
+	}
+	return d
+}
+
+//ProgramDateTimes returns the wall-clock start of every segment of the playlist: its EXT-X-PROGRAM-DATE-TIME,
+//or the one of the nearest segment before it plus the EXTINF durations in between. The date is reset by an
+//EXT-X-DISCONTINUITY without EXT-X-PROGRAM-DATE-TIME, so the dates of segments before the first
+//EXT-X-PROGRAM-DATE-TIME and after such a discontinuity are zero until the next one.
+func (p *MediaPlaylist) ProgramDateTimes() []time.Time {
+	dates := make([]time.Time, len(p.Segments))
+	var next time.Time
+	for i, s := range p.Segments {
+		switch {
+		case !s.ProgramDateTime.IsZero():
+			next = s.ProgramDateTime
+		case s.Discontinuity:
+			next = time.Time{}
+		}
+		dates[i] = next
+		if !next.IsZero() {
+			next = next.Add(time.Duration(s.duration() * float64(time.Second)))
+		}
+	}
+	return dates
+}
+
+//SegmentAtTime returns the segment playing at the wall-clock time t, or nil if no segment with a known
+//date plays at t.
+func (p *MediaPlaylist) SegmentAtTime(t time.Time) *Segment {
+	if i := p.indexAtTime(t); i != -1 {
+		return p.Segments[i]
+	}
+	return nil
+}
+
+//SegmentAtOffset returns the segment playing at offset seconds of the playlist. Like the TIME-OFFSET of
+//EXT-X-START, a negative offset is from the end of the last segment. It returns nil if the offset is outside
+//the playlist.
+func (p *MediaPlaylist) SegmentAtOffset(offset float64) *Segment {
+	if i := p.indexAtOffset(offset); i != -1 {
+		return p.Segments[i]
+	}
+	return nil
+}
+
+//indexAtTime returns the index of the segment playing at t, or -1.
+func (p *MediaPlaylist) indexAtTime(t time.Time) int {
+	for i, start := range p.ProgramDateTimes() {
+		if start.IsZero() {
+			continue
+		}
+		end := start.Add(time.Duration(p.Segments[i].duration() * float64(time.Second)))
+		if !t.Before(start) && t.Before(end) {
+			return i
+		}
+	}
+	return -1
+}
+
+//indexAtOffset returns the index of the segment playing at offset seconds, or -1.
+func (p *MediaPlaylist) indexAtOffset(offset float64) int {
+	if offset < 0 {
+		offset += p.Duration()
+	}
+	if offset < 0 {
+		return -1
+	}
+	var start float64
+	for i, s := range p.Segments {
+		end := start + s.duration()
+		if offset >= start && offset < end {
+			return i
+		}
+		start = end
+	}
+	return -1
+}
+
+//dated returns true if any of dates is known.
+func dated(dates []time.Time) bool {
+	for _, d := range dates {
+		if !d.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+//duration returns the EXTINF duration of the segment, in seconds, or zero without EXTINF.
+func (s *Segment) duration() float64 {
+	if s.Inf == nil {
+		return 0
+	}
+	return s.Inf.Duration
+}
@@ -0,0 +1,106 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgramDateTimes(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := NewMediaPlaylist(7)
+	p.Segments = Segments{
+		&Segment{URI: "pre.ts", Inf: &Inf{Duration: 4}},
+		&Segment{URI: "0.ts", Inf: &Inf{Duration: 6}, ProgramDateTime: start},
+		&Segment{URI: "1.ts", Inf: &Inf{Duration: 5.5}},
+		&Segment{URI: "2.ts", Inf: &Inf{Duration: 6}, Discontinuity: true},
+		&Segment{URI: "3.ts", Inf: &Inf{Duration: 6}, Discontinuity: true, ProgramDateTime: start.Add(time.Hour)},
+		&Segment{URI: "4.ts", Inf: &Inf{Duration: 6}},
+	}
+
+	expected := []time.Time{
+		{},
+		start,
+		start.Add(6 * time.Second),
+		{},
+		start.Add(time.Hour),
+		start.Add(time.Hour + 6*time.Second),
+	}
+	dates := p.ProgramDateTimes()
+	for i, d := range dates {
+		if !d.Equal(expected[i]) {
+			t.Errorf("Expected segment %d to start at %v, but got %v", i, expected[i], d)
+		}
+	}
+
+	if d := p.Duration(); d != 33.5 {
+		t.Errorf("Expected Duration to be 33.5, but got %v", d)
+	}
+
+	times := []struct {
+		t   time.Time
+		uri string
+	}{
+		{start.Add(-time.Second), ""},
+		{start, "0.ts"},
+		{start.Add(11 * time.Second), "1.ts"},
+		{start.Add(12 * time.Second), ""},
+		{start.Add(time.Hour + 7*time.Second), "4.ts"},
+		{start.Add(time.Hour + 12*time.Second), ""},
+	}
+	for _, tt := range times {
+		s := p.SegmentAtTime(tt.t)
+		if (s == nil && tt.uri != "") || (s != nil && s.URI != tt.uri) {
+			t.Errorf("Expected segment %q at %v, but got %v", tt.uri, tt.t, s)
+		}
+	}
+
+	offsets := []struct {
+		offset float64
+		uri    string
+	}{
+		{0, "pre.ts"},
+		{4, "0.ts"},
+		{15.4, "1.ts"},
+		{15.5, "2.ts"},
+		{-1, "4.ts"},
+		{-33.5, "pre.ts"},
+		{33.5, ""},
+		{-34, ""},
+	}
+	for _, tt := range offsets {
+		s := p.SegmentAtOffset(tt.offset)
+		if (s == nil && tt.uri != "") || (s != nil && s.URI != tt.uri) {
+			t.Errorf("Expected segment %q at offset %v, but got %v", tt.uri, tt.offset, s)
+		}
+	}
+}
+
+func TestProgramDateTimesParsed(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	err := p.Parse(strings.NewReader(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXTINF:6.0,
+0.ts
+#EXTINF:6.0,
+1.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:6.0,
+2.ts
+#EXT-X-ENDLIST
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []time.Time{start, start.Add(6 * time.Second), {}}
+	dates := p.ProgramDateTimes()
+	for i, d := range dates {
+		if !d.Equal(expected[i]) {
+			t.Errorf("Expected segment %d to start at %v, but got %v", i, expected[i], d)
+		}
+	}
+}
@@ -156,16 +156,23 @@ func decodeDateRange(line string) (*DateRange, error) {
 			} else {
 				return nil, err
 			}
-		case strings.HasPrefix(k, "X-"):
-			dr.XClientAttribute = append(dr.XClientAttribute, fmt.Sprintf("%s=%s", k, v))
 		case strings.HasPrefix(k, "SCTE35"):
 			dr.SCTE35 = decodeSCTE(k, v)
 		case k == "END-ON-NEXT" && strings.EqualFold(v, boolYes):
 			dr.EndOnNext = true
 		}
 	}
+	if err != nil {
+		return dr, err
+	}
 
-	return dr, err
+	//client-defined attributes keep their order and the quotes telling their type
+	for _, a := range splitAttributes(line) {
+		if strings.HasPrefix(a.name, "X-") {
+			dr.ClientAttributes.decode(a.name, a.value)
+		}
+	}
+	return dr, nil
 }
 
 func decodeSCTE(att string, value string) *SCTE35 {
@@ -217,6 +224,19 @@ func decodeByterange(value string) (*Byterange, error) {
 	return b, nil
 }
 
+//ParseKey parses a #EXT-X-KEY or #EXT-X-SESSION-KEY tag, such as the key signalling
+//delivered by a DRM system outside of a playlist.
+func ParseKey(tag string) (*Key, error) {
+	tag = strings.TrimSpace(tag)
+	switch {
+	case strings.HasPrefix(tag, "#EXT-X-KEY:"):
+		return decodeKey(strings.TrimPrefix(tag, "#EXT-X-KEY:"), false), nil
+	case strings.HasPrefix(tag, "#EXT-X-SESSION-KEY:"):
+		return decodeKey(strings.TrimPrefix(tag, "#EXT-X-SESSION-KEY:"), true), nil
+	}
+	return nil, fmt.Errorf("%q isn't a #EXT-X-KEY or #EXT-X-SESSION-KEY tag", tag)
+}
+
 func decodeKey(line string, isSession bool) *Key {
 	keyMap := splitParams(line)
 
@@ -238,6 +258,41 @@ func decodeKey(line string, isSession bool) *Key {
 	return &key
 }
 
+//inheritKeys returns the keys of a segment preceded by the EXT-X-KEY tags keys, given the keys of the
+//previous segment. A key replaces the previous keys of the same KEYFORMAT, and METHOD=NONE
+//replaces all of them, without applying to the following segments.
+func inheritKeys(previous []*Key, keys []*Key) []*Key {
+	if len(keys) == 0 {
+		if len(previous) == 1 && strings.ToUpper(previous[0].Method) == none {
+			return nil
+		}
+		return previous
+	}
+
+	replaced := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if strings.ToUpper(k.Method) == none {
+			return keys
+		}
+		replaced[keyformat(k)] = true
+	}
+	var inherited []*Key
+	for _, k := range previous {
+		if !replaced[keyformat(k)] {
+			inherited = append(inherited, k)
+		}
+	}
+	return append(inherited, keys...)
+}
+
+//keyformat returns the KEYFORMAT of k, which defaults to identity.
+func keyformat(k *Key) string {
+	if k.Keyformat == "" {
+		return identity
+	}
+	return k.Keyformat
+}
+
 func decodeStartPoint(line string) (*StartPoint, error) {
 	spMap := splitParams(line)
 	var err error
@@ -257,14 +312,29 @@ func decodeStartPoint(line string) (*StartPoint, error) {
 	return sp, err
 }
 
-//splitParams receives the comma-separated list of attributes and maps attribute-value pairs
-func splitParams(line string) map[string]string {
+//attribute is an attribute-value pair of an attribute list, with the value as written.
+type attribute struct {
+	name  string
+	value string
+}
+
+//splitAttributes receives the comma-separated list of attributes and returns the attribute-value pairs in order,
+//with uppercase names and values quotes included
+func splitAttributes(line string) []attribute {
 	//regex to recognize att=val format and split on comma, unless comma is inside quotes
 	re := regexp.MustCompile(`([a-zA-Z\d_-]+)=("[^"]+"|[^",]+)`)
-	m := make(map[string]string)
+	var list []attribute
 	for _, kv := range re.FindAllStringSubmatch(line, -1) {
-		k, v := kv[1], kv[2]
-		m[strings.ToUpper(k)] = strings.Trim(v, "\"")
+		list = append(list, attribute{name: strings.ToUpper(kv[1]), value: kv[2]})
+	}
+	return list
+}
+
+//splitParams receives the comma-separated list of attributes and maps attribute-value pairs
+func splitParams(line string) map[string]string {
+	m := make(map[string]string)
+	for _, a := range splitAttributes(line) {
+		m[a.name] = strings.Trim(a.value, "\"")
 	}
 	return m
 }
@@ -2,9 +2,11 @@ package hls
 
 import (
 	"bufio"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -187,11 +189,12 @@ func TestReadMediaPlaylist(t *testing.T) {
 		Keys:      []*Key{&Key{Method: "sample-aes", URI: "keyuri"}, &Key{Method: "sample-aes", URI: "secondkeyuri"}},
 		Map:       &Map{URI: "mapuri"},
 		DateRange: &DateRange{ID: "TEST",
-			StartDate:        pt,
-			EndDate:          pt.Add(1 * time.Hour),
-			SCTE35:           &SCTE35{Type: "IN", Value: "bla"},
-			XClientAttribute: []string{"X-THIS-TAG=TEST", "X-THIS-OTHER-TAG=TESTING"}},
+			StartDate: pt,
+			EndDate:   pt.Add(1 * time.Hour),
+			SCTE35:    &SCTE35{Type: "IN", Value: "bla"}},
 	}
+	seg.DateRange.ClientAttributes.SetString("X-THIS-TAG", "TEST")
+	seg.DateRange.ClientAttributes.SetString("X-THIS-OTHER-TAG", "TESTING")
 
 	seg2 := &Segment{
 		URI: "segment2.com",
@@ -256,8 +259,84 @@ func TestReadMediaPlaylist(t *testing.T) {
 		if !s.Map.Equal(newP.Segments[i].Map) {
 			t.Errorf("Expected %d Segment Map to be %v, but got %v", i, s.Map, newP.Segments[i].Map)
 		}
+		if s.Discontinuity != newP.Segments[i].Discontinuity {
+			t.Errorf("Expected %d Segment Discontinuity to be %v, but got %v", i, s.Discontinuity, newP.Segments[i].Discontinuity)
+		}
 		// if s.DateRange != nil && !reflect.DeepEqual(s.DateRange, newP.Segments[i].DateRange) {
 		// 	t.Errorf("Expected %d Segment DateRange to be %v, but got %v", i, s.DateRange, newP.Segments[i].DateRange)
 		// }
 	}
 }
+
+func TestParseKey(t *testing.T) {
+	k, err := ParseKey(`#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI="skd://key",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Key{IsSession: true, Method: "SAMPLE-AES", URI: "skd://key", Keyformat: "com.apple.streamingkeydelivery", Keyformatversions: "1"}
+	if !reflect.DeepEqual(k, expected) {
+		t.Errorf("Expected key %v, but got %v", expected, k)
+	}
+
+	if _, err := ParseKey("#EXT-X-MAP:URI=\"init.mp4\""); err == nil {
+		t.Error("Expected error parsing #EXT-X-MAP tag")
+	}
+}
+
+func TestGapBitrate(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:8
+#EXT-X-TARGETDURATION:6
+#EXT-X-BITRATE:1200
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-GAP
+#EXTINF:6.0,
+seg1.ts
+#EXT-X-BITRATE:900
+#EXTINF:6.0,
+seg2.ts
+#EXTINF:6.0,
+seg3.ts
+#EXT-X-ENDLIST
+`
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(playlist)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gap     bool
+		bitrate int
+	}{
+		{false, 1200},
+		{true, 1200},
+		{false, 900},
+		{false, 900},
+	}
+	if len(p.Segments) != len(tests) {
+		t.Fatalf("Expected %d segments, but got %d", len(tests), len(p.Segments))
+	}
+	for i, tt := range tests {
+		if p.Segments[i].Gap != tt.gap || p.Segments[i].Bitrate != tt.bitrate {
+			t.Errorf("Expected segment %d to have gap %t and bitrate %d, but got %t and %d", i, tt.gap, tt.bitrate, p.Segments[i].Gap, p.Segments[i].Bitrate)
+		}
+	}
+
+	buf, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(buf)
+	if n := strings.Count(string(b), "#EXT-X-BITRATE:"); n != 2 {
+		t.Errorf("Expected EXT-X-BITRATE only where the bitrate changes, but got %d in\n%s", n, b)
+	}
+	if !strings.Contains(string(b), "#EXT-X-GAP\n#EXTINF:6.000,\nseg1.ts") {
+		t.Errorf("Expected EXT-X-GAP before the gap segment, but got\n%s", b)
+	}
+
+	p.Version = 7
+	if _, err := p.Encode(); err == nil {
+		t.Errorf("Expected error encoding EXT-X-GAP below version 8")
+	}
+}
@@ -126,7 +126,8 @@ func (p *MasterPlaylist) Parse(reader io.Reader) error {
 type mediaPlaylistParseState struct {
 	eof             bool
 	previousMap     *Map
-	previousKey     *Key
+	previousKeys    []*Key
+	previousBitrate int
 	segmentSequence int
 }
 
@@ -198,7 +199,6 @@ func (p *MediaPlaylist) Parse(reader io.Reader) error {
 		case line[0:index] == "#EXT-X-KEY":
 			key := decodeKey(line[index+1:size], false)
 			key.mediaPlaylist = p
-			s.previousKey = key // we store this key for future reference because every segment between EXT-X-KEYs should use this key for decryption
 			segment.Keys = append(segment.Keys, key)
 		case line[0:index] == "#EXT-X-MAP":
 			s.previousMap, buf.Err = decodeMap(line[index+1 : size])
@@ -207,7 +207,16 @@ func (p *MediaPlaylist) Parse(reader io.Reader) error {
 		case line[0:index] == "#EXT-X-PROGRAM-DATE-TIME":
 			segment.ProgramDateTime, buf.Err = decodeDateTime(line[index+1 : size])
 		case line[0:index] == "#EXT-X-DATERANGE":
-			segment.DateRange, buf.Err = decodeDateRange(line[index+1 : size])
+			var d *DateRange
+			if d, buf.Err = decodeDateRange(line[index+1 : size]); buf.Err == nil {
+				segment.addDateRange(d)
+			}
+		case line == "#EXT-X-DISCONTINUITY":
+			segment.Discontinuity = true
+		case line == "#EXT-X-GAP":
+			segment.Gap = true
+		case line[0:index] == "#EXT-X-BITRATE":
+			s.previousBitrate, buf.Err = strconv.Atoi(line[index+1 : size])
 		case line[0:index] == "#EXT-X-BYTERANGE":
 			segment.Byterange, buf.Err = decodeByterange(line[index+1 : size])
 		case line[0:index] == "#EXTINF":
@@ -216,16 +225,19 @@ func (p *MediaPlaylist) Parse(reader io.Reader) error {
 			segment.URI = line
 			segment.ID = s.segmentSequence
 
-			// a previous EXT-X-KEY applies to this segment
-			if len(segment.Keys) == 0 && s.previousKey != nil && s.previousKey.URI != "" {
-				segment.Keys = append(segment.Keys, s.previousKey)
-			}
+			// previous EXT-X-KEYs apply to this segment, unless replaced by a EXT-X-KEY of the same KEYFORMAT.
+			// we store the keys for future reference because every segment between EXT-X-KEYs should use them for decryption
+			segment.Keys = inheritKeys(s.previousKeys, segment.Keys)
+			s.previousKeys = segment.Keys
 
 			// a previous EXT-X-MAP applies to this segment
 			if segment.Map == nil && s.previousMap != nil {
 				segment.Map = s.previousMap
 			}
 
+			// a previous EXT-X-BITRATE applies to this segment
+			segment.Bitrate = s.previousBitrate
+
 			p.Segments = append(p.Segments, segment)
 
 			// Reset segment
@@ -249,8 +249,16 @@ func (s *Segment) writeSegmentTags(buf *manifest.BufWrapper, previousSegment *Se
 			buf.WriteString(fmt.Sprintf("#EXT-X-PROGRAM-DATE-TIME:%s\n", s.ProgramDateTime.Format(time.RFC3339Nano)))
 		}
 		buf.WriteValidString(s.Discontinuity, "#EXT-X-DISCONTINUITY\n")
+		buf.WriteValidString(s.Gap, "#EXT-X-GAP\n")
 
-		s.DateRange.writeDateRange(buf)
+		// EXT-X-BITRATE applies until the next one, so it's only written where the bitrate changes
+		if s.Bitrate > 0 && (previousSegment == nil || previousSegment.Bitrate != s.Bitrate) {
+			buf.WriteString(fmt.Sprintf("#EXT-X-BITRATE:%d\n", s.Bitrate))
+		}
+
+		for _, d := range s.dateRanges() {
+			d.writeDateRange(buf)
+		}
 		if buf.Err != nil {
 			return
 		}
@@ -303,9 +311,12 @@ func (k *Key) writeKey(buf *manifest.BufWrapper) {
 			buf.Err = attributeNotSetError("KEY", "METHOD")
 			return
 		}
-		if k.URI != "" && strings.ToUpper(k.Method) != none {
-			buf.WriteValidString(k.URI, fmt.Sprintf(",URI=\"%s\"", k.URI))
-		} else {
+		//other attributes MUST NOT be present if METHOD is NONE
+		if strings.ToUpper(k.Method) == none {
+			buf.WriteRune('\n')
+			return
+		}
+		if !buf.WriteValidString(k.URI, fmt.Sprintf(",URI=\"%s\"", k.URI)) {
 			buf.Err = attributeNotSetError("EXT-X-KEY", "URI")
 			return
 		}
@@ -318,7 +329,7 @@ func (k *Key) writeKey(buf *manifest.BufWrapper) {
 
 //isValidMethod checks Key Method value is supported. Session Key Method can't be NONE
 func isValidMethod(isSession bool, method string) bool {
-	return (method == aes || method == sample) || (!isSession && method == none)
+	return (method == aes || method == sample || method == sampleCTR) || (!isSession && method == none)
 }
 
 func (m *Map) writeMap(buf *manifest.BufWrapper) {
@@ -366,16 +377,12 @@ func (d *DateRange) writeDateRange(buf *manifest.BufWrapper) {
 		if d.PlannedDuration != nil && *d.PlannedDuration >= float64(0) {
 			buf.WriteString(fmt.Sprintf(",PLANNED-DURATION=%s", strconv.FormatFloat(*d.PlannedDuration, 'f', 3, 32)))
 		}
-		if len(d.XClientAttribute) > 0 {
-			for _, customTag := range d.XClientAttribute {
-				if !strings.HasPrefix(strings.ToUpper(customTag), "X-") {
-					buf.Err = errors.New("EXT-X-DATERANGE client-defined attributes must start with X-")
-					return
-				}
-				buf.WriteString(",")
-				buf.WriteString(strings.ToUpper(customTag))
-			}
+		attrs, err := d.ClientAttributes.encode()
+		if err != nil {
+			buf.Err = err
+			return
 		}
+		buf.WriteString(attrs)
 
 		d.SCTE35.writeSCTE(buf)
 
@@ -435,6 +442,10 @@ func (p *MediaPlaylist) checkCompatibility(s *Segment) error {
 			}
 		}
 
+		if s.Gap && p.Version < 8 {
+			return backwardsCompatibilityError(p.Version, "#EXT-X-GAP")
+		}
+
 		if s.Map != nil {
 			if p.Version < 5 || (!p.IFramesOnly && p.Version < 6) {
 				return backwardsCompatibilityError(p.Version, "#EXT-X-MAP")
@@ -184,11 +184,12 @@ func TestGenerateMediaPlaylist(t *testing.T) {
 		Keys:      []*Key{&Key{Method: "sample-aes", URI: "keyuri"}},
 		Map:       &Map{URI: "mapuri"},
 		DateRange: &DateRange{ID: "test",
-			StartDate:        time.Now(),
-			EndDate:          time.Now().Add(1 * time.Hour),
-			SCTE35:           &SCTE35{Type: "in", Value: "blablabla"},
-			XClientAttribute: []string{"X-THIS-TAG=TEST", "X-THIS-OTHER-TAG=TESTING"}},
+			StartDate: time.Now(),
+			EndDate:   time.Now().Add(1 * time.Hour),
+			SCTE35:    &SCTE35{Type: "in", Value: "blablabla"}},
 	}
+	seg.DateRange.ClientAttributes.SetString("X-THIS-TAG", "TEST")
+	seg.DateRange.ClientAttributes.SetString("X-THIS-OTHER-TAG", "TESTING")
 
 	p := NewMediaPlaylist(7)
 	p.Segments = append(p.Segments, seg)
@@ -341,6 +342,18 @@ func TestCompatibilityCheck(t *testing.T) {
 	if err.Error() != backwardsCompatibilityError(p.Version, "#EXT-X-BYTERANGE").Error() {
 		t.Errorf("Error should be %s, but got %s", backwardsCompatibilityError(p.Version, "#EXT-X-BYTERANGE").Error(), err.Error())
 	}
+
+	p = NewMediaPlaylist(7)
+	s = &Segment{Gap: true}
+	err = p.checkCompatibility(s)
+	if err == nil || err.Error() != backwardsCompatibilityError(p.Version, "#EXT-X-GAP").Error() {
+		t.Errorf("Error should be %s, but got %v", backwardsCompatibilityError(p.Version, "#EXT-X-GAP"), err)
+	}
+
+	p = NewMediaPlaylist(8)
+	if err = p.checkCompatibility(s); err != nil {
+		t.Errorf("Expected err to be nil, but got %s", err)
+	}
 }
 
 func TestSortSegments(t *testing.T) {
@@ -0,0 +1,300 @@
+package hls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+//InterstitialClass is the EXT-X-DATERANGE CLASS of HLS Interstitials.
+const InterstitialClass = "com.apple.hls.interstitial"
+
+//X-CUE, X-SNAP and X-RESTRICT values of an Interstitial.
+const (
+	CuePre          = "PRE"  //Play the interstitial before the primary asset.
+	CuePost         = "POST" //Play the interstitial after the primary asset.
+	CueOnce         = "ONCE" //Play the interstitial once, and skip it when seeking back over it.
+	SnapOut         = "OUT"  //Snap the start of the interstitial to the nearest segment boundary of the primary asset.
+	SnapIn          = "IN"   //Snap the resumption of the primary asset to the nearest segment boundary.
+	SkipRestriction = "SKIP" //Clients must not skip the interstitial.
+	JumpRestriction = "JUMP" //Clients must not seek past the interstitial without playing it.
+)
+
+//Interstitial is a typed view of an EXT-X-DATERANGE of CLASS com.apple.hls.interstitial, which schedules
+//an interstitial asset, such as an ad, to play at a date in the primary presentation.
+type Interstitial struct {
+	ID               string           //Required. ID of the EXT-X-DATERANGE.
+	StartDate        time.Time        //Required. When the interstitial is scheduled to play.
+	Duration         *float64         //Optional. Duration of the date range.
+	PlannedDuration  *float64         //Optional. Expected duration, if the duration isn't known yet.
+	AssetURI         string           //Represents X-ASSET-URI. URI of the interstitial asset playlist. Required unless AssetList is present.
+	AssetList        string           //Represents X-ASSET-LIST. URI of an AssetList JSON document. Required unless AssetURI is present.
+	ResumeOffset     *float64         //Optional. Represents X-RESUME-OFFSET. Offset from StartDate where the primary asset resumes, in seconds. Default: the duration of the interstitial.
+	PlayoutLimit     *float64         //Optional. Represents X-PLAYOUT-LIMIT. Limit of the playout time of the interstitial, in seconds.
+	Snap             []string         //Optional. Represents X-SNAP. Possible Values: OUT, IN.
+	Restrict         []string         //Optional. Represents X-RESTRICT. Possible Values: SKIP, JUMP.
+	Cue              []string         //Optional. Represents X-CUE. Possible Values: PRE, POST, ONCE. MUST NOT contain both PRE and POST.
+	ClientAttributes ClientAttributes //Optional. Other client-defined attributes of the EXT-X-DATERANGE.
+}
+
+//Interstitial returns the interstitial the DateRange schedules. It returns an error if the DateRange
+//isn't of CLASS com.apple.hls.interstitial or its attributes don't follow the rules of interstitials.
+func (d *DateRange) Interstitial() (*Interstitial, error) {
+	if d.Class != InterstitialClass {
+		return nil, fmt.Errorf("EXT-X-DATERANGE %s is not an interstitial, its CLASS is %q", d.ID, d.Class)
+	}
+
+	i := &Interstitial{
+		ID:              d.ID,
+		StartDate:       d.StartDate,
+		Duration:        d.Duration,
+		PlannedDuration: d.PlannedDuration,
+	}
+	attrs := &d.ClientAttributes
+	var err error
+	for _, name := range attrs.Names() {
+		switch name {
+		case "X-ASSET-URI":
+			i.AssetURI, err = stringAttribute(attrs, i.ID, name)
+		case "X-ASSET-LIST":
+			i.AssetList, err = stringAttribute(attrs, i.ID, name)
+		case "X-RESUME-OFFSET":
+			i.ResumeOffset, err = floatAttribute(attrs, i.ID, name)
+		case "X-PLAYOUT-LIMIT":
+			i.PlayoutLimit, err = floatAttribute(attrs, i.ID, name)
+		case "X-SNAP", "X-RESTRICT", "X-CUE":
+			var value string
+			if value, err = stringAttribute(attrs, i.ID, name); err != nil {
+				break
+			}
+			switch name {
+			case "X-SNAP":
+				i.Snap = splitEnumeratedList(value)
+			case "X-RESTRICT":
+				i.Restrict = splitEnumeratedList(value)
+			default:
+				i.Cue = splitEnumeratedList(value)
+			}
+		default:
+			v, _ := attrs.Get(name)
+			i.ClientAttributes.set(name, v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+//Validate checks the interstitial follows the rules of the HLS spec.
+func (i *Interstitial) Validate() error {
+	if i.ID == "" {
+		return attributeNotSetError("EXT-X-DATERANGE", "ID")
+	}
+	if i.StartDate.IsZero() {
+		return attributeNotSetError("EXT-X-DATERANGE", "START-DATE")
+	}
+	if (i.AssetURI == "") == (i.AssetList == "") {
+		return fmt.Errorf("interstitial %s must have exactly one of X-ASSET-URI and X-ASSET-LIST", i.ID)
+	}
+	if i.ResumeOffset != nil && *i.ResumeOffset < 0 {
+		return fmt.Errorf("interstitial %s X-RESUME-OFFSET must not be negative", i.ID)
+	}
+	if i.PlayoutLimit != nil && *i.PlayoutLimit <= 0 {
+		return fmt.Errorf("interstitial %s X-PLAYOUT-LIMIT must be positive", i.ID)
+	}
+	if err := validateEnumeratedList(i.ID, "X-SNAP", i.Snap, SnapOut, SnapIn); err != nil {
+		return err
+	}
+	if err := validateEnumeratedList(i.ID, "X-RESTRICT", i.Restrict, SkipRestriction, JumpRestriction); err != nil {
+		return err
+	}
+	if err := validateEnumeratedList(i.ID, "X-CUE", i.Cue, CuePre, CuePost, CueOnce); err != nil {
+		return err
+	}
+	if contains(i.Cue, CuePre) && contains(i.Cue, CuePost) {
+		return fmt.Errorf("interstitial %s X-CUE must not contain both PRE and POST", i.ID)
+	}
+	for _, name := range i.ClientAttributes.Names() {
+		if !strings.HasPrefix(name, "X-") {
+			return fmt.Errorf("EXT-X-DATERANGE client-defined attribute %s must start with X-", name)
+		}
+	}
+	return nil
+}
+
+//DateRange returns the EXT-X-DATERANGE scheduling the interstitial.
+func (i *Interstitial) DateRange() (*DateRange, error) {
+	if err := i.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := &DateRange{
+		ID:              i.ID,
+		Class:           InterstitialClass,
+		StartDate:       i.StartDate,
+		Duration:        i.Duration,
+		PlannedDuration: i.PlannedDuration,
+	}
+	attrs := &d.ClientAttributes
+	if i.AssetURI != "" {
+		attrs.SetString("X-ASSET-URI", i.AssetURI)
+	}
+	if i.AssetList != "" {
+		attrs.SetString("X-ASSET-LIST", i.AssetList)
+	}
+	if i.ResumeOffset != nil {
+		attrs.SetFloat("X-RESUME-OFFSET", *i.ResumeOffset)
+	}
+	if i.PlayoutLimit != nil {
+		attrs.SetFloat("X-PLAYOUT-LIMIT", *i.PlayoutLimit)
+	}
+	if len(i.Snap) > 0 {
+		attrs.SetString("X-SNAP", strings.Join(i.Snap, ","))
+	}
+	if len(i.Restrict) > 0 {
+		attrs.SetString("X-RESTRICT", strings.Join(i.Restrict, ","))
+	}
+	if len(i.Cue) > 0 {
+		attrs.SetString("X-CUE", strings.Join(i.Cue, ","))
+	}
+	for _, name := range i.ClientAttributes.Names() {
+		v, _ := i.ClientAttributes.Get(name)
+		attrs.set(name, v)
+	}
+	return d, nil
+}
+
+//InsertInterstitial schedules the interstitial in the playlist, adding its EXT-X-DATERANGE to the segment
+//playing at its StartDate. The playlist must have EXT-X-PROGRAM-DATE-TIME tags to map the date to a segment.
+func (p *MediaPlaylist) InsertInterstitial(i *Interstitial) error {
+	d, err := i.DateRange()
+	if err != nil {
+		return err
+	}
+
+	dates := p.ProgramDateTimes()
+	if !dated(dates) {
+		return errors.New("interstitials require EXT-X-PROGRAM-DATE-TIME to be inserted")
+	}
+	if n := p.indexAtTime(i.StartDate); n != -1 {
+		p.Segments[n].addDateRange(d)
+		return nil
+	}
+	return fmt.Errorf("interstitial %s START-DATE %s is outside the playlist", i.ID, i.StartDate.Format(time.RFC3339Nano))
+}
+
+//AssetList represents the JSON document referenced by the X-ASSET-LIST of an interstitial, listing the
+//assets played in sequence.
+type AssetList struct {
+	Assets []*Asset `json:"ASSETS"`
+}
+
+//Asset is an interstitial asset of an AssetList.
+type Asset struct {
+	URI      string  `json:"URI"`      //Required. URI of the asset playlist.
+	Duration float64 `json:"DURATION"` //Required. Duration of the asset, in seconds.
+}
+
+//ParseAssetList decodes an X-ASSET-LIST JSON document. It returns an error if an asset has no URI or a
+//negative duration.
+func ParseAssetList(reader io.Reader) (*AssetList, error) {
+	l := &AssetList{}
+	if err := json.NewDecoder(reader).Decode(l); err != nil {
+		return nil, err
+	}
+	if err := l.validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//Encode writes the AssetList JSON document.
+func (l *AssetList) Encode() (io.Reader, error) {
+	if err := l.validate(); err != nil {
+		return nil, err
+	}
+	if l.Assets == nil {
+		l = &AssetList{Assets: []*Asset{}}
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (l *AssetList) validate() error {
+	for n, a := range l.Assets {
+		if a == nil || a.URI == "" {
+			return fmt.Errorf("asset %d of ASSETS must have a URI", n)
+		}
+		if a.Duration < 0 {
+			return fmt.Errorf("asset %d of ASSETS must not have a negative DURATION", n)
+		}
+	}
+	return nil
+}
+
+//stringAttribute returns the quoted-string value of the attribute name, or an error if it has another type.
+func stringAttribute(attrs *ClientAttributes, id string, name string) (string, error) {
+	v, ok := attrs.Get(name)
+	if !ok {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("interstitial %s %s must be a quoted-string", id, name)
+	}
+	return s, nil
+}
+
+//floatAttribute returns the decimal-floating-point value of the attribute name, or an error if it has another type.
+func floatAttribute(attrs *ClientAttributes, id string, name string) (*float64, error) {
+	v, ok := attrs.Get(name)
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("interstitial %s %s must be a decimal-floating-point", id, name)
+	}
+	return &f, nil
+}
+
+//splitEnumeratedList splits a quoted string of comma-separated enumerated strings.
+func splitEnumeratedList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func validateEnumeratedList(id string, name string, list []string, values ...string) error {
+	for _, v := range list {
+		if !contains(values, v) {
+			return fmt.Errorf("interstitial %s %s value %s must be one of %s", id, name, v, strings.Join(values, ", "))
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package hls
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterstitial(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := NewMediaPlaylist(7)
+	p.TargetDuration = 6
+	for i := 0; i < 3; i++ {
+		p.Segments = append(p.Segments, &Segment{ID: i, URI: "seg.ts", Inf: &Inf{Duration: 6}})
+	}
+	p.Segments[0].ProgramDateTime = start
+
+	resume := 0.0
+	i := &Interstitial{
+		ID:           "ad1",
+		StartDate:    start.Add(8 * time.Second),
+		AssetURI:     "https://ads.example.com/Ad1/index.m3u8",
+		ResumeOffset: &resume,
+		Restrict:     []string{SkipRestriction, JumpRestriction},
+		Cue:          []string{CueOnce},
+	}
+	if err := p.InsertInterstitial(i); err != nil {
+		t.Fatal(err)
+	}
+	if p.Segments[1].DateRange == nil || p.Segments[1].DateRange.Class != InterstitialClass {
+		t.Fatalf("Expected interstitial on the segment playing at its START-DATE, but got %v", p.Segments[1].DateRange)
+	}
+	second := &Interstitial{ID: "ad2", StartDate: start.Add(10 * time.Second), AssetList: "https://ads.example.com/ads.json"}
+	if err := p.InsertInterstitial(second); err != nil || len(p.Segments[1].DateRanges) != 2 {
+		t.Errorf("Expected a second EXT-X-DATERANGE on the segment, but got %v", err)
+	}
+
+	buf, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(buf)
+	if !strings.Contains(string(b), `X-ASSET-URI="https://ads.example.com/Ad1/index.m3u8"`) || !strings.Contains(string(b), `X-RESTRICT="SKIP,JUMP"`) {
+		t.Errorf("Expected quoted interstitial attributes, but got\n%s", b)
+	}
+
+	parsed := NewMediaPlaylist(0)
+	if err := parsed.Parse(strings.NewReader(string(b))); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parsed.Segments[1].DateRange.Interstitial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != i.ID || !got.StartDate.Equal(i.StartDate) || got.AssetURI != i.AssetURI || *got.ResumeOffset != 0 ||
+		!reflect.DeepEqual(got.Restrict, i.Restrict) || !reflect.DeepEqual(got.Cue, i.Cue) {
+		t.Errorf("Expected interstitial %+v, but got %+v", i, got)
+	}
+	if len(parsed.Segments[1].DateRanges) != 2 || parsed.Segments[1].DateRanges[1].ID != "ad2" {
+		t.Errorf("Expected both EXT-X-DATERANGE tags of the segment, but got %v", parsed.Segments[1].DateRanges)
+	}
+}
+
+func TestInterstitialValidate(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	negative := -1.0
+	tests := []struct {
+		name string
+		i    *Interstitial
+	}{
+		{"no asset", &Interstitial{ID: "ad", StartDate: start}},
+		{"both assets", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", AssetList: "ads.json"}},
+		{"no ID", &Interstitial{StartDate: start, AssetURI: "ad.m3u8"}},
+		{"no START-DATE", &Interstitial{ID: "ad", AssetURI: "ad.m3u8"}},
+		{"negative resume", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", ResumeOffset: &negative}},
+		{"negative limit", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", PlayoutLimit: &negative}},
+		{"snap", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", Snap: []string{"LEFT"}}},
+		{"restrict", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", Restrict: []string{"PAUSE"}}},
+		{"pre and post", &Interstitial{ID: "ad", StartDate: start, AssetURI: "ad.m3u8", Cue: []string{CuePre, CuePost}}},
+	}
+	for _, tt := range tests {
+		if err := tt.i.Validate(); err == nil {
+			t.Errorf("Expected error for %s", tt.name)
+		}
+	}
+
+	if _, err := (&DateRange{ID: "ad", Class: "com.example.ad", StartDate: start}).Interstitial(); err == nil {
+		t.Errorf("Expected error for a DateRange of another CLASS")
+	}
+	d := &DateRange{ID: "ad", Class: InterstitialClass, StartDate: start}
+	d.ClientAttributes.SetString("X-ASSET-LIST", "ads.json")
+	d.ClientAttributes.SetString("X-PLAYOUT-LIMIT", "abc")
+	if _, err := d.Interstitial(); err == nil {
+		t.Errorf("Expected error for a quoted-string X-PLAYOUT-LIMIT")
+	}
+	d.ClientAttributes.SetFloat("X-PLAYOUT-LIMIT", 30)
+	d.ClientAttributes.SetFloat("X-ASSET-LIST", 1)
+	if _, err := d.Interstitial(); err == nil {
+		t.Errorf("Expected error for a decimal-floating-point X-ASSET-LIST")
+	}
+}
+
+func TestInsertInterstitialErrors(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	i := &Interstitial{ID: "ad", StartDate: start, AssetList: "ads.json"}
+
+	p := NewMediaPlaylist(7)
+	p.Segments = Segments{&Segment{URI: "seg.ts", Inf: &Inf{Duration: 6}}}
+	if err := p.InsertInterstitial(i); err == nil {
+		t.Errorf("Expected error inserting in a playlist without EXT-X-PROGRAM-DATE-TIME")
+	}
+
+	p.Segments[0].ProgramDateTime = start.Add(time.Minute)
+	if err := p.InsertInterstitial(i); err == nil {
+		t.Errorf("Expected error inserting before the playlist")
+	}
+
+	//segments before the first EXT-X-PROGRAM-DATE-TIME aren't dated
+	p.Segments = append(Segments{&Segment{URI: "seg.ts", Inf: &Inf{Duration: 60}}}, p.Segments...)
+	if err := p.InsertInterstitial(i); err == nil {
+		t.Errorf("Expected error inserting before the first EXT-X-PROGRAM-DATE-TIME")
+	}
+	i.StartDate = start.Add(time.Minute + time.Second)
+	if err := p.InsertInterstitial(i); err != nil || p.Segments[1].DateRange == nil {
+		t.Errorf("Expected interstitial on the dated segment, but got %v", err)
+	}
+}
+
+func TestAssetList(t *testing.T) {
+	doc := `{"ASSETS": [{"URI": "https://ads.example.com/Ad1/index.m3u8", "DURATION": 15.0}, {"URI": "https://ads.example.com/Ad2/index.m3u8", "DURATION": 30}]}`
+	l, err := ParseAssetList(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Assets) != 2 || l.Assets[1].URI != "https://ads.example.com/Ad2/index.m3u8" || l.Assets[1].Duration != 30 {
+		t.Errorf("Expected 2 assets, but got %+v", l.Assets)
+	}
+
+	r, err := l.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := ParseAssetList(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(encoded, l) {
+		t.Errorf("Expected %+v, but got %+v", l, encoded)
+	}
+
+	r, _ = (&AssetList{}).Encode()
+	b, _ := ioutil.ReadAll(r)
+	if string(b) != `{"ASSETS":[]}` {
+		t.Errorf("Expected empty ASSETS, but got %s", b)
+	}
+
+	if _, err := ParseAssetList(strings.NewReader(`{"ASSETS": [{"DURATION": 15}]}`)); err == nil {
+		t.Errorf("Expected error for an asset without URI")
+	}
+}
@@ -0,0 +1,213 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+//Recorder follows live Media Playlists through a Source, reloading them as the HLS spec recommends,
+//and accumulates their segments into VOD playlists. Segments are deduplicated by their Media
+//Sequence Number, so every segment listed by any reload is recorded once.
+//
+//Segments missed because the live window slid past them between reloads are reported with an
+//EXT-X-DISCONTINUITY on the first segment recorded after the gap.
+type Recorder struct {
+	Source Source
+	//Interval overrides the time between reloads of a playlist. Default: the TargetDuration of the playlist,
+	//or half of it after a reload without new segments.
+	Interval time.Duration
+	//Download, if not nil, receives the payload of each recorded segment, read from Source, as it's recorded.
+	//Gap segments aren't downloaded.
+	Download func(s *Segment, r io.Reader) error
+}
+
+//NewRecorder returns a Recorder reading playlists and segments from src.
+func NewRecorder(src Source) *Recorder {
+	return &Recorder{Source: src}
+}
+
+//recording is the state of a Media Playlist being recorded.
+type recording struct {
+	vod     *MediaPlaylist
+	next    int //Media Sequence Number of the next segment to record
+	started bool
+}
+
+//Record follows the Media Playlist of v until it ends with EXT-X-ENDLIST or ctx is done, which stops the
+//recording, and returns its segments as a VOD playlist with EXT-X-PLAYLIST-TYPE:VOD and EXT-X-ENDLIST.
+//If reloading the playlist or downloading a segment fails, the playlist recorded so far is returned with the error.
+func (r *Recorder) Record(ctx context.Context, v *Variant) (*MediaPlaylist, error) {
+	if r.Source == nil {
+		return nil, errors.New("Recorder has no Source")
+	}
+	rec := &recording{}
+	for {
+		p, err := r.Source.Media(ctx, v)
+		if err != nil {
+			if ctx.Err() != nil {
+				return rec.playlist(v), nil
+			}
+			return rec.playlist(v), err
+		}
+
+		added := rec.update(p)
+		if err := r.download(ctx, added); err != nil {
+			if ctx.Err() != nil {
+				return rec.playlist(v), nil
+			}
+			return rec.playlist(v), err
+		}
+		if p.EndList {
+			return rec.playlist(v), nil
+		}
+
+		t := time.NewTimer(r.reloadInterval(p, len(added) > 0))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return rec.playlist(v), nil
+		case <-t.C:
+		}
+	}
+}
+
+//RecordMaster records the Media Playlists of every variant of m at once, until they end or ctx is done.
+//The VOD playlists are returned in the order of m.Variants. If recording a variant fails, the others are
+//stopped and its error is returned with the playlists recorded so far.
+func (r *Recorder) RecordMaster(ctx context.Context, m *MasterPlaylist) ([]*MediaPlaylist, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	playlists := make([]*MediaPlaylist, len(m.Variants))
+	for i, v := range m.Variants {
+		wg.Add(1)
+		go func(i int, v *Variant) {
+			defer wg.Done()
+			p, err := r.Record(ctx, v)
+			playlists[i] = p
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("failed to record %s: %v", v.URI, err)
+					cancel()
+				})
+			}
+		}(i, v)
+	}
+	wg.Wait()
+	return playlists, firstErr
+}
+
+//reloadInterval returns the time to wait before reloading the playlist p.
+func (r *Recorder) reloadInterval(p *MediaPlaylist, changed bool) time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	d := time.Duration(p.TargetDuration) * time.Second
+	if d <= 0 {
+		d = time.Second
+	}
+	if !changed {
+		d /= 2
+	}
+	return d
+}
+
+//download passes the payloads of segments to Download. Gap segments are missing, so they're skipped.
+func (r *Recorder) download(ctx context.Context, segments []*Segment) error {
+	if r.Download == nil {
+		return nil
+	}
+	for _, s := range segments {
+		if s.Gap {
+			continue
+		}
+		uri, err := s.AbsoluteURL()
+		if err != nil {
+			return err
+		}
+		body, err := r.Source.Resource(ctx, uri)
+		if err != nil {
+			return err
+		}
+		err = r.Download(s, body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//update adds the segments of the reloaded playlist p that weren't recorded yet, and returns them.
+func (rec *recording) update(p *MediaPlaylist) []*Segment {
+	if rec.vod == nil {
+		rec.vod = NewMediaPlaylist(p.Version)
+		rec.vod.MediaSequence = p.MediaSequence
+		//every segment of the first load is recorded, so the discontinuities before it are the same
+		rec.vod.DiscontinuitySequence = p.DiscontinuitySequence
+		rec.vod.IndependentSegments = p.IndependentSegments
+	}
+	if p.Version > rec.vod.Version {
+		rec.vod.Version = p.Version
+	}
+	if p.TargetDuration > rec.vod.TargetDuration {
+		rec.vod.TargetDuration = p.TargetDuration
+	}
+
+	var added []*Segment
+	for _, s := range p.Segments {
+		if rec.started && s.ID < rec.next {
+			continue
+		}
+		//the live window slid past segments that were never listed by a reload
+		if rec.started && len(added) == 0 && s.ID > rec.next {
+			s.Discontinuity = true
+		}
+
+		if n := len(rec.vod.Segments); n > 0 {
+			shareTags(rec.vod.Segments[n-1], s)
+		}
+		rec.vod.Segments = append(rec.vod.Segments, s)
+		added = append(added, s)
+		rec.next = s.ID + 1
+		rec.started = true
+	}
+	return added
+}
+
+//shareTags replaces the keys and map of s with the ones of the previous segment where they're equal, IV included.
+//Reloads parse new Key and Map pointers, and Encode only writes the tags where the pointers change.
+func shareTags(previous *Segment, s *Segment) {
+	for i, k := range s.Keys {
+		for _, pk := range previous.Keys {
+			if pk.Equal(k) && pk.IV == k.IV {
+				s.Keys[i] = pk
+				break
+			}
+		}
+	}
+	if previous.Map.Equal(s.Map) {
+		s.Map = previous.Map
+	}
+}
+
+//playlist returns the VOD playlist of the segments recorded from the playlist of v.
+func (rec *recording) playlist(v *Variant) *MediaPlaylist {
+	p := rec.vod
+	if p == nil {
+		p = NewMediaPlaylist(0)
+	}
+	p.Variant = v
+	p.Type = "VOD"
+	p.EndList = true
+	return p
+}
@@ -0,0 +1,232 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//liveSource serves successive reloads of live media playlists, keyed by variant URI. The last reload
+//of a playlist is served again once they're all served, and an empty reload fails.
+type liveSource struct {
+	mu       sync.Mutex
+	reloads  map[string][]string
+	served   map[string]int
+	segments map[string][]byte
+}
+
+func (s *liveSource) Master(ctx context.Context, uri string) (*MasterPlaylist, error) {
+	return nil, fmt.Errorf("%s not found", uri)
+}
+
+func (s *liveSource) Media(ctx context.Context, variant *Variant) (*MediaPlaylist, error) {
+	s.mu.Lock()
+	reloads, ok := s.reloads[variant.URI]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%s not found", variant.URI)
+	}
+	n := s.served[variant.URI]
+	if n >= len(reloads) {
+		n = len(reloads) - 1
+	}
+	s.served[variant.URI]++
+	s.mu.Unlock()
+
+	if reloads[n] == "" {
+		return nil, fmt.Errorf("failed to reload %s", variant.URI)
+	}
+	p := NewMediaPlaylist(0).WithVariant(variant)
+	if err := p.Parse(strings.NewReader(reloads[n])); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *liveSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	b, ok := s.segments[uri]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", uri)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+//livePlaylist returns a live playlist of the segments first to last.
+func livePlaylist(first, last int, discontinuitySequence int, endList bool) string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+	if discontinuitySequence > 0 {
+		fmt.Fprintf(buf, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", discontinuitySequence)
+	}
+	buf.WriteString("#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin\"\n")
+	for i := first; i <= last; i++ {
+		fmt.Fprintf(buf, "#EXTINF:6.0,\nseg%d.ts\n", i)
+	}
+	if endList {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return buf.String()
+}
+
+func newLiveSource(reloads map[string][]string) *liveSource {
+	segments := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		segments[fmt.Sprintf("seg%d.ts", i)] = []byte(fmt.Sprintf("segment %d", i))
+	}
+	return &liveSource{reloads: reloads, served: make(map[string]int), segments: segments}
+}
+
+func TestRecord(t *testing.T) {
+	src := newLiveSource(map[string][]string{
+		"live.m3u8": {
+			livePlaylist(10, 12, 3, false),
+			livePlaylist(11, 13, 3, false),
+			livePlaylist(11, 13, 3, false),
+			livePlaylist(13, 15, 3, false),
+			//reloads missed segment 16
+			livePlaylist(17, 19, 3, true),
+		},
+	})
+
+	var downloaded []string
+	r := NewRecorder(src)
+	r.Interval = time.Millisecond
+	r.Download = func(s *Segment, body io.Reader) error {
+		b, err := ioutil.ReadAll(body)
+		downloaded = append(downloaded, string(b))
+		return err
+	}
+	p, err := r.Record(context.Background(), &Variant{URI: "live.m3u8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.EndList || p.Type != "VOD" {
+		t.Errorf("Expected VOD playlist with EXT-X-ENDLIST, but got type %s and EndList %t", p.Type, p.EndList)
+	}
+	if p.MediaSequence != 10 || p.DiscontinuitySequence != 3 {
+		t.Errorf("Expected MediaSequence 10 and DiscontinuitySequence 3, but got %d and %d", p.MediaSequence, p.DiscontinuitySequence)
+	}
+	expected := []int{10, 11, 12, 13, 14, 15, 17, 18, 19}
+	if len(p.Segments) != len(expected) {
+		t.Fatalf("Expected %d segments, but got %d", len(expected), len(p.Segments))
+	}
+	for i, s := range p.Segments {
+		if s.ID != expected[i] {
+			t.Errorf("Expected segment %d to have ID %d, but got %d", i, expected[i], s.ID)
+		}
+		if s.Discontinuity != (s.ID == 17) {
+			t.Errorf("Expected discontinuity only after the gap, but segment %d has %t", s.ID, s.Discontinuity)
+		}
+	}
+	if len(downloaded) != len(expected) || downloaded[6] != "segment 17" {
+		t.Errorf("Expected every recorded segment to be downloaded once, but got %v", downloaded)
+	}
+
+	buf, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(buf)
+	for tag, count := range map[string]int{"#EXT-X-PLAYLIST-TYPE:VOD": 1, "#EXT-X-ENDLIST": 1, "#EXT-X-KEY:": 1, "#EXT-X-DISCONTINUITY\n": 1} {
+		if n := strings.Count(string(b), tag); n != count {
+			t.Errorf("Expected %d %s, but got %d in\n%s", count, tag, n, b)
+		}
+	}
+}
+
+func TestRecordSourceDiscontinuity(t *testing.T) {
+	//segment 12 follows an EXT-X-DISCONTINUITY of the source, which slides out of the window before the end
+	discontinuity := func(p string) string {
+		return strings.Replace(p, "#EXTINF:6.0,\nseg12.ts", "#EXT-X-DISCONTINUITY\n#EXTINF:6.0,\nseg12.ts", 1)
+	}
+	src := newLiveSource(map[string][]string{
+		"live.m3u8": {
+			discontinuity(livePlaylist(10, 12, 0, false)),
+			discontinuity(livePlaylist(11, 13, 0, false)),
+			livePlaylist(13, 14, 1, true),
+		},
+	})
+	r := NewRecorder(src)
+	r.Interval = time.Millisecond
+	p, err := r.Record(context.Background(), &Variant{URI: "live.m3u8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Segments) != 5 || p.DiscontinuitySequence != 0 {
+		t.Fatalf("Expected 5 segments from DiscontinuitySequence 0, but got %d from %d", len(p.Segments), p.DiscontinuitySequence)
+	}
+	for _, s := range p.Segments {
+		if s.Discontinuity != (s.ID == 12) {
+			t.Errorf("Expected the discontinuity of the source only on segment 12, but segment %d has %t", s.ID, s.Discontinuity)
+		}
+	}
+
+	buf, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(buf)
+	if n := strings.Count(string(b), "#EXT-X-DISCONTINUITY\n"); n != 1 {
+		t.Errorf("Expected 1 EXT-X-DISCONTINUITY, but got %d in\n%s", n, b)
+	}
+}
+
+func TestRecordStop(t *testing.T) {
+	src := newLiveSource(map[string][]string{
+		"live.m3u8": {livePlaylist(0, 2, 0, false), livePlaylist(1, 3, 0, false)},
+	})
+	r := NewRecorder(src)
+	r.Interval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p, err := r.Record(ctx, &Variant{URI: "live.m3u8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.EndList || len(p.Segments) != 4 {
+		t.Errorf("Expected VOD playlist of 4 segments at stop, but got %d segments and EndList %t", len(p.Segments), p.EndList)
+	}
+
+	//a failed reload returns what was recorded
+	src.reloads["live.m3u8"] = append(src.reloads["live.m3u8"], "")
+	src.served["live.m3u8"] = 0
+	p, err = r.Record(context.Background(), &Variant{URI: "live.m3u8"})
+	if err == nil || len(p.Segments) != 4 {
+		t.Errorf("Expected error with 4 recorded segments, but got %v with %d segments", err, len(p.Segments))
+	}
+}
+
+func TestRecordMaster(t *testing.T) {
+	src := newLiveSource(map[string][]string{
+		"low.m3u8":  {livePlaylist(0, 2, 0, false), livePlaylist(1, 3, 0, true)},
+		"high.m3u8": {livePlaylist(0, 1, 0, false), livePlaylist(0, 2, 0, true)},
+	})
+	r := NewRecorder(src)
+	r.Interval = time.Millisecond
+
+	master := NewMasterPlaylist(3)
+	master.Variants = []*Variant{{URI: "low.m3u8", Bandwidth: 400000}, {URI: "high.m3u8", Bandwidth: 800000}}
+	playlists, err := r.RecordMaster(context.Background(), master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []int{4, 3} {
+		if len(playlists[i].Segments) != expected || playlists[i].Variant != master.Variants[i] {
+			t.Errorf("Expected %d segments for %s, but got %d", expected, master.Variants[i].URI, len(playlists[i].Segments))
+		}
+	}
+
+	master.Variants = append(master.Variants, &Variant{URI: "missing.m3u8"})
+	if _, err := r.RecordMaster(context.Background(), master); err == nil {
+		t.Errorf("Expected error for a missing variant")
+	}
+}
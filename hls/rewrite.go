@@ -0,0 +1,89 @@
+package hls
+
+import (
+	"strings"
+
+	"github.com/ingest/manifest"
+)
+
+//Kinds of the URIs passed to the manifest.Rewriter of Rewrite.
+const (
+	KindVariant     = "Variant"     //URI of a variant or I-frame playlist
+	KindRendition   = "Rendition"   //URI of an EXT-X-MEDIA rendition
+	KindSessionData = "SessionData" //URI of an EXT-X-SESSION-DATA
+	KindKey         = "Key"         //URI of an EXT-X-KEY or EXT-X-SESSION-KEY
+	KindSegment     = "Segment"     //URI of a media segment
+	KindMap         = "Map"         //URI of an EXT-X-MAP
+	KindAssetURI    = "AssetURI"    //X-ASSET-URI of an interstitial EXT-X-DATERANGE
+	KindAssetList   = "AssetList"   //X-ASSET-LIST of an interstitial EXT-X-DATERANGE
+)
+
+//Rewrite replaces every URI of the master playlist with the URI returned by r, such as to add CDN tokens
+//or move resources to another host. r is called with the kind KindVariant for variants and I-frame
+//playlists, KindRendition, KindSessionData and KindKey for session keys. Renditions and session data without
+//a URI, and keys of METHOD=NONE, are skipped.
+func (m *MasterPlaylist) Rewrite(r manifest.Rewriter) {
+	for _, v := range m.Variants {
+		rewriteURI(r, KindVariant, &v.URI)
+	}
+	for _, rendition := range m.Renditions {
+		rewriteURI(r, KindRendition, &rendition.URI)
+	}
+	for _, sd := range m.SessionData {
+		rewriteURI(r, KindSessionData, &sd.URI)
+	}
+	for _, k := range m.SessionKeys {
+		rewriteKey(r, k)
+	}
+}
+
+//Rewrite replaces every URI of the media playlist with the URI returned by r, such as to add CDN tokens
+//or move resources to another host. r is called with the kind KindSegment, KindKey, KindMap, and KindAssetURI
+//or KindAssetList for the X-ASSET-URI and X-ASSET-LIST of interstitials. Keys of METHOD=NONE are skipped,
+//and keys and maps shared by segments are rewritten once.
+func (p *MediaPlaylist) Rewrite(r manifest.Rewriter) {
+	keys := make(map[*Key]bool)
+	maps := make(map[*Map]bool)
+	dateRanges := make(map[*DateRange]bool)
+	for _, s := range p.Segments {
+		for _, k := range s.Keys {
+			if !keys[k] {
+				keys[k] = true
+				rewriteKey(r, k)
+			}
+		}
+		if s.Map != nil && !maps[s.Map] {
+			maps[s.Map] = true
+			rewriteURI(r, KindMap, &s.Map.URI)
+		}
+		for _, d := range s.dateRanges() {
+			if !dateRanges[d] {
+				dateRanges[d] = true
+				rewriteAttribute(r, KindAssetURI, &d.ClientAttributes, "X-ASSET-URI")
+				rewriteAttribute(r, KindAssetList, &d.ClientAttributes, "X-ASSET-LIST")
+			}
+		}
+		rewriteURI(r, KindSegment, &s.URI)
+	}
+}
+
+//rewriteURI sets uri to the URI returned by r, unless it's empty.
+func rewriteURI(r manifest.Rewriter, kind string, uri *string) {
+	if *uri != "" {
+		*uri = r(kind, *uri)
+	}
+}
+
+//rewriteKey sets the URI of k to the URI returned by r, unless k is of METHOD=NONE.
+func rewriteKey(r manifest.Rewriter, k *Key) {
+	if strings.ToUpper(k.Method) != none {
+		rewriteURI(r, KindKey, &k.URI)
+	}
+}
+
+//rewriteAttribute sets the string client attribute name to the URI returned by r, if present.
+func rewriteAttribute(r manifest.Rewriter, kind string, attrs *ClientAttributes, name string) {
+	if uri, ok := attrs.String(name); ok && uri != "" {
+		attrs.SetString(name, r(kind, uri))
+	}
+}
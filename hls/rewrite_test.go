@@ -0,0 +1,60 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ingest/manifest"
+)
+
+func TestMasterPlaylistRewrite(t *testing.T) {
+	m := NewMasterPlaylist(7)
+	m.Variants = []*Variant{{URI: "low.m3u8", Bandwidth: 800000}, {URI: "iframe.m3u8", Bandwidth: 100000, IsIframe: true}}
+	m.Renditions = []*Rendition{{Type: "AUDIO", GroupID: "aud", Name: "en", URI: "audio.m3u8"}, {Type: "CLOSED-CAPTIONS", GroupID: "cc", Name: "cc", InstreamID: "CC1"}}
+	m.SessionData = []*SessionData{{DataID: "com.example.title", URI: "title.json"}}
+	m.SessionKeys = []*Key{{Method: "SAMPLE-AES", URI: "skd://asset", Keyformat: "com.apple.streamingkeydelivery"}, {Method: "NONE", URI: "none.key"}}
+
+	var got []string
+	m.Rewrite(func(kind string, uri string) string {
+		got = append(got, kind+" "+uri)
+		return "/media/" + uri
+	})
+	expect := "Variant low.m3u8,Variant iframe.m3u8,Rendition audio.m3u8,SessionData title.json,Key skd://asset"
+	if strings.Join(got, ",") != expect {
+		t.Errorf("Expected URIs %s, but got %s", expect, strings.Join(got, ","))
+	}
+	if m.Variants[1].URI != "/media/iframe.m3u8" || m.Renditions[1].URI != "" || m.SessionKeys[1].URI != "none.key" {
+		t.Errorf("Expected URIs to be rewritten, and renditions without URI and keys of METHOD=NONE to be skipped")
+	}
+}
+
+func TestMediaPlaylistRewrite(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(clipPlaylist)); err != nil {
+		t.Fatal(err)
+	}
+	p.Segments[3].DateRange = &DateRange{ID: "break", Class: InterstitialClass}
+	p.Segments[3].DateRange.ClientAttributes.SetString("X-ASSET-URI", "ad.m3u8")
+
+	counts := make(map[string]int)
+	p.Rewrite(manifest.Chain(func(kind string, uri string) string {
+		counts[kind]++
+		return uri
+	}, manifest.Absolute("https://origin.example.com/vod/index.m3u8"), manifest.AkamaiToken("hdnts", []byte("secret"), "/vod/*", p.Segments[0].ProgramDateTime)))
+	expect := map[string]int{KindSegment: 5, KindKey: 1, KindMap: 1, KindAssetURI: 1}
+	for kind, n := range expect {
+		if counts[kind] != n {
+			t.Errorf("Expected %d %s URIs, but got %d", n, kind, counts[kind])
+		}
+	}
+
+	token := "?hdnts=exp=1577836800~acl=/vod/*~hmac="
+	for _, uri := range []string{p.Segments[4].URI, p.Segments[4].Keys[0].URI, p.Segments[4].Map.URI} {
+		if !strings.HasPrefix(uri, "https://origin.example.com/vod/") || strings.Count(uri, token) != 1 {
+			t.Errorf("Expected an absolute URI with a single token, but got %s", uri)
+		}
+	}
+	if uri, _ := p.Segments[3].DateRange.ClientAttributes.String("X-ASSET-URI"); !strings.HasPrefix(uri, "https://origin.example.com/vod/ad.m3u8"+token) {
+		t.Errorf("Expected the interstitial asset to be rewritten, but got %s", uri)
+	}
+}
@@ -0,0 +1,102 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//KeyProvider supplies the keys of each key period of a KeyRotation.
+type KeyProvider interface {
+	//Keys returns the keys of the segments of key period, one per KEYFORMAT. Periods are
+	//numbered from 0 in playlist order. Returning the key of a previous period for a
+	//KEYFORMAT keeps that key, and no keys leaves the segments of the period in the clear.
+	Keys(ctx context.Context, period int) ([]*Key, error)
+}
+
+//KeyRotation assigns keys from Provider to the segments of a Media Playlist, changing the
+//keys every Segments segments or every Interval of media, whichever comes first. If both
+//are zero the keys of the first period are never rotated.
+//
+//Segments are assigned keys once, in the order they're added to the playlist, so the keys
+//of the segments that remain stay correct when the live window evicts old segments. A
+//KeyRotation is meant for a single playlist.
+type KeyRotation struct {
+	Provider KeyProvider
+	Segments int           //Optional. Number of segments per key period.
+	Interval time.Duration //Optional. Media duration of each key period.
+
+	period   int
+	keys     []*Key
+	count    int
+	duration time.Duration
+	started  bool
+	last     *Segment //last segment assigned keys
+}
+
+//Period returns the current key period, the period of the last segment assigned keys.
+func (r *KeyRotation) Period() int {
+	return r.period
+}
+
+//RotateKeys assigns keys to the segments added to p since the last call, leaving the keys of
+//segments that already have some. Every segment of a key period shares the same Key pointers, so
+//Encode only writes EXT-X-KEY tags at the segments where the keys change.
+func (p *MediaPlaylist) RotateKeys(ctx context.Context, r *KeyRotation) error {
+	if r.Provider == nil {
+		return errors.New("KeyRotation has no KeyProvider")
+	}
+
+	//segments are only evicted from the start of the playlist, so if the last segment assigned
+	//keys is gone every segment is new
+	next := 0
+	for i, s := range p.Segments {
+		if s == r.last {
+			next = i + 1
+		}
+	}
+
+	for _, s := range p.Segments[next:] {
+		r.last = s
+		if len(s.Keys) > 0 {
+			continue
+		}
+
+		if !r.started || r.rotate() {
+			period := r.period + 1
+			if !r.started {
+				period = 0
+			}
+			keys, err := r.Provider.Keys(ctx, period)
+			if err != nil {
+				return fmt.Errorf("failed to get keys of key period %d: %v", period, err)
+			}
+			for _, k := range keys {
+				k.mediaPlaylist = p
+			}
+			//a clear period after an encrypted one must signal that segments aren't encrypted anymore
+			if len(keys) == 0 && len(r.keys) > 0 && strings.ToUpper(r.keys[0].Method) != none {
+				keys = []*Key{{Method: none, mediaPlaylist: p}}
+			}
+			r.keys = keys
+			r.period = period
+			r.count = 0
+			r.duration = 0
+			r.started = true
+		}
+
+		s.Keys = r.keys
+		r.count++
+		if s.Inf != nil {
+			r.duration += time.Duration(s.Inf.Duration * float64(time.Second))
+		}
+	}
+	return nil
+}
+
+//rotate returns true if the current key period is over.
+func (r *KeyRotation) rotate() bool {
+	return (r.Segments > 0 && r.count >= r.Segments) || (r.Interval > 0 && r.duration >= r.Interval)
+}
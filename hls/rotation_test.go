@@ -0,0 +1,151 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+//rotatingProvider rotates an AES-128 key every period, and keeps the same FairPlay key.
+type rotatingProvider struct {
+	fairPlay *Key
+	clear    map[int]bool
+	periods  []int
+}
+
+func (p *rotatingProvider) Keys(ctx context.Context, period int) ([]*Key, error) {
+	p.periods = append(p.periods, period)
+	if p.clear[period] {
+		return nil, nil
+	}
+	return []*Key{{Method: "AES-128", URI: fmt.Sprintf("key-%d.bin", period)}, p.fairPlay}, nil
+}
+
+func appendSegments(p *MediaPlaylist, n int) {
+	for i := 0; i < n; i++ {
+		id := p.MediaSequence + len(p.Segments)
+		p.Segments = append(p.Segments, &Segment{ID: id, URI: fmt.Sprintf("%d.ts", id), Inf: &Inf{Duration: 4}})
+	}
+}
+
+func encodeString(t *testing.T, p *MediaPlaylist) string {
+	r, err := p.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	return string(b)
+}
+
+func TestRotateKeys(t *testing.T) {
+	provider := &rotatingProvider{fairPlay: &Key{Method: "SAMPLE-AES", URI: "skd://asset", Keyformat: "com.apple.streamingkeydelivery", Keyformatversions: "1"}}
+	r := &KeyRotation{Provider: provider, Segments: 2}
+	p := NewMediaPlaylist(5)
+	p.TargetDuration = 4
+	appendSegments(p, 6)
+	if err := p.RotateKeys(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range p.Segments {
+		expected := fmt.Sprintf("key-%d.bin", i/2)
+		if len(s.Keys) != 2 || s.Keys[0].URI != expected || s.Keys[1] != provider.fairPlay {
+			t.Errorf("Expected segment %d keys %s and FairPlay, but got %v", i, expected, s.Keys)
+		}
+	}
+	if r.Period() != 2 {
+		t.Errorf("Expected key period 2, but got %d", r.Period())
+	}
+
+	out := encodeString(t, p)
+	if n := strings.Count(out, "#EXT-X-KEY:METHOD=AES-128"); n != 3 {
+		t.Errorf("Expected 3 AES-128 keys, but got %d:\n%s", n, out)
+	}
+	if n := strings.Count(out, "com.apple.streamingkeydelivery"); n != 1 {
+		t.Errorf("Expected FairPlay key once, but got %d:\n%s", n, out)
+	}
+
+	//the parsed playlist keeps the FairPlay key on every segment
+	parsed := NewMediaPlaylist(0)
+	if err := parsed.Parse(strings.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range parsed.Segments {
+		if len(s.Keys) != 2 || s.Keys[0].URI != "skd://asset" && s.Keys[1].URI != "skd://asset" {
+			t.Errorf("Expected segment %d to have AES-128 and FairPlay keys, but got %v", i, s.Keys)
+		}
+	}
+	if out2 := encodeString(t, parsed); out2 != out {
+		t.Errorf("Expected parsed playlist to encode the same, but got:\n%s", out2)
+	}
+
+	//slide the window: evict 3 segments, add 3
+	p.Segments = p.Segments[3:]
+	p.MediaSequence += 3
+	appendSegments(p, 3)
+	if err := p.RotateKeys(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	var uris []string
+	for _, s := range p.Segments {
+		uris = append(uris, s.Keys[0].URI)
+	}
+	if got := strings.Join(uris, ","); got != "key-1.bin,key-2.bin,key-2.bin,key-3.bin,key-3.bin,key-4.bin" {
+		t.Errorf("Expected keys to continue rotating, but got %s", got)
+	}
+	out = encodeString(t, p)
+	if !strings.HasPrefix(out[strings.Index(out, "#EXT-X-KEY"):], "#EXT-X-KEY:METHOD=AES-128,URI=\"key-1.bin\"\n#EXT-X-KEY:METHOD=SAMPLE-AES") {
+		t.Errorf("Expected first segment to carry its keys, but got:\n%s", out)
+	}
+}
+
+func TestRotateKeysInterval(t *testing.T) {
+	provider := &rotatingProvider{fairPlay: &Key{Method: "SAMPLE-AES", URI: "skd://asset", Keyformat: "com.apple.streamingkeydelivery", Keyformatversions: "1"},
+		clear: map[int]bool{1: true}}
+	r := &KeyRotation{Provider: provider, Interval: 10 * time.Second}
+	p := NewMediaPlaylist(5)
+	p.TargetDuration = 4
+	appendSegments(p, 4)
+	if err := p.RotateKeys(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	appendSegments(p, 5)
+	if err := p.RotateKeys(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(provider.periods) != "[0 1 2]" {
+		t.Errorf("Expected key periods [0 1 2], but got %v", provider.periods)
+	}
+	for i, s := range p.Segments {
+		var expected string
+		switch i / 3 {
+		case 0:
+			expected = "key-0.bin"
+		case 2:
+			expected = "key-2.bin"
+		}
+		var got string
+		if len(s.Keys) > 0 {
+			got = s.Keys[0].URI
+		}
+		if got != expected {
+			t.Errorf("Expected segment %d key %q, but got %q", i, expected, got)
+		}
+	}
+
+	out := encodeString(t, p)
+	if n := strings.Count(out, "#EXT-X-KEY:METHOD=NONE\n"); n != 1 {
+		t.Errorf("Expected METHOD=NONE for the clear period, but got %d:\n%s", n, out)
+	}
+	if n := strings.Count(out, "com.apple.streamingkeydelivery"); n != 2 {
+		t.Errorf("Expected FairPlay key after the clear period, but got %d:\n%s", n, out)
+	}
+
+	if err := p.RotateKeys(context.Background(), &KeyRotation{}); err == nil {
+		t.Error("Expected error for KeyRotation without KeyProvider")
+	}
+}
@@ -0,0 +1,167 @@
+package source
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ingest/manifest/hls"
+)
+
+// maxCacheable is the size of the largest resource the cache keeps, which leaves out segments.
+const maxCacheable = 1 << 20
+
+// Cache returns a source interface that keeps up to entries resources of src in memory, evicting the least recently
+// used. Responses are cached as their Cache-Control header allows: for max-age seconds, less their Age, and never
+// if no-store or no-cache. Resources without Cache-Control are cached if they're VOD playlists, which don't change,
+// but not live playlists or segments. Playlists are parsed from the resources of src.
+func Cache(src hls.Source, entries int) hls.Source {
+	return CacheKeys(src, entries, nil)
+}
+
+// CacheKeys returns a Cache that also keeps the resources without Cache-Control for which isKey returns true, such
+// as AES-128 keys, which don't change either. isKey is called with the URI of the resource, so key servers can be
+// matched by host or path; nil matches no resource.
+func CacheKeys(src hls.Source, entries int, isKey func(uri string) bool) hls.Source {
+	c := &cache{src: src, entries: entries, isKey: isKey, lru: list.New(), items: make(map[string]*list.Element)}
+	return &resourceSource{open: c.open}
+}
+
+type cache struct {
+	src     hls.Source
+	entries int
+	isKey   func(uri string) bool
+
+	mu    sync.Mutex
+	lru   *list.List // of *cacheEntry, most recently used first
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	uri     string
+	body    []byte
+	header  http.Header
+	expires time.Time // zero if the entry doesn't expire
+}
+
+func (c *cache) open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if e := c.get(uri); e != nil {
+		return &headerBody{ReadCloser: ioutil.NopCloser(bytes.NewReader(e.body)), header: e.header}, nil
+	}
+
+	body, err := c.src.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	h := header(body)
+	maxAge, cacheable := cacheControl(h)
+	if !cacheable {
+		return body, nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(body, maxCacheable+1))
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if len(b) > maxCacheable {
+		//too large to keep, hand back what was read with the rest of the body
+		return &headerBody{ReadCloser: struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), body), body}, header: h}, nil
+	}
+	body.Close()
+
+	e := &cacheEntry{uri: uri, body: b, header: h}
+	switch {
+	case maxAge > 0:
+		e.expires = time.Now().Add(maxAge)
+		c.add(e)
+	case maxAge < 0 && ((c.isKey != nil && c.isKey(uri)) || immutable(b)):
+		c.add(e)
+	}
+	return &headerBody{ReadCloser: ioutil.NopCloser(bytes.NewReader(b)), header: h}, nil
+}
+
+// get returns the entry of uri if it's cached and fresh.
+func (c *cache) get(uri string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[uri]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expires.IsZero() && !time.Now().Before(e.expires) {
+		c.lru.Remove(el)
+		delete(c.items, uri)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+func (c *cache) add(e *cacheEntry) {
+	if c.entries <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.uri]; ok {
+		c.lru.Remove(el)
+	}
+	c.items[e.uri] = c.lru.PushFront(e)
+	for c.lru.Len() > c.entries {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.items, el.Value.(*cacheEntry).uri)
+	}
+}
+
+// cacheControl returns how long a response with header h can be cached, or -1 if its Cache-Control doesn't say,
+// and false if it can't be cached.
+func cacheControl(h http.Header) (time.Duration, bool) {
+	cc := h.Get("Cache-Control")
+	if cc == "" {
+		return -1, true
+	}
+
+	maxAge := time.Duration(-1)
+	for _, directive := range strings.Split(cc, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return 0, false
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err != nil {
+				return 0, false
+			}
+			maxAge = time.Duration(seconds) * time.Second
+		}
+	}
+	if maxAge < 0 {
+		return -1, true
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil {
+		maxAge -= time.Duration(age) * time.Second
+	}
+	if maxAge <= 0 {
+		return 0, false
+	}
+	return maxAge, true
+}
+
+// immutable returns true if the resource is a VOD playlist, which doesn't change.
+func immutable(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("#EXTM3U")) &&
+		(bytes.Contains(b, []byte("#EXT-X-ENDLIST")) || bytes.Contains(b, []byte("#EXT-X-PLAYLIST-TYPE:VOD")))
+}
@@ -0,0 +1,137 @@
+package source_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ingest/manifest"
+	"github.com/ingest/manifest/hls/source"
+)
+
+const (
+	vodPlaylist  = "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-ENDLIST\n"
+	livePlaylist = "#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n"
+)
+
+func TestCache(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/max-age.m3u8":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			w.Write([]byte(livePlaylist))
+		case "/no-store.m3u8":
+			w.Header().Set("Cache-Control", "no-store")
+			w.Write([]byte(vodPlaylist))
+		case "/aged.m3u8":
+			w.Header().Set("Cache-Control", "max-age=5")
+			w.Header().Set("Age", "5")
+			w.Write([]byte(livePlaylist))
+		case "/vod.m3u8":
+			w.Write([]byte(vodPlaylist))
+		case "/live.m3u8":
+			w.Write([]byte(livePlaylist))
+		case "/key.bin", "/key.ts":
+			w.Write([]byte("0123456789abcdef"))
+		case "/seg0.ts":
+			w.Write([]byte("segment"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.CacheKeys(source.HTTP(nil), 10, func(uri string) bool {
+		return strings.HasSuffix(uri, "/key.bin")
+	})
+
+	tests := []struct {
+		path     string
+		requests int
+	}{
+		{"/max-age.m3u8", 1},
+		{"/no-store.m3u8", 2},
+		{"/aged.m3u8", 2},
+		{"/vod.m3u8", 1},
+		{"/live.m3u8", 2},
+		{"/key.bin", 1},
+		{"/key.ts", 2},
+		{"/seg0.ts", 2},
+	}
+	for _, tt := range tests {
+		var first []byte
+		for i := 0; i < 2; i++ {
+			body, err := src.Resource(ctx, server.URL+tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(body)
+			body.Close()
+			if i == 0 {
+				first = b
+			} else if string(b) != string(first) {
+				t.Errorf("Expected cached content %q of %s, but got %q", first, tt.path, b)
+			}
+		}
+		if requests[tt.path] != tt.requests {
+			t.Errorf("Expected %d requests for %s, but got %d", tt.requests, tt.path, requests[tt.path])
+		}
+	}
+
+	//playlists are parsed from the cache
+	if _, err := src.Master(ctx, server.URL+"/vod.m3u8"); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/vod.m3u8"] != 1 {
+		t.Errorf("Expected 1 request for /vod.m3u8, but got %d", requests["/vod.m3u8"])
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	requests := make(map[string]int)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Write([]byte("0123456789abcdef"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.CacheKeys(source.HTTP(nil), 1, func(uri string) bool {
+		return true
+	})
+	for _, path := range []string{"/key1.bin", "/key1.bin", "/key2.bin", "/key1.bin"} {
+		body, err := src.Resource(ctx, server.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+	}
+
+	if requests["/key1.bin"] != 2 {
+		t.Errorf("Expected key1.bin to be evicted and requested twice, but got %d requests", requests["/key1.bin"])
+	}
+	if requests["/key2.bin"] != 1 {
+		t.Errorf("Expected 1 request for key2.bin, but got %d", requests["/key2.bin"])
+	}
+}
+
+func TestCacheError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := source.Cache(source.HTTP(nil), 10).Resource(context.Background(), server.URL+"/key.bin")
+	if serr, ok := err.(*manifest.StatusError); !ok || serr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 StatusError, but got %v", err)
+	}
+}
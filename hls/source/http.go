@@ -6,13 +6,26 @@ import (
 	"net/http"
 
 	"github.com/ingest/manifest/hls"
+	"github.com/ingest/manifest/internal/source"
 )
 
+// headerBody is a response body that keeps the response headers, such as Cache-Control.
+type headerBody struct {
+	io.ReadCloser
+	header http.Header
+}
+
+// Header returns the headers of the response.
+func (b *headerBody) Header() http.Header {
+	return b.header
+}
+
 type httpSource struct {
 	Client *http.Client
 }
 
-// HTTP returns a source interface that fetches content using HTTP
+// HTTP returns a source interface that fetches content using HTTP.
+// Responses without a 2xx status code fail with a *manifest.StatusError.
 func HTTP(c *http.Client) hls.Source {
 	if c == nil {
 		c = http.DefaultClient
@@ -33,9 +46,7 @@ func (s *httpSource) Master(ctx context.Context, uri string) (*hls.MasterPlaylis
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res, err := s.Client.Do(req)
+	res, err := source.Do(ctx, s.Client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +65,7 @@ func (s *httpSource) Media(ctx context.Context, variant *hls.Variant) (*hls.Medi
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res, err := s.Client.Do(req)
+	res, err := source.Do(ctx, s.Client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -72,17 +81,16 @@ func (s *httpSource) Media(ctx context.Context, variant *hls.Variant) (*hls.Medi
 
 // Resource will download, and return the http.Response.Body for further parsing for whatever the structure might be.
 // Some examples of a resource might be the actual media segment, or session decryption key.
+// The body has a Header() http.Header method returning the response headers.
 func (s *httpSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-
-	res, err := s.Client.Do(req)
+	res, err := source.Do(ctx, s.Client, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return res.Body, nil
+	return &headerBody{ReadCloser: res.Body, header: res.Header}, nil
 }
@@ -2,8 +2,12 @@ package source_test
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"testing"
 
+	"github.com/ingest/manifest"
 	"github.com/ingest/manifest/hls/source"
 )
 
@@ -27,3 +31,59 @@ func ExampleHTTP() {
 	segment, _ := src.Resource(ctx, sURL)
 	segment.Close()
 }
+
+func TestHTTPStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/key.bin":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte("0123456789abcdef"))
+		case "/busy.m3u8":
+			http.Error(w, "busy", http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.HTTP(nil)
+
+	tests := []struct {
+		path      string
+		code      int
+		temporary bool
+	}{
+		{"/master.m3u8", http.StatusNotFound, false},
+		{"/busy.m3u8", http.StatusTooManyRequests, true},
+	}
+	for _, tt := range tests {
+		_, err := src.Master(ctx, server.URL+tt.path)
+		serr, ok := err.(*manifest.StatusError)
+		if !ok {
+			t.Fatalf("Expected StatusError for %s, but got %v", tt.path, err)
+		}
+		if serr.StatusCode != tt.code || serr.Temporary() != tt.temporary {
+			t.Errorf("Expected status %d (temporary %t), but got %d (temporary %t)", tt.code, tt.temporary, serr.StatusCode, serr.Temporary())
+		}
+		if serr.URI != server.URL+tt.path {
+			t.Errorf("Expected URI %s, but got %s", server.URL+tt.path, serr.URI)
+		}
+	}
+
+	body, err := src.Resource(ctx, server.URL+"/key.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	b, _ := ioutil.ReadAll(body)
+	if string(b) != "0123456789abcdef" {
+		t.Errorf("Expected key content, but got %q", b)
+	}
+	h, ok := body.(interface {
+		Header() http.Header
+	})
+	if !ok || h.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("Expected body with response headers")
+	}
+}
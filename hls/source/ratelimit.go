@@ -0,0 +1,182 @@
+package source
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ingest/manifest/hls"
+)
+
+// minPrune is the number of hosts RateLimit keeps limits for before pruning the idle ones.
+const minPrune = 64
+
+// RateLimit returns a source interface that limits the requests of src per host: requests to a host start at least
+// interval apart, and at most concurrency of them are open at once. A request stays open until its body is closed,
+// so segment downloads count against the cap. Zero disables either limit. Playlists are parsed from the resources of src.
+// The limits of hosts without open requests, whose interval has passed, are dropped as new hosts are requested,
+// so sources reading from many hosts don't grow without bound.
+func RateLimit(src hls.Source, interval time.Duration, concurrency int) hls.Source {
+	l := &rateLimiter{src: src, interval: interval, concurrency: concurrency, hosts: make(map[string]*hostLimit), pruneAt: minPrune}
+	return &resourceSource{open: l.open}
+}
+
+type rateLimiter struct {
+	src         hls.Source
+	interval    time.Duration
+	concurrency int
+
+	mu      sync.Mutex
+	hosts   map[string]*hostLimit
+	pruneAt int // number of hosts at which idle ones are pruned
+}
+
+func (l *rateLimiter) open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	var host string
+	if u, err := url.Parse(uri); err == nil {
+		host = u.Host
+	}
+	limit := l.acquire(host)
+
+	if err := limit.wait(ctx); err != nil {
+		l.done(limit)
+		return nil, err
+	}
+	body, err := l.src.Resource(ctx, uri)
+	if err != nil {
+		limit.release()
+		l.done(limit)
+		return nil, err
+	}
+	return &releaseBody{ReadCloser: body, release: func() {
+		limit.release()
+		l.done(limit)
+	}}, nil
+}
+
+// acquire returns the limits of host, counting a request in progress until done is called.
+func (l *rateLimiter) acquire(host string) *hostLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, ok := l.hosts[host]
+	if !ok {
+		if len(l.hosts) >= l.pruneAt {
+			l.prune()
+		}
+		limit = &hostLimit{interval: l.interval}
+		if l.concurrency > 0 {
+			limit.slots = make(chan struct{}, l.concurrency)
+		}
+		l.hosts[host] = limit
+	}
+	limit.requests++
+	return limit
+}
+
+// done ends a request counted by acquire.
+func (l *rateLimiter) done(limit *hostLimit) {
+	l.mu.Lock()
+	limit.requests--
+	l.mu.Unlock()
+}
+
+// prune drops the limits of hosts without requests in progress whose next request can start now, as a new
+// hostLimit would behave the same. It's called with mu held.
+func (l *rateLimiter) prune() {
+	now := time.Now()
+	for host, limit := range l.hosts {
+		limit.mu.Lock()
+		idle := limit.requests == 0 && !limit.next.After(now)
+		limit.mu.Unlock()
+		if idle {
+			delete(l.hosts, host)
+		}
+	}
+	l.pruneAt = 2 * len(l.hosts)
+	if l.pruneAt < minPrune {
+		l.pruneAt = minPrune
+	}
+}
+
+// hostLimit holds the request limits of a host.
+type hostLimit struct {
+	interval time.Duration
+	slots    chan struct{} // open requests, nil if unlimited
+	requests int           // requests in progress, guarded by the mutex of the rateLimiter
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+}
+
+// wait blocks until a request can start.
+func (l *hostLimit) wait(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			l.release()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// release frees the slot of a request.
+func (l *hostLimit) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// releaseBody releases the slot of its request when closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// Header returns the response headers of the body, or nil if it doesn't have any.
+func (b *releaseBody) Header() http.Header {
+	return header(b.ReadCloser)
+}
+
+// header returns the response headers of body, if it's from the HTTP source.
+func header(body io.ReadCloser) http.Header {
+	if h, ok := body.(interface {
+		Header() http.Header
+	}); ok {
+		return h.Header()
+	}
+	return nil
+}
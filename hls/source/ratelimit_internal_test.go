@@ -0,0 +1,37 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRateLimitPrune(t *testing.T) {
+	resources := make(map[string][]byte)
+	for i := 0; i < 1000; i++ {
+		resources[fmt.Sprintf("https://cdn%d.example.com/seg0.ts", i)] = []byte("segment")
+	}
+	l := &rateLimiter{src: Memory(resources), concurrency: 1, hosts: make(map[string]*hostLimit), pruneAt: minPrune}
+	src := &resourceSource{open: l.open}
+
+	ctx := context.Background()
+	held, err := src.Resource(ctx, "https://cdn0.example.com/seg0.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 1000; i++ {
+		body, err := src.Resource(ctx, fmt.Sprintf("https://cdn%d.example.com/seg0.ts", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+	}
+
+	if len(l.hosts) > minPrune {
+		t.Errorf("Expected at most %d host limits, but got %d", minPrune, len(l.hosts))
+	}
+	if _, ok := l.hosts["cdn0.example.com"]; !ok {
+		t.Error("Expected the limits of a host with an open request to be kept")
+	}
+	held.Close()
+}
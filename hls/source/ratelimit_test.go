@@ -0,0 +1,98 @@
+package source_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest/hls/source"
+)
+
+func TestRateLimitInterval(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		w.Write([]byte("segment"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	interval := 20 * time.Millisecond
+	src := source.RateLimit(source.HTTP(nil), interval, 0)
+	for i := 0; i < 3; i++ {
+		body, err := src.Resource(ctx, server.URL+"/seg0.ts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+	}
+
+	for i := 1; i < len(starts); i++ {
+		//allow for the timer firing a little early relative to the handler's clock
+		if d := starts[i].Sub(starts[i-1]); d < interval-5*time.Millisecond {
+			t.Errorf("Expected requests %s apart, but got %s", interval, d)
+		}
+	}
+}
+
+func TestRateLimitConcurrency(t *testing.T) {
+	var open, peak int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&open, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&open, -1)
+		w.Write([]byte("segment"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.RateLimit(source.HTTP(nil), 0, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := src.Resource(ctx, server.URL+"/seg0.ts")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body.Close()
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if p := atomic.LoadInt32(&peak); p != 2 {
+		t.Errorf("Expected at most 2 concurrent requests, but got %d", p)
+	}
+
+	//a request waiting for a slot gives up with its context
+	blocked := source.RateLimit(source.HTTP(nil), 0, 1)
+	held, err := blocked.Resource(ctx, server.URL+"/seg0.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := blocked.Resource(timeout, server.URL+"/seg1.ts"); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package source
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ingest/manifest"
+	"github.com/ingest/manifest/hls"
+)
+
+// Retry returns a source interface that retries requests of src failing with a temporary error: a 5xx or 429
+// manifest.StatusError, or a network timeout. It makes up to attempts requests, waiting backoff before the first retry and
+// doubling the wait for each following one. Playlists are parsed from the resources of src.
+func Retry(src hls.Source, attempts int, backoff time.Duration) hls.Source {
+	return &resourceSource{
+		open: func(ctx context.Context, uri string) (io.ReadCloser, error) {
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				body, err := src.Resource(ctx, uri)
+				if err == nil || attempt >= attempts || ctx.Err() != nil || !temporary(err) {
+					return body, err
+				}
+
+				t := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return nil, ctx.Err()
+				case <-t.C:
+				}
+				wait *= 2
+			}
+		},
+	}
+}
+
+// temporary returns true if a request failing with err is worth retrying.
+func temporary(err error) bool {
+	switch err := err.(type) {
+	case *manifest.StatusError:
+		return err.Temporary()
+	case net.Error:
+		return err.Timeout()
+	}
+	return false
+}
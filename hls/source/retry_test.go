@@ -0,0 +1,80 @@
+package source_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ingest/manifest"
+	"github.com/ingest/manifest/hls/source"
+)
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		switch {
+		case r.URL.Path == "/missing.ts":
+			http.NotFound(w, r)
+		case n <= 2:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("segment"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	src := source.Retry(source.HTTP(nil), 3, time.Millisecond)
+
+	body, err := src.Resource(ctx, server.URL+"/seg0.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(b) != "segment" {
+		t.Errorf("Expected content %q, but got %q", "segment", b)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("Expected 3 requests, but got %d", n)
+	}
+
+	//not found isn't retried
+	atomic.StoreInt32(&requests, 10)
+	_, err = src.Resource(ctx, server.URL+"/missing.ts")
+	if serr, ok := err.(*manifest.StatusError); !ok || serr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 StatusError, but got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 11 {
+		t.Errorf("Expected 1 request for a 404, but got %d", n-10)
+	}
+
+	//gives up after attempts
+	atomic.StoreInt32(&requests, 0)
+	_, err = source.Retry(source.HTTP(nil), 2, time.Millisecond).Resource(ctx, server.URL+"/seg0.ts")
+	if serr, ok := err.(*manifest.StatusError); !ok || !serr.Temporary() {
+		t.Errorf("Expected temporary StatusError, but got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected 2 requests, but got %d", n)
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := source.Retry(source.HTTP(nil), 5, time.Hour).Resource(ctx, server.URL+"/seg0.ts")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package source
+
+import (
+	"context"
+	"io"
+
+	"github.com/ingest/manifest/hls"
+	"github.com/ingest/manifest/internal/source"
+)
+
+// resourceSource is a Source reading playlists and resources with open, resolving URIs the same way
+// the HTTP source does. Sources backed by storage, and decorators of other sources, parse playlists
+// from resources with it.
+type resourceSource struct {
+	open source.Open
+}
+
+// Dir returns a source interface that reads content from the directory root, such as the output of a packager.
+// URIs are resolved as paths relative to root. The path of URLs with a scheme and host is used, so playlists
+// listing absolute URLs can be read from a copy of their origin.
+func Dir(root string) hls.Source {
+	return &resourceSource{open: source.Dir(root)}
+}
+
+// Memory returns a source interface that serves content from resources, keyed by URI.
+// Relative URIs resolve against the master playlist URI as in the HTTP source, so resources should be keyed by
+// the URI the master playlist was read with and the resolved URIs of its playlists and segments.
+// Missing resources return an error satisfying os.IsNotExist.
+func Memory(resources map[string][]byte) hls.Source {
+	return &resourceSource{open: source.Memory(resources)}
+}
+
+// Master will read, and attempt to parse the document at the URI into a HLS master playlist.
+func (s *resourceSource) Master(ctx context.Context, uri string) (*hls.MasterPlaylist, error) {
+	master := hls.NewMasterPlaylist(0)
+	master.URI = uri
+
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if err := master.Parse(body); err != nil {
+		return nil, err
+	}
+	return master, nil
+}
+
+// Media will read, and attempt to parse the HLS media playlist from the given variant that was parsed from a master playlist.
+func (s *resourceSource) Media(ctx context.Context, variant *hls.Variant) (*hls.MediaPlaylist, error) {
+	uri, err := variant.AbsoluteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	media := hls.NewMediaPlaylist(0).WithVariant(variant)
+	if err := media.Parse(body); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// Resource will return the content at the URI, for further parsing for whatever the structure might be.
+func (s *resourceSource) Resource(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.open(ctx, uri)
+}
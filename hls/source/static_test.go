@@ -0,0 +1,151 @@
+package source_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ingest/manifest/hls"
+	"github.com/ingest/manifest/hls/source"
+)
+
+var files = map[string][]byte{
+	"hls/master.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+v1/prog_index.m3u8
+`),
+	"hls/v1/prog_index.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-KEY:METHOD=AES-128,URI="../key.bin"
+#EXTINF:6.0,
+seg0.ts
+#EXT-X-ENDLIST
+`),
+	"hls/v1/seg0.ts": []byte("segment"),
+	"hls/key.bin":    []byte("0123456789abcdef"),
+}
+
+func testSource(t *testing.T, src hls.Source, masterURI string, base string) {
+	ctx := context.Background()
+	master, err := src.Master(ctx, masterURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(master.Variants) != 1 {
+		t.Fatalf("Expected 1 variant, but got %d", len(master.Variants))
+	}
+
+	media, err := src.Media(ctx, master.Variants[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(media.Segments) != 1 || len(media.Segments[0].Keys) != 1 {
+		t.Fatalf("Expected 1 encrypted segment, but got %v", media.Segments)
+	}
+
+	tests := []struct {
+		uri      func() (string, error)
+		expected string
+		content  string
+	}{
+		{master.Variants[0].AbsoluteURL, base + "v1/prog_index.m3u8", ""},
+		{media.Segments[0].AbsoluteURL, base + "v1/seg0.ts", "segment"},
+		{media.Segments[0].Keys[0].AbsoluteURL, base + "key.bin", "0123456789abcdef"},
+	}
+	for _, tt := range tests {
+		uri, err := tt.uri()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri != tt.expected {
+			t.Errorf("Expected URI %s, but got %s", tt.expected, uri)
+		}
+		if tt.content == "" {
+			continue
+		}
+		body, err := src.Resource(ctx, uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(body)
+		body.Close()
+		if string(b) != tt.content {
+			t.Errorf("Expected content %q of %s, but got %q", tt.content, uri, b)
+		}
+	}
+
+	if _, err := src.Resource(ctx, base+"missing.ts"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, but got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := src.Master(cancelled, masterURI); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "hls-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for name, b := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := source.Dir(root)
+	testSource(t, src, "hls/master.m3u8", "hls/")
+	testSource(t, src, "https://cdn.example.com/hls/master.m3u8", "https://cdn.example.com/hls/")
+
+	//paths can't escape root
+	if _, err := src.Resource(context.Background(), "../../hls/key.bin"); err != nil {
+		t.Errorf("Expected ../../hls/key.bin to resolve in root, but got %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testSource(t, source.Memory(files), "hls/master.m3u8", "hls/")
+}
+
+// Memory implements the source interface for content kept in memory, such as fixtures of tests.
+func ExampleMemory() {
+	ctx := context.Background()
+	src := source.Memory(map[string][]byte{
+		"master.m3u8":   []byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nv1/index.m3u8\n"),
+		"v1/index.m3u8": []byte("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-ENDLIST\n"),
+		"v1/seg0.ts":    []byte("segment"),
+	})
+
+	master, _ := src.Master(ctx, "master.m3u8")
+	media, _ := src.Media(ctx, master.Variants[0])
+
+	sURL, _ := media.Segments[0].AbsoluteURL()
+	segment, _ := src.Resource(ctx, sURL)
+	segment.Close()
+}
+
+// Dir implements the source interface for content on disk, such as the output of a packager.
+func ExampleDir() {
+	ctx := context.Background()
+	src := source.Dir("/var/www/hls")
+
+	// Reads /var/www/hls/event/master.m3u8 and the media playlist of its first variant
+	master, _ := src.Master(ctx, "event/master.m3u8")
+	media, _ := src.Media(ctx, master.Variants[0])
+
+	sURL, _ := media.Segments[0].AbsoluteURL()
+	segment, _ := src.Resource(ctx, sURL)
+	segment.Close()
+}
@@ -0,0 +1,331 @@
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//spliceTolerance is how far from a segment boundary, in seconds, a splice offset can be and still splice at it,
+//as the sum of EXTINF durations is rarely exact.
+const spliceTolerance = 0.001
+
+//Splice inserts the segments of ad into p before the segment at index, or at the end of p if index is the
+//number of segments, such as to stitch an ad into content. EXT-X-DISCONTINUITY is added where the ad starts
+//and where the content resumes, and the keys and map of each side are listed again after the break, with
+//METHOD=NONE where segments encrypted with keys are followed by clear ones.
+//
+//The ad segments are copied with their URIs resolved against ad, so ad is unchanged and can be spliced again.
+//The segments of p are renumbered from its MediaSequence, and the segment resuming the content keeps its
+//EXT-X-PROGRAM-DATE-TIME and gets an explicit EXT-X-BYTERANGE offset, as do the first ad segments of each
+//resource. DiscontinuitySequence is unchanged, as the added discontinuities are listed in p.
+//AES-128 keys without IV of renumbered segments get the IV of their sequence number before the splice,
+//so they still decrypt.
+func (p *MediaPlaylist) Splice(index int, ad *MediaPlaylist) error {
+	if index < 0 || index > len(p.Segments) {
+		return fmt.Errorf("splice index %d is outside the playlist", index)
+	}
+	if ad == nil || len(ad.Segments) == 0 {
+		return errors.New("spliced playlist must have at least one Segment")
+	}
+	segments, err := p.copySegments(ad)
+	if err != nil {
+		return err
+	}
+	explicitOffsets(segments, byteOffsets(ad.Segments))
+
+	dates := p.ProgramDateTimes()
+	if index > 0 {
+		if err := spliceBoundary(p.Segments[index-1], segments[0]); err != nil {
+			return err
+		}
+	}
+	segments[0].Discontinuity = true
+	if index < len(p.Segments) {
+		resume := p.Segments[index]
+		if err := spliceBoundary(segments[len(segments)-1], resume); err != nil {
+			return err
+		}
+		resume.Discontinuity = true
+		explicitOffsets(p.Segments[index:index+1], byteOffsets(p.Segments)[index:index+1])
+		if resume.ProgramDateTime.IsZero() {
+			resume.ProgramDateTime = dates[index]
+		}
+	}
+
+	spliced := make(Segments, 0, len(p.Segments)+len(segments))
+	spliced = append(spliced, p.Segments[:index]...)
+	spliced = append(spliced, segments...)
+	p.Segments = append(spliced, p.Segments[index:]...)
+	for i, s := range p.Segments {
+		if id := p.MediaSequence + i; s.ID != id {
+			keepIV(s)
+			s.ID = id
+		}
+	}
+
+	if ad.Version > p.Version {
+		p.Version = ad.Version
+	}
+	if ad.TargetDuration > p.TargetDuration {
+		p.TargetDuration = ad.TargetDuration
+	}
+	return nil
+}
+
+//SpliceAt inserts the segments of ad into p at the first segment boundary at or after the wall-clock time t,
+//with Splice. It returns an error if no dated segment of p plays at or after t.
+func (p *MediaPlaylist) SpliceAt(t time.Time, ad *MediaPlaylist) error {
+	index := -1
+	for i, start := range p.ProgramDateTimes() {
+		if start.IsZero() {
+			continue
+		}
+		end := start.Add(time.Duration(p.Segments[i].duration() * float64(time.Second)))
+		if !start.Before(t) {
+			index = i
+			break
+		}
+		if t.Before(end) || (t.Equal(end) && i == len(p.Segments)-1) {
+			index = i + 1
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("splice time %s is outside the playlist", t.Format(time.RFC3339Nano))
+	}
+	return p.Splice(index, ad)
+}
+
+//boundaryAtOffset returns the index of the first segment starting at or after offset seconds, the number
+//of segments if offset is the end of the playlist, or -1 if it's after the end.
+func (p *MediaPlaylist) boundaryAtOffset(offset float64) int {
+	var start float64
+	for i, s := range p.Segments {
+		if offset <= start+spliceTolerance {
+			return i
+		}
+		start += s.duration()
+	}
+	if offset <= start+spliceTolerance {
+		return len(p.Segments)
+	}
+	return -1
+}
+
+//copySegments copies the segments of ad to be listed in p, with their keys and maps. URIs are resolved
+//against ad, and keys and maps shared by segments are still shared by the copies.
+func (p *MediaPlaylist) copySegments(ad *MediaPlaylist) (Segments, error) {
+	base, err := ad.absoluteURL()
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[*Key]*Key)
+	maps := make(map[*Map]*Map)
+
+	segments := make(Segments, len(ad.Segments))
+	for i, s := range ad.Segments {
+		c := *s
+		c.mediaPlaylist = p
+		if c.URI, err = resolveURLReference(base, s.URI); err != nil {
+			return nil, err
+		}
+
+		c.Keys = nil
+		for _, k := range s.Keys {
+			if _, ok := keys[k]; !ok {
+				kc := *k
+				kc.mediaPlaylist = p
+				if kc.URI != "" {
+					if kc.URI, err = resolveURLReference(base, k.URI); err != nil {
+						return nil, err
+					}
+				}
+				keys[k] = &kc
+			}
+			c.Keys = append(c.Keys, keys[k])
+		}
+
+		if s.Map != nil {
+			if _, ok := maps[s.Map]; !ok {
+				mc := *s.Map
+				mc.mediaPlaylist = p
+				if mc.URI, err = resolveURLReference(base, s.Map.URI); err != nil {
+					return nil, err
+				}
+				maps[s.Map] = &mc
+			}
+			c.Map = maps[s.Map]
+		}
+		segments[i] = &c
+	}
+	return segments, nil
+}
+
+//keepIV sets the IV of the AES-128 keys without IV of s to its sequence number, which is the IV they
+//imply, before s is renumbered. Each segment gets its own copy of the keys, as their IVs differ.
+func keepIV(s *Segment) {
+	keys := make([]*Key, len(s.Keys))
+	for i, k := range s.Keys {
+		keys[i] = k
+		if strings.ToUpper(k.Method) == aes && k.IV == "" {
+			kc := *k
+			kc.IV = fmt.Sprintf("0x%032X", s.ID)
+			keys[i] = &kc
+		}
+	}
+	s.Keys = keys
+}
+
+//spliceBoundary makes the keys and map of next apply after the segment prev from another playlist.
+//Encode lists them again as their pointers differ, but keys and maps of prev that next doesn't replace
+//would still apply to it.
+func spliceBoundary(prev *Segment, next *Segment) error {
+	if next.Map == nil && prev.Map != nil {
+		return errors.New("segments without EXT-X-MAP can't be spliced after segments with EXT-X-MAP")
+	}
+
+	encrypted := false
+	for _, k := range prev.Keys {
+		if strings.ToUpper(k.Method) != none {
+			encrypted = true
+		}
+	}
+	switch {
+	case len(next.Keys) == 0 && encrypted:
+		next.Keys = []*Key{{Method: none}}
+	case encrypted:
+		for _, k := range prev.Keys {
+			replaced := false
+			for _, nk := range next.Keys {
+				if strings.ToUpper(nk.Method) == none || keyformat(nk) == keyformat(k) {
+					replaced = true
+				}
+			}
+			if !replaced {
+				return fmt.Errorf("EXT-X-KEY of KEYFORMAT %s would still apply after the splice", keyformat(k))
+			}
+		}
+	}
+
+	return nil
+}
+
+//Splice inserts the ad master playlist into m at offset seconds, such as to stitch an ad into content.
+//The Media Playlist of every variant, I-frame playlist and rendition of m is read from src, and spliced
+//with the matching one of ad at its first segment boundary at or after offset, with MediaPlaylist.Splice.
+//
+//Variants are matched to the ad variant of the same kind, with the same codecs, and the closest bandwidth.
+//Renditions are matched to the ad rendition of the same type and language, or of the same type.
+//The spliced playlists are returned by their URI as listed in m.
+func (m *MasterPlaylist) Splice(ctx context.Context, src Source, offset float64, ad *MasterPlaylist) (map[string]*MediaPlaylist, error) {
+	ads := make(map[string]*MediaPlaylist)
+	spliced := make(map[string]*MediaPlaylist)
+	splice := func(uri string, p *MediaPlaylist, adURI string, load func() (*MediaPlaylist, error)) error {
+		a, ok := ads[adURI]
+		if !ok {
+			var err error
+			if a, err = load(); err != nil {
+				return err
+			}
+			ads[adURI] = a
+		}
+		index := p.boundaryAtOffset(offset)
+		if index == -1 {
+			return fmt.Errorf("splice offset %v is outside %s", offset, uri)
+		}
+		if err := p.Splice(index, a); err != nil {
+			return fmt.Errorf("failed to splice %s: %v", uri, err)
+		}
+		spliced[uri] = p
+		return nil
+	}
+
+	for _, v := range m.Variants {
+		if _, ok := spliced[v.URI]; ok {
+			continue
+		}
+		av := matchVariant(v, ad.Variants)
+		if av == nil {
+			return nil, fmt.Errorf("no ad variant matches the codecs of %s", v.URI)
+		}
+		p, err := src.Media(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		if err := splice(v.URI, p, "variant:"+av.URI, func() (*MediaPlaylist, error) { return src.Media(ctx, av) }); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range m.Renditions {
+		if _, ok := spliced[r.URI]; ok || r.URI == "" {
+			continue
+		}
+		ar := matchRendition(r, ad.Renditions)
+		if ar == nil {
+			return nil, fmt.Errorf("no ad rendition matches %s rendition %s", r.Type, r.URI)
+		}
+		p, err := r.MediaPlaylist(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		if err := splice(r.URI, p, "rendition:"+ar.URI, func() (*MediaPlaylist, error) { return ar.MediaPlaylist(ctx, src) }); err != nil {
+			return nil, err
+		}
+	}
+	return spliced, nil
+}
+
+//matchVariant returns the variant of ads of the same kind as v, with the same codecs, and the closest bandwidth.
+//Variants without CODECS match any codecs.
+func matchVariant(v *Variant, ads []*Variant) *Variant {
+	var match *Variant
+	var distance int64
+	for _, a := range ads {
+		if a.IsIframe != v.IsIframe || a.URI == "" {
+			continue
+		}
+		if v.Codecs != "" && a.Codecs != "" && codecs(v.Codecs) != codecs(a.Codecs) {
+			continue
+		}
+		d := a.Bandwidth - v.Bandwidth
+		if d < 0 {
+			d = -d
+		}
+		if match == nil || d < distance {
+			match, distance = a, d
+		}
+	}
+	return match
+}
+
+//codecs returns the CODECS list in a canonical order, to compare lists regardless of order.
+func codecs(list string) string {
+	var formats []string
+	for _, f := range strings.Split(list, ",") {
+		formats = append(formats, strings.ToLower(strings.TrimSpace(f)))
+	}
+	sort.Strings(formats)
+	return strings.Join(formats, ",")
+}
+
+//matchRendition returns the rendition of ads with a playlist of the same type and language as r,
+//or else of the same type.
+func matchRendition(r *Rendition, ads []*Rendition) *Rendition {
+	var match *Rendition
+	for _, a := range ads {
+		if a.Type != r.Type || a.URI == "" {
+			continue
+		}
+		if strings.EqualFold(a.Language, r.Language) {
+			return a
+		}
+		if match == nil {
+			match = a
+		}
+	}
+	return match
+}
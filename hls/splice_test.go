@@ -0,0 +1,288 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+const spliceContent = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXTINF:6.000,
+0.mp4
+#EXTINF:6.000,
+1.mp4
+#EXTINF:6.000,
+2.mp4
+#EXTINF:6.000,
+3.mp4
+#EXT-X-ENDLIST
+`
+
+const spliceAd = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:5
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-MAP:URI="ad-init.mp4"
+#EXTINF:5.000,
+a0.mp4
+#EXTINF:5.000,
+a1.mp4
+#EXT-X-ENDLIST
+`
+
+func parseSplicePlaylist(t *testing.T, uri string, playlist string) *MediaPlaylist {
+	p := NewMediaPlaylist(0).WithVariant(&Variant{URI: uri})
+	if err := p.Parse(strings.NewReader(playlist)); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestMediaPlaylistSplice(t *testing.T) {
+	p := parseSplicePlaylist(t, "https://example.com/video.m3u8", spliceContent)
+	ad := parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", spliceAd)
+
+	if err := p.Splice(2, ad); err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentURIs(p.Segments); got != "0.mp4,1.mp4,https://ads.example.com/ad/a0.mp4,https://ads.example.com/ad/a1.mp4,2.mp4,3.mp4" {
+		t.Errorf("Expected the ad between 1.mp4 and 2.mp4, but got %s", got)
+	}
+	for i, s := range p.Segments {
+		if s.ID != 100+i {
+			t.Errorf("Expected segment %d to have ID %d, but got %d", i, 100+i, s.ID)
+		}
+	}
+	if !p.Segments[2].Discontinuity || !p.Segments[4].Discontinuity || p.Segments[3].Discontinuity {
+		t.Errorf("Expected discontinuities where the ad starts and ends")
+	}
+	if p.Version != 7 || p.TargetDuration != 6 {
+		t.Errorf("Expected version 7 and target duration 6, but got %d and %d", p.Version, p.TargetDuration)
+	}
+	if ad.Segments[0].URI != "a0.mp4" || ad.Segments[0].Discontinuity || len(ad.Segments[0].Keys) != 0 {
+		t.Errorf("Expected the ad to be left unchanged, but got %+v", ad.Segments[0])
+	}
+
+	encoded := encodeString(t, p)
+	expect := `#EXT-X-KEY:METHOD=NONE
+#EXT-X-MAP:URI="https://ads.example.com/ad/ad-init.mp4"
+#EXT-X-DISCONTINUITY
+#EXTINF:5.000,
+https://ads.example.com/ad/a0.mp4
+#EXTINF:5.000,
+https://ads.example.com/ad/a1.mp4
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin",IV=0x00000000000000000000000000000066
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:12Z
+#EXT-X-DISCONTINUITY
+#EXTINF:6.000,
+2.mp4
+`
+	if !strings.Contains(encoded, expect) {
+		t.Errorf("Expected spliced playlist to contain\n%s\nbut got\n%s", expect, encoded)
+	}
+}
+
+func TestMediaPlaylistSpliceByterange(t *testing.T) {
+	byteranges := func(uri string, ranges ...string) string {
+		buf := new(bytes.Buffer)
+		buf.WriteString("#EXTM3U\n#EXT-X-VERSION:4\n#EXT-X-TARGETDURATION:6\n")
+		for _, r := range ranges {
+			buf.WriteString("#EXTINF:5.000,\n#EXT-X-BYTERANGE:" + r + "\n" + uri + "\n")
+		}
+		return buf.String()
+	}
+	p := parseSplicePlaylist(t, "https://example.com/video.m3u8", byteranges("main.ts", "1000@0", "1000", "1000", "1000"))
+	ad := parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", byteranges("ad.ts", "500", "500"))
+
+	if err := p.Splice(2, ad); err != nil {
+		t.Fatal(err)
+	}
+	//the ad and the content resuming after it don't follow a segment of the same resource
+	for i, expected := range []string{"1000@0", "1000", "500@0", "500", "1000@2000", "1000"} {
+		if got := byterangeString(p.Segments[i].Byterange); got != expected {
+			t.Errorf("Expected byte range %s for segment %d, but got %s", expected, i, got)
+		}
+	}
+	if ad.Segments[0].Byterange.Offset != nil {
+		t.Errorf("Expected the ad to be left unchanged")
+	}
+}
+
+func TestMediaPlaylistSpliceDecrypt(t *testing.T) {
+	adKey := []byte("fedcba9876543210")
+	encryptedAd := strings.Replace(spliceAd, "#EXT-X-MAP", "#EXT-X-MEDIA-SEQUENCE:7\n"+`#EXT-X-KEY:METHOD=AES-128,URI="ad.key"`+"\n#EXT-X-MAP", 1)
+	p := parseSplicePlaylist(t, "https://example.com/video.m3u8", spliceContent)
+	ad := parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", encryptedAd)
+
+	//segments are encrypted with the IV of their sequence number before the splice
+	src := &memSource{
+		resources: map[string][]byte{"https://example.com/key.bin": testKey, "https://ads.example.com/ad/ad.key": adKey},
+		requests:  map[string]int{},
+	}
+	segments := make(map[string][]byte)
+	for _, playlist := range []*MediaPlaylist{p, ad} {
+		key := testKey
+		if playlist == ad {
+			key = adKey
+		}
+		for _, s := range playlist.Segments {
+			iv, _ := (&Key{}).SegmentIV(s.ID)
+			segments[s.URI] = encrypt(t, []byte("segment "+s.URI), key, iv)
+		}
+	}
+
+	if err := p.Splice(2, ad); err != nil {
+		t.Fatal(err)
+	}
+	//decrypt the segments of the encoded playlist, as a client would
+	spliced := parseSplicePlaylist(t, "https://example.com/video.m3u8", encodeString(t, p))
+	cache := NewKeyCache(src)
+	for _, s := range spliced.Segments {
+		uri := strings.TrimPrefix(s.URI, "https://ads.example.com/ad/")
+		r, err := cache.Decrypt(context.Background(), s, bytes.NewReader(segments[uri]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %v", s.URI, err)
+		}
+		if string(decrypted) != "segment "+uri {
+			t.Errorf("Expected segment %s to decrypt, but got %q", s.URI, decrypted)
+		}
+	}
+}
+
+func TestMediaPlaylistSpliceAt(t *testing.T) {
+	p := parseSplicePlaylist(t, "https://example.com/video.m3u8", spliceContent)
+	ad := parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", spliceAd)
+
+	if err := p.SpliceAt(time.Date(2020, 1, 1, 0, 0, 7, 0, time.UTC), ad); err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentURIs(p.Segments[:3]); got != "0.mp4,1.mp4,https://ads.example.com/ad/a0.mp4" {
+		t.Errorf("Expected the ad after the segment playing at the splice time, but got %s", got)
+	}
+	if err := p.SpliceAt(time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), ad); err == nil {
+		t.Error("Expected error splicing after the end of the playlist")
+	}
+}
+
+func TestMediaPlaylistSpliceErrors(t *testing.T) {
+	ad := parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", spliceAd)
+	p := parseSplicePlaylist(t, "https://example.com/video.m3u8", spliceContent)
+	for _, index := range []int{-1, 5} {
+		if err := p.Splice(index, ad); err == nil {
+			t.Errorf("Expected error splicing at index %d", index)
+		}
+	}
+
+	fairPlay := strings.Replace(spliceContent, `METHOD=AES-128,URI="key.bin"`, `METHOD=SAMPLE-AES,URI="skd://asset",KEYFORMAT="com.apple.streamingkeydelivery"`, 1)
+	encrypted := strings.Replace(spliceAd, "#EXT-X-MAP", `#EXT-X-KEY:METHOD=AES-128,URI="ad.key"`+"\n#EXT-X-MAP", 1)
+	p = parseSplicePlaylist(t, "https://example.com/video.m3u8", fairPlay)
+	if err := p.Splice(2, parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", encrypted)); err == nil {
+		t.Error("Expected error splicing an ad leaving a FairPlay key in effect")
+	}
+
+	unmapped := strings.Replace(spliceAd, "#EXT-X-MAP:URI=\"ad-init.mp4\"\n", "", 1)
+	p = parseSplicePlaylist(t, "https://example.com/video.m3u8", spliceContent)
+	if err := p.Splice(2, parseSplicePlaylist(t, "https://ads.example.com/ad/index.m3u8", unmapped)); err == nil {
+		t.Error("Expected error splicing an ad without EXT-X-MAP")
+	}
+	if len(p.Segments) != 4 || p.Segments[2].Discontinuity {
+		t.Errorf("Expected a failed splice to leave the playlist unchanged")
+	}
+}
+
+func TestMasterPlaylistSplice(t *testing.T) {
+	src := &clipSource{memSource{
+		resources: map[string][]byte{
+			"https://example.com/low.m3u8":          []byte(spliceContent),
+			"https://example.com/high.m3u8":         []byte(spliceContent),
+			"https://example.com/audio.m3u8":        []byte(spliceContent),
+			"https://ads.example.com/ad/low.m3u8":   []byte(spliceAd),
+			"https://ads.example.com/ad/high.m3u8":  []byte(spliceAd),
+			"https://ads.example.com/ad/hevc.m3u8":  []byte(spliceAd),
+			"https://ads.example.com/ad/audio.m3u8": []byte(spliceAd),
+		},
+		requests: map[string]int{},
+	}}
+	m := NewMasterPlaylist(7)
+	m.URI = "https://example.com/master.m3u8"
+	m.Variants = []*Variant{
+		{URI: "low.m3u8", Bandwidth: 800000, Codecs: "avc1.4d401f,mp4a.40.2"},
+		{URI: "high.m3u8", Bandwidth: 4000000, Codecs: "avc1.4d401f,mp4a.40.2"},
+		{URI: "low.m3u8", Bandwidth: 800000, Codecs: "avc1.4d401f,mp4a.40.2", Audio: "aud"},
+	}
+	m.Renditions = []*Rendition{{Type: "AUDIO", GroupID: "aud", Name: "en", Language: "en", URI: "audio.m3u8"}}
+	for _, v := range m.Variants {
+		v.masterPlaylist = m
+	}
+	for _, r := range m.Renditions {
+		r.masterPlaylist = m
+	}
+
+	ad := NewMasterPlaylist(7)
+	ad.URI = "https://ads.example.com/ad/master.m3u8"
+	ad.Variants = []*Variant{
+		{URI: "hevc.m3u8", Bandwidth: 800000, Codecs: "hvc1.1.6.L93.B0,mp4a.40.2"},
+		{URI: "low.m3u8", Bandwidth: 1000000, Codecs: "mp4a.40.2,avc1.4d401f"},
+		{URI: "high.m3u8", Bandwidth: 3000000, Codecs: "avc1.4d401f,mp4a.40.2"},
+		{URI: "iframe.m3u8", Bandwidth: 4000000, Codecs: "avc1.4d401f", IsIframe: true},
+	}
+	ad.Renditions = []*Rendition{
+		{Type: "AUDIO", GroupID: "aud", Name: "fr", Language: "fr", URI: "audio-fr.m3u8"},
+		{Type: "AUDIO", GroupID: "aud", Name: "en", Language: "en", URI: "audio.m3u8"},
+	}
+	for _, v := range ad.Variants {
+		v.masterPlaylist = ad
+	}
+	for _, r := range ad.Renditions {
+		r.masterPlaylist = ad
+	}
+
+	spliced, err := m.Splice(context.Background(), src, 12, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spliced) != 3 {
+		t.Fatalf("Expected low.m3u8, high.m3u8 and audio.m3u8 to be spliced, but got %v", spliced)
+	}
+	tests := map[string]string{
+		"low.m3u8":   "https://ads.example.com/ad/a0.mp4",
+		"high.m3u8":  "https://ads.example.com/ad/a0.mp4",
+		"audio.m3u8": "https://ads.example.com/ad/a0.mp4",
+	}
+	for uri, expect := range tests {
+		if got := spliced[uri].Segments[2].URI; got != expect {
+			t.Errorf("Expected %s to be spliced at 12s, but got %s", uri, got)
+		}
+	}
+	for _, uri := range []string{"https://ads.example.com/ad/low.m3u8", "https://ads.example.com/ad/high.m3u8", "https://ads.example.com/ad/audio.m3u8"} {
+		if src.requests[uri] != 1 {
+			t.Errorf("Expected %s to be read once, but got %d requests", uri, src.requests[uri])
+		}
+	}
+	if src.requests["https://ads.example.com/ad/hevc.m3u8"] != 0 {
+		t.Error("Expected the ad variant with other codecs not to be used")
+	}
+
+	if _, err := m.Splice(context.Background(), src, 100, ad); err == nil {
+		t.Error("Expected error splicing after the end of the playlists")
+	}
+	ad.Variants = ad.Variants[:1]
+	if _, err := m.Splice(context.Background(), src, 12, ad); err == nil {
+		t.Error("Expected error without an ad variant of the same codecs")
+	}
+}
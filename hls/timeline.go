@@ -0,0 +1,217 @@
+package hls
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//Timeline is the schedule of the EXT-X-DATERANGE tags of a Media Playlist, mapped onto its segments.
+type Timeline struct {
+	Ranges   []*TimelineRange //Every date range, in the order its first tag appears in the playlist.
+	Overlaps []Overlap        //Date ranges of the same CLASS that overlap, which the HLS spec forbids.
+}
+
+//TimelineRange is a date range of a Timeline, merged from every EXT-X-DATERANGE tag with its ID.
+type TimelineRange struct {
+	//DateRange has the attributes of every tag with the ID. Its SCTE35 is nil, the signals are in Out, In and Cmd.
+	DateRange *DateRange
+	Tags      []*DateRange //Every tag with the ID, in playlist order.
+	//End is when the range ends: its END-DATE, its START-DATE plus DURATION, the START-DATE of the next range
+	//of its CLASS for END-ON-NEXT, or the date of the segment of its SCTE35-IN. It's zero if the end isn't known yet.
+	End      time.Time
+	Out      *SCTE35    //SCTE35-OUT of the range, if any.
+	In       *SCTE35    //SCTE35-IN of the range, if any.
+	Cmd      *SCTE35    //SCTE35-CMD of the range, if any.
+	Segments []*Segment //Segments playing during the range. Ranges without an End cover every segment after their START-DATE.
+}
+
+//Overlap reports two date ranges of the same CLASS that overlap.
+type Overlap struct {
+	First  *TimelineRange
+	Second *TimelineRange
+}
+
+//Timeline correlates the EXT-X-DATERANGE tags of the playlist: tags with the same ID are merged, END-ON-NEXT is
+//resolved against the next range of the same CLASS, SCTE35-OUT and SCTE35-IN are paired by ID, and each range is
+//mapped onto the segments it covers using their ProgramDateTimes. Segments without a date aren't covered
+//by any range.
+//It returns an error if tags with the same ID have conflicting attributes.
+func (p *MediaPlaylist) Timeline() (*Timeline, error) {
+	t := &Timeline{}
+	byID := make(map[string]*TimelineRange)
+	inDates := make(map[*TimelineRange]time.Time)
+	starts := p.ProgramDateTimes()
+	ends := make([]time.Time, len(starts))
+	for i, s := range p.Segments {
+		if !starts[i].IsZero() {
+			ends[i] = starts[i].Add(time.Duration(s.duration() * float64(time.Second)))
+		}
+
+		for _, d := range s.dateRanges() {
+			r, ok := byID[d.ID]
+			if !ok {
+				r = &TimelineRange{DateRange: &DateRange{ID: d.ID}}
+				byID[d.ID] = r
+				t.Ranges = append(t.Ranges, r)
+			}
+			if err := r.merge(d); err != nil {
+				return nil, err
+			}
+			if d.SCTE35 != nil && strings.EqualFold(d.SCTE35.Type, "IN") && !starts[i].IsZero() {
+				inDates[r] = starts[i]
+			}
+		}
+	}
+	if len(t.Ranges) > 0 && !dated(starts) {
+		return nil, errors.New("EXT-X-DATERANGE requires EXT-X-PROGRAM-DATE-TIME to be mapped onto segments")
+	}
+
+	for _, r := range t.Ranges {
+		r.End = t.end(r, inDates[r])
+		for i, s := range p.Segments {
+			if starts[i].IsZero() {
+				continue
+			}
+			if r.covers(starts[i], ends[i]) {
+				r.Segments = append(r.Segments, s)
+			}
+		}
+	}
+
+	for i, a := range t.Ranges {
+		for _, b := range t.Ranges[i+1:] {
+			if a.DateRange.Class != "" && a.DateRange.Class == b.DateRange.Class && a.overlaps(b) {
+				t.Overlaps = append(t.Overlaps, Overlap{First: a, Second: b})
+			}
+		}
+	}
+	return t, nil
+}
+
+//Range returns the date range with the ID, or nil if there's none.
+func (t *Timeline) Range(id string) *TimelineRange {
+	for _, r := range t.Ranges {
+		if r.DateRange.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+//end resolves the end of r. in is the date of the segment of its SCTE35-IN, if any.
+func (t *Timeline) end(r *TimelineRange, in time.Time) time.Time {
+	d := r.DateRange
+	switch {
+	case !d.EndDate.IsZero():
+		return d.EndDate
+	case d.Duration != nil:
+		return d.StartDate.Add(time.Duration(*d.Duration * float64(time.Second)))
+	case d.EndOnNext:
+		var next time.Time
+		for _, o := range t.Ranges {
+			if o.DateRange.Class == d.Class && o.DateRange.StartDate.After(d.StartDate) &&
+				(next.IsZero() || o.DateRange.StartDate.Before(next)) {
+				next = o.DateRange.StartDate
+			}
+		}
+		return next
+	}
+	return in
+}
+
+//covers returns true if the segment playing from start to end plays during r. Ranges without a duration cover
+//the segment playing at their START-DATE.
+func (r *TimelineRange) covers(start time.Time, end time.Time) bool {
+	if end.Before(r.DateRange.StartDate) || end.Equal(r.DateRange.StartDate) {
+		return false
+	}
+	if r.End.IsZero() {
+		return true
+	}
+	if r.End.Equal(r.DateRange.StartDate) {
+		return !r.DateRange.StartDate.Before(start)
+	}
+	return start.Before(r.End)
+}
+
+//overlaps returns true if r and o are scheduled at the same time. Ranges without an End never end.
+func (r *TimelineRange) overlaps(o *TimelineRange) bool {
+	before := func(a *TimelineRange, b *TimelineRange) bool {
+		return !a.End.IsZero() && !a.End.After(b.DateRange.StartDate)
+	}
+	return !before(r, o) && !before(o, r)
+}
+
+//merge adds the attributes of the tag d to r. Tags with the same ID MUST have the same values for the
+//attributes they share.
+func (r *TimelineRange) merge(d *DateRange) error {
+	m := r.DateRange
+	conflict := func(name string) error {
+		return fmt.Errorf("EXT-X-DATERANGE %s has conflicting %s values", d.ID, name)
+	}
+
+	if d.Class != "" {
+		if m.Class != "" && m.Class != d.Class {
+			return conflict("CLASS")
+		}
+		m.Class = d.Class
+	}
+	if !d.StartDate.IsZero() {
+		if !m.StartDate.IsZero() && !m.StartDate.Equal(d.StartDate) {
+			return conflict("START-DATE")
+		}
+		m.StartDate = d.StartDate
+	}
+	if !d.EndDate.IsZero() {
+		if !m.EndDate.IsZero() && !m.EndDate.Equal(d.EndDate) {
+			return conflict("END-DATE")
+		}
+		m.EndDate = d.EndDate
+	}
+	if d.Duration != nil {
+		if m.Duration != nil && *m.Duration != *d.Duration {
+			return conflict("DURATION")
+		}
+		v := *d.Duration
+		m.Duration = &v
+	}
+	if d.PlannedDuration != nil {
+		if m.PlannedDuration != nil && *m.PlannedDuration != *d.PlannedDuration {
+			return conflict("PLANNED-DURATION")
+		}
+		v := *d.PlannedDuration
+		m.PlannedDuration = &v
+	}
+	m.EndOnNext = m.EndOnNext || d.EndOnNext
+	for _, name := range d.ClientAttributes.Names() {
+		v, _ := d.ClientAttributes.Get(name)
+		if current, ok := m.ClientAttributes.Get(name); ok && !reflect.DeepEqual(current, v) {
+			return conflict(name)
+		}
+		m.ClientAttributes.set(name, v)
+	}
+
+	if d.SCTE35 != nil {
+		var signal **SCTE35
+		switch strings.ToUpper(d.SCTE35.Type) {
+		case "OUT":
+			signal = &r.Out
+		case "IN":
+			signal = &r.In
+		case "CMD":
+			signal = &r.Cmd
+		default:
+			return errors.New("SCTE35 type must be IN, OUT or CMD")
+		}
+		if *signal != nil && (*signal).Value != d.SCTE35.Value {
+			return conflict("SCTE35-" + strings.ToUpper(d.SCTE35.Type))
+		}
+		*signal = d.SCTE35
+	}
+
+	r.Tags = append(r.Tags, d)
+	return nil
+}
@@ -0,0 +1,159 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const timelinePlaylist = `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.000,
+pre.ts
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="chapter-1",CLASS="com.example.chapter",START-DATE="2020-01-01T00:00:00Z",END-ON-NEXT=YES
+#EXTINF:6.000,
+0.ts
+#EXT-X-DATERANGE:ID="splice-1",START-DATE="2020-01-01T00:00:06Z",PLANNED-DURATION=12,SCTE35-OUT=0xFC30
+#EXTINF:6.000,
+1.ts
+#EXT-X-DATERANGE:ID="ad-1",CLASS="com.example.ad",START-DATE="2020-01-01T00:00:06Z",DURATION=12,X-COM-EXAMPLE-AD-ID="1"
+#EXTINF:6.000,
+2.ts
+#EXT-X-DATERANGE:ID="splice-1",START-DATE="2020-01-01T00:00:06Z",SCTE35-IN=0xFC31
+#EXTINF:6.000,
+3.ts
+#EXT-X-DATERANGE:ID="chapter-2",CLASS="com.example.chapter",START-DATE="2020-01-01T00:00:24Z",END-ON-NEXT=YES
+#EXTINF:6.000,
+4.ts
+#EXT-X-DATERANGE:ID="ad-2",CLASS="com.example.ad",START-DATE="2020-01-01T00:00:12Z",DURATION=12
+#EXTINF:6.000,
+5.ts
+#EXT-X-ENDLIST
+`
+
+func segmentURIs(segments []*Segment) string {
+	var uris []string
+	for _, s := range segments {
+		uris = append(uris, s.URI)
+	}
+	return strings.Join(uris, ",")
+}
+
+func TestTimeline(t *testing.T) {
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(timelinePlaylist)); err != nil {
+		t.Fatal(err)
+	}
+	tl, err := p.Timeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tl.Ranges) != 5 {
+		t.Fatalf("Expected 5 ranges, but got %d", len(tl.Ranges))
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		id       string
+		end      time.Time
+		segments string
+	}{
+		{"chapter-1", start.Add(24 * time.Second), "0.ts,1.ts,2.ts,3.ts"},
+		{"splice-1", start.Add(18 * time.Second), "1.ts,2.ts"},
+		{"ad-1", start.Add(18 * time.Second), "1.ts,2.ts"},
+		{"chapter-2", time.Time{}, "4.ts,5.ts"},
+		{"ad-2", start.Add(24 * time.Second), "2.ts,3.ts"},
+	}
+	for _, tt := range tests {
+		r := tl.Range(tt.id)
+		if r == nil {
+			t.Errorf("Expected range %s", tt.id)
+			continue
+		}
+		if !r.End.Equal(tt.end) {
+			t.Errorf("Expected %s to end at %v, but got %v", tt.id, tt.end, r.End)
+		}
+		if got := segmentURIs(r.Segments); got != tt.segments {
+			t.Errorf("Expected %s to cover %s, but got %s", tt.id, tt.segments, got)
+		}
+	}
+
+	splice := tl.Range("splice-1")
+	if len(splice.Tags) != 2 || splice.Out == nil || splice.Out.Value != "0xFC30" || splice.In == nil || splice.In.Value != "0xFC31" {
+		t.Errorf("Expected splice-1 to pair SCTE35-OUT and SCTE35-IN, but got %+v", splice)
+	}
+	if splice.DateRange.PlannedDuration == nil || *splice.DateRange.PlannedDuration != 12 {
+		t.Errorf("Expected splice-1 to keep PLANNED-DURATION of its first tag")
+	}
+	if v, _ := tl.Range("ad-1").DateRange.ClientAttributes.String("X-COM-EXAMPLE-AD-ID"); v != "1" {
+		t.Errorf("Expected ad-1 X-COM-EXAMPLE-AD-ID to be 1, but got %q", v)
+	}
+
+	if len(tl.Overlaps) != 1 || tl.Overlaps[0].First.DateRange.ID != "ad-1" || tl.Overlaps[0].Second.DateRange.ID != "ad-2" {
+		t.Errorf("Expected ad-1 and ad-2 to overlap, but got %+v", tl.Overlaps)
+	}
+}
+
+func TestTimelineErrors(t *testing.T) {
+	conflict := `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="ad",START-DATE="2020-01-01T00:00:00Z",DURATION=6
+#EXTINF:6.000,
+0.ts
+#EXT-X-DATERANGE:ID="ad",START-DATE="2020-01-01T00:00:00Z",DURATION=12
+#EXTINF:6.000,
+1.ts
+`
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(conflict)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Timeline(); err == nil {
+		t.Errorf("Expected error for conflicting DURATION values")
+	}
+
+	p = NewMediaPlaylist(7)
+	p.Segments = Segments{&Segment{URI: "0.ts", Inf: &Inf{Duration: 6}, DateRange: &DateRange{ID: "ad", StartDate: time.Now()}}}
+	if _, err := p.Timeline(); err == nil {
+		t.Errorf("Expected error for a playlist without EXT-X-PROGRAM-DATE-TIME")
+	}
+}
+
+func TestTimelineSegmentDateRanges(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z
+#EXT-X-DATERANGE:ID="splice-1",START-DATE="2020-01-01T00:00:00Z",PLANNED-DURATION=6,SCTE35-OUT=0xFC30
+#EXT-X-DATERANGE:ID="ad-1",CLASS="com.example.ad",START-DATE="2020-01-01T00:00:00Z",DURATION=6
+#EXTINF:6.000,
+0.ts
+#EXTINF:6.000,
+1.ts
+#EXT-X-ENDLIST
+`
+	p := NewMediaPlaylist(0)
+	if err := p.Parse(strings.NewReader(playlist)); err != nil {
+		t.Fatal(err)
+	}
+	s := p.Segments[0]
+	if len(s.DateRanges) != 2 || s.DateRanges[0].ID != "splice-1" || s.DateRanges[1].ID != "ad-1" || s.DateRange != s.DateRanges[0] {
+		t.Fatalf("Expected both EXT-X-DATERANGE tags of the segment, but got %v", s.DateRanges)
+	}
+	tl, err := p.Timeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tl.Range("splice-1") == nil || tl.Range("ad-1") == nil {
+		t.Errorf("Expected ranges splice-1 and ad-1, but got %d ranges", len(tl.Ranges))
+	}
+
+	encoded := encodeString(t, p)
+	expect := "#EXT-X-DATERANGE:ID=splice-1"
+	if i := strings.Index(encoded, expect); i == -1 || !strings.Contains(encoded[i:], "#EXT-X-DATERANGE:ID=ad-1") {
+		t.Errorf("Expected both EXT-X-DATERANGE tags in order, but got\n%s", encoded)
+	}
+}
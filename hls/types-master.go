@@ -1,6 +1,7 @@
 package hls
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -73,6 +74,26 @@ func (r *Rendition) AbsoluteURL() (string, error) {
 	return resolveURLReference(r.masterPlaylist.URI, r.URI)
 }
 
+// MediaPlaylist reads the Media Playlist of the rendition from src. Renditions aren't variants, so the playlist
+// is read as a resource, and resolves its resources against the rendition URI.
+func (r *Rendition) MediaPlaylist(ctx context.Context, src Source) (*MediaPlaylist, error) {
+	uri, err := r.AbsoluteURL()
+	if err != nil {
+		return nil, err
+	}
+	body, err := src.Resource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	p := NewMediaPlaylist(0).WithVariant(&Variant{URI: uri})
+	if err := p.Parse(body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
 // Variant represents the tag #EXT-X-STREAM-INF: <attribute-list> and tag #EXT-X-I-FRAME-STREAM-INF.
 // #EXT-X-STREAM-INF specifies a Variant Stream, which is one of the ren which can be combined to play the presentation.
 // A URI line following the tag indicates the Media Playlist carrying a rendition of the Variant Stream and it MUST be present.
@@ -20,3 +20,15 @@ type MediaPlaylist struct {
 	IndependentSegments   bool        //Represents tag #EXT-X-INDEPENDENT-SEGMENTS. Applies to every Media Segment in the playlist.
 	StartPoint            *StartPoint //Represents tag #EXT-X-START
 }
+
+//absoluteURL returns the URI of the playlist, resolved against the Master Playlist it was listed in if any.
+//Relative resource locations of the playlist are resolved against it.
+func (p *MediaPlaylist) absoluteURL() (string, error) {
+	if p.Variant == nil {
+		return "", nil
+	}
+	if p.Variant.masterPlaylist == nil {
+		return p.URI, nil
+	}
+	return p.Variant.AbsoluteURL()
+}
@@ -15,12 +15,33 @@ type Segment struct {
 	Discontinuity   bool //Represents tag #EXT-X-DISCONTINUITY. MUST be present if there's change in file format; number, type and identifiers of tracks or timestamp sequence
 	Keys            []*Key
 	Map             *Map
-	ProgramDateTime time.Time //Represents tag #EXT-X-PROGRAM-DATE-TIME
-	DateRange       *DateRange
+	ProgramDateTime time.Time    //Represents tag #EXT-X-PROGRAM-DATE-TIME
+	DateRanges      []*DateRange //Represents the #EXT-X-DATERANGE tags before the segment.
+	DateRange       *DateRange   //Deprecated: use DateRanges. Parse sets it to the first of DateRanges, and Encode only writes it if DateRanges is empty.
+	Gap             bool         //Represents tag #EXT-X-GAP. Indicates the segment is missing and MUST NOT be loaded by clients. V8 or higher
+	Bitrate         int          //Represents tag #EXT-X-BITRATE. Approximate bit rate of the segment in kbit/s. Applies to every following segment until the next EXT-X-BITRATE.
 
 	mediaPlaylist *MediaPlaylist // MediaPlaylist is included to be used internally for resolving relative resource locations
 }
 
+//dateRanges returns the EXT-X-DATERANGE tags of the segment: DateRanges, or DateRange if it's the only one set.
+func (s *Segment) dateRanges() []*DateRange {
+	if len(s.DateRanges) == 0 && s.DateRange != nil {
+		return []*DateRange{s.DateRange}
+	}
+	return s.DateRanges
+}
+
+//addDateRange adds the EXT-X-DATERANGE tag d to the segment. DateRanges is copied, as segments copied
+//from another playlist share it.
+func (s *Segment) addDateRange(d *DateRange) {
+	dateRanges := s.dateRanges()
+	s.DateRanges = append(dateRanges[:len(dateRanges):len(dateRanges)], d)
+	if s.DateRange == nil {
+		s.DateRange = d
+	}
+}
+
 // Request creates a new http request ready to send to retrieve the segment
 func (s *Segment) Request() (*http.Request, error) {
 	uri, err := s.AbsoluteURL()
@@ -37,7 +58,11 @@ func (s *Segment) Request() (*http.Request, error) {
 
 // AbsoluteURL will resolve the segment URI to a absolute path, given it is a relative URL.
 func (s *Segment) AbsoluteURL() (string, error) {
-	return resolveURLReference(s.mediaPlaylist.URI, s.URI)
+	base, err := s.mediaPlaylist.absoluteURL()
+	if err != nil {
+		return "", err
+	}
+	return resolveURLReference(base, s.URI)
 }
 
 // Segments implements golang/sort interface to sort a Segment slice by Segment ID
@@ -88,7 +113,7 @@ func (b *Byterange) Equal(other *Byterange) bool {
 // TODO(jstackhouse): Split SESSION-KEY into it's own type as it's got different validation properties, and is part of the master playlist, not media playlist.
 type Key struct {
 	IsSession         bool   //Identifies if #EXT-X-KEY or #EXT-X-SESSION-KEY. If #EXT-X-SESSION-KEY, Method MUST NOT be NONE.
-	Method            string //Required. Possible Values: NONE, AES-128, SAMPLE-AES. If NONE, other attributes MUST NOT be present.
+	Method            string //Required. Possible Values: NONE, AES-128, SAMPLE-AES, SAMPLE-AES-CTR. If NONE, other attributes MUST NOT be present.
 	URI               string //Required unless the method is NONE. Specifies how to get the key for the encryption method.
 	IV                string //Optional. Hexadecimal that specifies a 128-bit int Initialization Vector to be used with the key.
 	Keyformat         string //Optional. Specifies how the key is represented in the resource. V5 or higher
@@ -120,7 +145,11 @@ func (k *Key) AbsoluteURL() (string, error) {
 	if k.IsSession {
 		uri, err = resolveURLReference(k.masterPlaylist.URI, k.URI)
 	} else {
-		uri, err = resolveURLReference(k.mediaPlaylist.URI, k.URI)
+		var base string
+		if base, err = k.mediaPlaylist.absoluteURL(); err != nil {
+			return "", err
+		}
+		uri, err = resolveURLReference(base, k.URI)
 	}
 
 	return uri, err
@@ -190,7 +219,11 @@ func (m *Map) Request() (*http.Request, error) {
 
 // AbsoluteURL will resolve the EXT-X-MAP URI to a absolute path, given it is a URL.
 func (m *Map) AbsoluteURL() (string, error) {
-	return resolveURLReference(m.mediaPlaylist.URI, m.URI)
+	base, err := m.mediaPlaylist.absoluteURL()
+	if err != nil {
+		return "", err
+	}
+	return resolveURLReference(base, m.URI)
 }
 
 //DateRange represents tag #EXT-X-DATERANGE:<attribute=value>.
@@ -198,14 +231,14 @@ func (m *Map) AbsoluteURL() (string, error) {
 //If present, playlist MUST also contain at least one EXT-X-PROGRAM-DATE-TIME tag.
 //Tags with the same Class MUST NOT indicate ranges that overlap.
 type DateRange struct {
-	ID               string    //Required. If more than one tag with same ID exists, att values MUST be the same.
-	Class            string    //Optional. Specifies some set of attributes and their associated value semantics.
-	StartDate        time.Time //Required.
-	EndDate          time.Time //Optional.
-	Duration         *float64  //Optional. If both EndDate and Duration present, check EndDate equal to Duration + StartDate
-	PlannedDuration  *float64  //Optional. Expected duration.
-	XClientAttribute []string  //Optional. Namespace reserved for client-defined att. eg. X-COM-EXAMPLE="example".
-	EndOnNext        bool      //Optional. Possible Value: YES. Indicates the end of the current date range is equal to the start date of the following range of the samePROGRAM-DATE-TIME class.
+	ID               string           //Required. If more than one tag with same ID exists, att values MUST be the same.
+	Class            string           //Optional. Specifies some set of attributes and their associated value semantics.
+	StartDate        time.Time        //Required.
+	EndDate          time.Time        //Optional.
+	Duration         *float64         //Optional. If both EndDate and Duration present, check EndDate equal to Duration + StartDate
+	PlannedDuration  *float64         //Optional. Expected duration.
+	ClientAttributes ClientAttributes //Optional. Namespace reserved for client-defined att. eg. X-COM-EXAMPLE="example".
+	EndOnNext        bool             //Optional. Possible Value: YES. Indicates the end of the current date range is equal to the start date of the following range of the samePROGRAM-DATE-TIME class.
 	SCTE35           *SCTE35
 }
 
@@ -2,21 +2,22 @@ package hls
 
 import (
 	"context"
-	"fmt"
 	"io"
-	"net/url"
+
+	"github.com/ingest/manifest/internal/source"
 )
 
 const (
-	sub     = "SUBTITLES"
-	aud     = "AUDIO"
-	vid     = "VIDEO"
-	cc      = "CLOSED-CAPTIONS"
-	aes     = "AES-128"
-	none    = "NONE"
-	sample  = "SAMPLE-AES"
-	boolYes = "YES"
-	boolNo  = "NO"
+	sub       = "SUBTITLES"
+	aud       = "AUDIO"
+	vid       = "VIDEO"
+	cc        = "CLOSED-CAPTIONS"
+	aes       = "AES-128"
+	none      = "NONE"
+	sample    = "SAMPLE-AES"
+	sampleCTR = "SAMPLE-AES-CTR"
+	boolYes   = "YES"
+	boolNo    = "NO"
 )
 
 // Source represents how you can fetch the components of a HLS manifest from different locations
@@ -27,17 +28,5 @@ type Source interface {
 }
 
 func resolveURLReference(base, sub string) (string, error) {
-	ref, err := url.Parse(sub)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse subresource uri: %v", err)
-	}
-	if ref.IsAbs() {
-		return ref.String(), nil
-	}
-
-	baseURL, err := url.Parse(base)
-	if err != nil {
-		return "", err
-	}
-	return baseURL.ResolveReference(ref).String(), nil
+	return source.ResolveURL(base, sub)
 }
@@ -0,0 +1,91 @@
+//Package source holds the parts of the hls and dash sources that don't depend on the manifest format:
+//opening resources from a directory or memory, checking HTTP responses and resolving URIs.
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ingest/manifest"
+)
+
+//Open opens the resource at uri.
+type Open func(ctx context.Context, uri string) (io.ReadCloser, error)
+
+//Dir returns an Open reading resources from the directory root. URIs are resolved as paths relative to root.
+//The path of URLs with a scheme and host is used, so manifests listing absolute URLs can be read from a
+//copy of their origin.
+func Dir(root string) Open {
+	return func(ctx context.Context, uri string) (io.ReadCloser, error) {
+		name, err := dirPath(root, uri)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(name)
+	}
+}
+
+//Memory returns an Open serving resources, keyed by URI.
+//Missing resources return an error satisfying os.IsNotExist.
+func Memory(resources map[string][]byte) Open {
+	return func(ctx context.Context, uri string) (io.ReadCloser, error) {
+		b, ok := resources[uri]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: uri, Err: os.ErrNotExist}
+		}
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+//dirPath returns the path of the file of uri in root. The path is cleaned first, so it can't escape root.
+func dirPath(root string, uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(path.Clean("/"+u.Path))), nil
+}
+
+//Do sends the request with c, returning a *manifest.StatusError if the response status code isn't 2xx.
+func Do(ctx context.Context, c *http.Client, req *http.Request) (*http.Response, error) {
+	res, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, &manifest.StatusError{URI: req.URL.String(), StatusCode: res.StatusCode, Status: res.Status, Header: res.Header}
+	}
+	return res, nil
+}
+
+//ResolveURL resolves the reference ref against base. A relative base, such as a path in a directory,
+//resolves relative references to relative paths too.
+func ResolveURL(base, ref string) (string, error) {
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse subresource uri: %v", err)
+	}
+	if r.IsAbs() {
+		return r.String(), nil
+	}
+
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	resolved := b.ResolveReference(r)
+	if !b.IsAbs() && b.Host == "" && !strings.HasPrefix(b.Path, "/") && !strings.HasPrefix(r.Path, "/") {
+		resolved.Path = strings.TrimPrefix(resolved.Path, "/")
+	}
+	return resolved.String(), nil
+}
@@ -0,0 +1,31 @@
+package source
+
+import "testing"
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		base   string
+		ref    string
+		expect string
+	}{
+		{"https://cdn.example.com/hls/master.m3u8", "720p/index.m3u8", "https://cdn.example.com/hls/720p/index.m3u8"},
+		{"https://cdn.example.com/hls/master.m3u8", "/dash/manifest.mpd", "https://cdn.example.com/dash/manifest.mpd"},
+		{"https://cdn.example.com/hls/master.m3u8", "https://ads.example.com/ad.m3u8", "https://ads.example.com/ad.m3u8"},
+		{"hls/master.m3u8", "720p/index.m3u8", "hls/720p/index.m3u8"},
+		{"master.m3u8", "../720p/index.m3u8", "720p/index.m3u8"},
+	}
+
+	for _, tt := range tests {
+		uri, err := ResolveURL(tt.base, tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri != tt.expect {
+			t.Errorf("Expected %s against %s to resolve to %s, but got %s", tt.ref, tt.base, tt.expect, uri)
+		}
+	}
+
+	if _, err := ResolveURL("master.m3u8", "%zz"); err == nil {
+		t.Error("Expected error resolving an invalid reference")
+	}
+}
@@ -0,0 +1,255 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ingest/manifest/hls"
+)
+
+//ref is a URI of a playlist to rewrite to the local file name.
+type ref struct {
+	uri  *string
+	name string
+}
+
+//byteRange is the byte range of a segment or map in the resource at url.
+type byteRange struct {
+	url       string
+	name      string
+	byterange *hls.Byterange
+}
+
+//mediaPlaylist is a media playlist being mirrored to the local file name.
+type mediaPlaylist struct {
+	*hls.MediaPlaylist
+	name   string
+	refs   []ref
+	ranges []byteRange
+}
+
+//HLS mirrors the master playlist at uri, read from src, with its variants, I-frame playlists,
+//renditions, session keys and data, and the keys, maps and segments of every media playlist.
+//Gap segments aren't downloaded, and keep their URI.
+//It returns the path of the local master playlist.
+func (m *Mirror) HLS(ctx context.Context, src hls.Source, uri string) (string, error) {
+	master, err := src.Master(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	masterName, err := m.localPath(uri)
+	if err != nil {
+		return "", err
+	}
+
+	//every URI is resolved before any is rewritten, as the playlists resolve against each other
+	resources := make(map[string]string)
+	var masterRefs []ref
+	var playlists []*mediaPlaylist
+	addMedia := func(field *string, abs string, p *hls.MediaPlaylist) error {
+		mp, err := m.hlsMedia(p, abs, resources)
+		if err != nil {
+			return err
+		}
+		masterRefs = append(masterRefs, ref{uri: field, name: mp.name})
+		playlists = append(playlists, mp)
+		return nil
+	}
+
+	for _, v := range master.Variants {
+		abs, err := v.AbsoluteURL()
+		if err != nil {
+			return "", err
+		}
+		p, err := src.Media(ctx, v)
+		if err != nil {
+			return "", err
+		}
+		if err := addMedia(&v.URI, abs, p); err != nil {
+			return "", err
+		}
+	}
+	for _, r := range master.Renditions {
+		if r.URI == "" {
+			continue
+		}
+		abs, err := r.AbsoluteURL()
+		if err != nil {
+			return "", err
+		}
+		p, err := r.MediaPlaylist(ctx, src)
+		if err != nil {
+			return "", err
+		}
+		if err := addMedia(&r.URI, abs, p); err != nil {
+			return "", err
+		}
+	}
+	for _, k := range master.SessionKeys {
+		if k.Method == "NONE" || !fetchable(k.URI) {
+			continue
+		}
+		abs, err := k.AbsoluteURL()
+		if err != nil {
+			return "", err
+		}
+		if masterRefs, err = m.addResource(resources, masterRefs, &k.URI, abs); err != nil {
+			return "", err
+		}
+	}
+	for _, sd := range master.SessionData {
+		if sd.URI == "" || !fetchable(sd.URI) {
+			continue
+		}
+		abs, err := sd.AbsoluteURL()
+		if err != nil {
+			return "", err
+		}
+		if masterRefs, err = m.addResource(resources, masterRefs, &sd.URI, abs); err != nil {
+			return "", err
+		}
+	}
+
+	if err := m.download(ctx, src.Resource, resources); err != nil {
+		return "", err
+	}
+	if m.VerifyByteRanges {
+		for _, p := range playlists {
+			if err := verifyByteRanges(p.ranges); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, p := range playlists {
+		if err := rewrite(p.name, p.refs); err != nil {
+			return "", err
+		}
+		r, err := p.Encode()
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s: %v", p.name, err)
+		}
+		if err := write(p.name, r); err != nil {
+			return "", err
+		}
+	}
+	if err := rewrite(masterName, masterRefs); err != nil {
+		return "", err
+	}
+	r, err := master.Encode()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %v", masterName, err)
+	}
+	if err := write(masterName, r); err != nil {
+		return "", err
+	}
+	return masterName, nil
+}
+
+//hlsMedia resolves the keys, maps and segments of the media playlist p at abs, adding them to resources.
+func (m *Mirror) hlsMedia(p *hls.MediaPlaylist, abs string, resources map[string]string) (*mediaPlaylist, error) {
+	name, err := m.localPath(abs)
+	if err != nil {
+		return nil, err
+	}
+	mp := &mediaPlaylist{MediaPlaylist: p, name: name}
+
+	var previousMap *hls.Map
+	for _, s := range p.Segments {
+		for _, k := range s.Keys {
+			if k.Method == "NONE" || !fetchable(k.URI) {
+				continue
+			}
+			uri, err := k.AbsoluteURL()
+			if err != nil {
+				return nil, err
+			}
+			if mp.refs, err = m.addResource(resources, mp.refs, &k.URI, uri); err != nil {
+				return nil, err
+			}
+		}
+		if s.Map != nil && s.Map != previousMap {
+			previousMap = s.Map
+			uri, err := s.Map.AbsoluteURL()
+			if err != nil {
+				return nil, err
+			}
+			if mp.refs, err = m.addResource(resources, mp.refs, &s.Map.URI, uri); err != nil {
+				return nil, err
+			}
+			if s.Map.Byterange != nil {
+				mp.ranges = append(mp.ranges, byteRange{url: uri, name: resources[uri], byterange: s.Map.Byterange})
+			}
+		}
+
+		//gap segments are missing, and clients don't load them
+		if s.Gap {
+			continue
+		}
+		uri, err := s.AbsoluteURL()
+		if err != nil {
+			return nil, err
+		}
+		if mp.refs, err = m.addResource(resources, mp.refs, &s.URI, uri); err != nil {
+			return nil, err
+		}
+		if s.Byterange != nil {
+			mp.ranges = append(mp.ranges, byteRange{url: uri, name: resources[uri], byterange: s.Byterange})
+		}
+	}
+	return mp, nil
+}
+
+//addResource adds the resource at abs to resources, and the URI field referencing it to refs.
+func (m *Mirror) addResource(resources map[string]string, refs []ref, field *string, abs string) ([]ref, error) {
+	name, ok := resources[abs]
+	if !ok {
+		var err error
+		if name, err = m.localPath(abs); err != nil {
+			return nil, err
+		}
+		resources[abs] = name
+	}
+	return append(refs, ref{uri: field, name: name}), nil
+}
+
+//rewrite sets the URIs of refs to the local files, relative to the local playlist name.
+func rewrite(name string, refs []ref) error {
+	for _, r := range refs {
+		rel, err := relative(name, r.name)
+		if err != nil {
+			return err
+		}
+		*r.uri = rel
+	}
+	return nil
+}
+
+//verifyByteRanges checks that the byte ranges are within the downloaded resources. Ranges without an
+//offset follow the previous range of the same resource.
+func verifyByteRanges(ranges []byteRange) error {
+	next := make(map[string]int64)
+	sizes := make(map[string]int64)
+	for _, r := range ranges {
+		size, ok := sizes[r.name]
+		if !ok {
+			fi, err := os.Stat(r.name)
+			if err != nil {
+				return err
+			}
+			size = fi.Size()
+			sizes[r.name] = size
+		}
+
+		offset := next[r.name]
+		if r.byterange.Offset != nil {
+			offset = *r.byterange.Offset
+		}
+		if offset+r.byterange.Length > size {
+			return fmt.Errorf("byte range %d@%d of %s exceeds its %d bytes", r.byterange.Length, offset, r.url, size)
+		}
+		next[r.name] = offset + r.byterange.Length
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+//Package mirror downloads a whole presentation, its playlists and every resource they reference,
+//to a local directory that can be served or read back as is, such as to archive deliveries or
+//reproduce playback issues offline.
+//
+//Resources are stored under the directory at the path of their URL, prefixed by its host for
+//absolute URLs, and every URI in the re-encoded playlists is rewritten to the relative path of
+//the local copy:
+//
+//	m := mirror.New("/var/archive/delivery")
+//	master, err := m.HLS(ctx, source.HTTP(nil), "https://cdn.example.com/hls/master.m3u8")
+//	//master is /var/archive/delivery/cdn.example.com/hls/master.m3u8
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ingest/manifest/internal/source"
+)
+
+//DefaultConcurrency is the default number of resources downloaded at once.
+const DefaultConcurrency = 4
+
+//Mirror downloads presentations to a local directory.
+type Mirror struct {
+	//Dir is the directory the presentation is written to.
+	Dir string
+	//Concurrency limits how many resources are downloaded at once. Default: DefaultConcurrency.
+	Concurrency int
+	//VerifyByteRanges checks that the byte ranges of segments and maps are within the resources
+	//they were downloaded from, so truncated resources are reported instead of archived.
+	VerifyByteRanges bool
+}
+
+//New returns a Mirror writing to dir.
+func New(dir string) *Mirror {
+	return &Mirror{Dir: dir, Concurrency: DefaultConcurrency}
+}
+
+//download fetches every resource of resources, keyed by URL, to its local path with open.
+//Up to Concurrency resources are downloaded at once, and the first error cancels the rest.
+func (m *Mirror) download(ctx context.Context, open source.Open, resources map[string]string) error {
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	jobs := make(chan string)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uri := range jobs {
+				if err := m.fetch(ctx, open, uri, resources[uri]); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for uri := range resources {
+		select {
+		case jobs <- uri:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+//fetch copies the resource at uri to the local file name.
+func (m *Mirror) fetch(ctx context.Context, open source.Open, uri string, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := open(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", uri, err)
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(name)
+		return fmt.Errorf("failed to fetch %s: %v", uri, err)
+	}
+	return f.Close()
+}
+
+//write writes the encoded playlist r to the local file name.
+func write(name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//localPath returns the path in the mirror directory of the resource at uri: the path of the URL,
+//prefixed by its host if any. A hash of the query is added to the name, so resources differing
+//only by query don't overwrite each other.
+func (m *Mirror) localPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	p := path.Clean("/" + u.Path)
+	if p == "/" {
+		p = "/index"
+	}
+	if u.Host != "" {
+		p = "/" + strings.Replace(u.Host, ":", "_", -1) + p
+	}
+	if u.RawQuery != "" {
+		h := fnv.New32a()
+		h.Write([]byte(u.RawQuery))
+		ext := path.Ext(p)
+		p = fmt.Sprintf("%s-%08x%s", strings.TrimSuffix(p, ext), h.Sum32(), ext)
+	}
+	return filepath.Join(m.Dir, filepath.FromSlash(p)), nil
+}
+
+//relative returns the URI of the local file name, relative to the local playlist it's listed in.
+func relative(playlist string, name string) (string, error) {
+	rel, err := filepath.Rel(filepath.Dir(playlist), name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+//fetchable returns true if uri can be downloaded: a relative reference or a HTTP URL, rather than
+//a key identifier such as skd:// or a data URI.
+func fetchable(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || u.Scheme == "http" || u.Scheme == "https"
+}
@@ -0,0 +1,243 @@
+package mirror_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ingest/manifest"
+	"github.com/ingest/manifest/hls/source"
+	"github.com/ingest/manifest/mirror"
+)
+
+var files = map[string][]byte{
+	"hls/master.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-SESSION-KEY:METHOD=AES-128,URI="keys/session.bin"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aac",NAME="English",DEFAULT=YES,AUTOSELECT=YES,LANGUAGE="en",URI="audio/en.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360,AUDIO="aac"
+video/360p.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=80000,URI="video/iframes.m3u8"
+`),
+	"hls/video/360p.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-KEY:METHOD=AES-128,URI="../keys/session.bin"
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:4@0
+360p.mp4
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:4
+360p.mp4
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key-1",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXTINF:6.0,
+https://cdn.example.com/hls/video/360p-2.mp4?token=abc
+#EXT-X-ENDLIST
+`),
+	"hls/video/iframes.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXT-X-I-FRAMES-ONLY
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:6.0,
+#EXT-X-BYTERANGE:2@0
+360p.mp4
+#EXT-X-ENDLIST
+`),
+	"hls/audio/en.m3u8": []byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:6
+#EXTINF:6.0,
+en0.aac
+#EXT-X-ENDLIST
+`),
+	"hls/keys/session.bin": []byte("0123456789abcdef"),
+	"hls/video/init.mp4":   []byte("init"),
+	"hls/video/360p.mp4":   []byte("seg0seg1"),
+	"hls/audio/en0.aac":    []byte("audio"),
+	"https://cdn.example.com/hls/video/360p-2.mp4?token=abc": []byte("seg2"),
+}
+
+func TestHLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	m := mirror.New(dir)
+	m.VerifyByteRanges = true
+	name, err := m.HLS(ctx, source.Memory(files), "hls/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "hls", "master.m3u8"); name != expected {
+		t.Errorf("Expected master playlist %s, but got %s", expected, name)
+	}
+
+	//the mirror reads back with every URI local
+	local := source.Dir(dir)
+	master, err := local.Master(ctx, "hls/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(master.Variants) != 2 || len(master.Renditions) != 1 || len(master.SessionKeys) != 1 {
+		t.Fatalf("Expected 2 variants, 1 rendition and 1 session key, but got %d, %d and %d", len(master.Variants), len(master.Renditions), len(master.SessionKeys))
+	}
+	if master.SessionKeys[0].URI != "keys/session.bin" {
+		t.Errorf("Expected session key URI keys/session.bin, but got %s", master.SessionKeys[0].URI)
+	}
+
+	var contents []string
+	read := func(uri string) {
+		body, err := local.Resource(ctx, uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(body)
+		body.Close()
+		contents = append(contents, string(b))
+	}
+	for _, v := range master.Variants {
+		media, err := local.Media(ctx, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range media.Segments {
+			if strings.Contains(s.URI, "://") {
+				t.Errorf("Expected relative segment URI, but got %s", s.URI)
+			}
+			uri, _ := s.AbsoluteURL()
+			read(uri)
+			for _, k := range s.Keys {
+				if k.Method == "AES-128" {
+					uri, _ := k.AbsoluteURL()
+					read(uri)
+				} else if k.URI != "skd://key-1" {
+					t.Errorf("Expected skd:// key URI to be kept, but got %s", k.URI)
+				}
+			}
+		}
+		uri, _ := media.Segments[0].Map.AbsoluteURL()
+		read(uri)
+	}
+	rendition, err := ioutil.ReadFile(filepath.Join(dir, "hls", "audio", "en.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rendition), "en0.aac") {
+		t.Errorf("Expected rendition playlist to list en0.aac, but got %s", rendition)
+	}
+	read("hls/audio/en0.aac")
+
+	expected := []string{
+		"seg0seg1", "0123456789abcdef", "seg0seg1", "0123456789abcdef", "seg2", "0123456789abcdef", "init",
+		"seg0seg1", "init",
+		"audio",
+	}
+	if strings.Join(contents, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected mirrored contents %v, but got %v", expected, contents)
+	}
+}
+
+func TestHLSVerifyByteRanges(t *testing.T) {
+	truncated := make(map[string][]byte)
+	for uri, b := range files {
+		truncated[uri] = b
+	}
+	truncated["hls/video/360p.mp4"] = []byte("seg0se")
+
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := mirror.New(dir)
+	if _, err := m.HLS(context.Background(), source.Memory(truncated), "hls/master.m3u8"); err != nil {
+		t.Errorf("Expected byte ranges to be ignored, but got %v", err)
+	}
+	m.VerifyByteRanges = true
+	_, err = m.HLS(context.Background(), source.Memory(truncated), "hls/master.m3u8")
+	if err == nil || !strings.Contains(err.Error(), "4@4") {
+		t.Errorf("Expected error for byte range 4@4, but got %v", err)
+	}
+}
+
+func TestHLSGap(t *testing.T) {
+	//the gap segment isn't in the source, so fetching it would fail the mirror
+	gap := map[string][]byte{
+		"master.m3u8": []byte("#EXTM3U\n#EXT-X-VERSION:8\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nindex.m3u8\n"),
+		"index.m3u8":  []byte("#EXTM3U\n#EXT-X-VERSION:8\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-GAP\n#EXTINF:6.0,\nseg1.ts\n#EXT-X-ENDLIST\n"),
+		"seg0.ts":     []byte("segment"),
+	}
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := mirror.New(dir).HLS(context.Background(), source.Memory(gap), "master.m3u8"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "seg1.ts")); !os.IsNotExist(err) {
+		t.Errorf("Expected gap segment not to be mirrored, but got %v", err)
+	}
+}
+
+func TestHLSHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hls/master.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n/media/index.m3u8\n"))
+		case "/media/index.m3u8":
+			w.Write([]byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:6\n#EXTINF:6.0,\nseg0.ts\n#EXT-X-ENDLIST\n"))
+		case "/media/seg0.ts":
+			w.Write([]byte("segment"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	name, err := mirror.New(dir).HLS(ctx, source.HTTP(nil), server.URL+"/hls/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.Replace(strings.TrimPrefix(server.URL, "http://"), ":", "_", -1)
+	if expected := filepath.Join(dir, host, "hls", "master.m3u8"); name != expected {
+		t.Errorf("Expected master playlist %s, but got %s", expected, name)
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\n../media/index.m3u8\n") {
+		t.Errorf("Expected variant URI ../media/index.m3u8, but got %s", b)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, host, "media", "seg0.ts"))
+	if err != nil || string(b) != "segment" {
+		t.Errorf("Expected segment to be mirrored, but got %q (%v)", b, err)
+	}
+
+	//missing resources fail the mirror
+	_, err = mirror.New(dir).HLS(ctx, source.HTTP(nil), server.URL+"/hls/missing.m3u8")
+	if _, ok := err.(*manifest.StatusError); !ok {
+		t.Errorf("Expected StatusError, but got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//Rewriter returns the URI to list instead of uri, a URI of the given kind found in a manifest, such as
+//hls.KindSegment in a HLS playlist or dash.KindBaseURL in a MPD. It returns uri to leave it unchanged.
+//URIs are passed as listed, so they may be relative.
+type Rewriter func(kind string, uri string) string
+
+//Chain returns a Rewriter applying every rewriter in order.
+func Chain(rewriters ...Rewriter) Rewriter {
+	return func(kind string, uri string) string {
+		for _, r := range rewriters {
+			uri = r(kind, uri)
+		}
+		return uri
+	}
+}
+
+//Kinds returns a Rewriter applying r to URIs of the given kinds only.
+func Kinds(r Rewriter, kinds ...string) Rewriter {
+	return func(kind string, uri string) string {
+		for _, k := range kinds {
+			if k == kind {
+				return r(kind, uri)
+			}
+		}
+		return uri
+	}
+}
+
+//Absolute returns a Rewriter resolving relative URIs against base.
+func Absolute(base string) Rewriter {
+	b, err := url.Parse(base)
+	return func(kind string, uri string) string {
+		if err != nil {
+			return uri
+		}
+		ref, err := url.Parse(uri)
+		if err != nil {
+			return uri
+		}
+		return b.ResolveReference(ref).String()
+	}
+}
+
+//Relative returns a Rewriter making URIs of the same scheme and host as base relative to base,
+//such as to serve a manifest from another location along with its resources.
+//Relative URIs and URIs of other hosts are unchanged.
+func Relative(base string) Rewriter {
+	b, err := url.Parse(base)
+	return func(kind string, uri string) string {
+		if err != nil {
+			return uri
+		}
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || !strings.EqualFold(u.Scheme, b.Scheme) || !strings.EqualFold(u.Host, b.Host) || u.Opaque != "" {
+			return uri
+		}
+		rel := relativePath(b.EscapedPath(), u.EscapedPath())
+		if i := strings.IndexAny(uri, "?#"); i != -1 {
+			rel += uri[i:]
+		}
+		return rel
+	}
+}
+
+//relativePath returns the path to, relative to the directory of the path from.
+func relativePath(from string, to string) string {
+	if from == "" {
+		from = "/"
+	}
+	if to == "" {
+		to = "/"
+	}
+	dir := strings.Split(from[:strings.LastIndex(from, "/")+1], "/")
+	target := strings.Split(to, "/")
+	common := 0
+	for common < len(dir)-1 && common < len(target)-1 && dir[common] == target[common] {
+		common++
+	}
+
+	rel := strings.Repeat("../", len(dir)-1-common) + strings.Join(target[common:], "/")
+	if rel == "" || strings.HasPrefix(rel, "/") || strings.Contains(strings.SplitN(rel, "/", 2)[0], ":") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+//Host returns a Rewriter replacing the host of absolute URIs on the host from, such as to move
+//resources to another CDN. to may include a port.
+func Host(from string, to string) Rewriter {
+	return func(kind string, uri string) string {
+		u, err := url.Parse(uri)
+		if err != nil || u.Host == "" || !strings.EqualFold(u.Host, from) {
+			return uri
+		}
+		u.Host = to
+		return u.String()
+	}
+}
+
+//AkamaiToken returns a Rewriter adding an Akamai-style token authorization query parameter of the
+//given name, such as "hdnts", to HTTP URIs. The token allows the acl path pattern, such as "/live/*",
+//until expires, and is signed with HMAC-SHA256 with key:
+//
+//	exp=<expires>~acl=<acl>~hmac=<hex signature>
+//
+//As clients expand DASH templates, the token applies to a path pattern rather than each URI.
+func AkamaiToken(name string, key []byte, acl string, expires time.Time) Rewriter {
+	token := fmt.Sprintf("exp=%d~acl=%s", expires.Unix(), acl)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(token))
+	token += "~hmac=" + hex.EncodeToString(mac.Sum(nil))
+
+	return func(kind string, uri string) string {
+		if !signable(uri) {
+			return uri
+		}
+		return addQuery(uri, name+"="+token)
+	}
+}
+
+//CloudFrontToken returns a Rewriter adding CloudFront-style signed URL query parameters to HTTP URIs:
+//Policy, Signature and Key-Pair-Id. The policy allows the resource URL pattern, such as
+//"https://cdn.example.com/live/*", until expires.
+//CloudFront signs policies with RSA key pairs, while the policy is signed with HMAC-SHA256 with key here,
+//for origins or test servers verifying tokens with a shared key.
+func CloudFrontToken(keyPairID string, key []byte, resource string, expires time.Time) Rewriter {
+	//the policy only holds a string and a number, so it always marshals
+	policy, _ := json.Marshal(cloudFrontPolicy{Statement: []cloudFrontStatement{{
+		Resource:  resource,
+		Condition: cloudFrontCondition{DateLessThan: cloudFrontEpochTime{EpochTime: expires.Unix()}},
+	}}})
+	mac := hmac.New(sha256.New, key)
+	mac.Write(policy)
+	query := "Policy=" + cloudFrontBase64(policy) +
+		"&Signature=" + cloudFrontBase64(mac.Sum(nil)) +
+		"&Key-Pair-Id=" + url.QueryEscape(keyPairID)
+
+	return func(kind string, uri string) string {
+		if !signable(uri) {
+			return uri
+		}
+		return addQuery(uri, query)
+	}
+}
+
+//cloudFrontPolicy is a CloudFront custom policy, allowing a resource URL pattern until an epoch time.
+type cloudFrontPolicy struct {
+	Statement []cloudFrontStatement `json:"Statement"`
+}
+
+type cloudFrontStatement struct {
+	Resource  string              `json:"Resource"`
+	Condition cloudFrontCondition `json:"Condition"`
+}
+
+type cloudFrontCondition struct {
+	DateLessThan cloudFrontEpochTime `json:"DateLessThan"`
+}
+
+type cloudFrontEpochTime struct {
+	EpochTime int64 `json:"AWS:EpochTime"`
+}
+
+//cloudFrontBase64 encodes b in the URL safe base64 variant of CloudFront.
+func cloudFrontBase64(b []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(b))
+}
+
+//signable returns true if uri is a relative reference or a HTTP URL, rather than a key identifier
+//such as skd:// or a data URI, which can't have query parameters.
+func signable(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "" || scheme == "http" || scheme == "https"
+}
+
+//addQuery appends the query parameters to uri, before its fragment. The URI isn't re-encoded,
+//so templates such as $Number$ are kept as is.
+func addQuery(uri string, query string) string {
+	fragment := ""
+	if i := strings.Index(uri, "#"); i != -1 {
+		uri, fragment = uri[:i], uri[i:]
+	}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query + fragment
+	}
+	return uri + "?" + query + fragment
+}
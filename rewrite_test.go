@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAbsolute(t *testing.T) {
+	r := Absolute("https://example.com/hls/master.m3u8")
+	tests := map[string]string{
+		"video/index.m3u8":             "https://example.com/hls/video/index.m3u8",
+		"../key.bin":                   "https://example.com/key.bin",
+		"/media/1.ts?a=1":              "https://example.com/media/1.ts?a=1",
+		"https://cdn.example.com/1.ts": "https://cdn.example.com/1.ts",
+		"skd://asset":                  "skd://asset",
+	}
+	for uri, expect := range tests {
+		if got := r("Segment", uri); got != expect {
+			t.Errorf("Expected %s to resolve to %s, but got %s", uri, expect, got)
+		}
+	}
+}
+
+func TestRelative(t *testing.T) {
+	r := Relative("https://example.com/hls/master.m3u8")
+	tests := map[string]string{
+		"https://example.com/hls/video/index.m3u8": "video/index.m3u8",
+		"https://example.com/hls/1.ts?a=1#t=2":     "1.ts?a=1#t=2",
+		"https://example.com/keys/key.bin":         "../keys/key.bin",
+		"https://example.com/hls/":                 "./",
+		"https://example.com/hls/a:b.ts":           "./a:b.ts",
+		"https://cdn.example.com/hls/1.ts":         "https://cdn.example.com/hls/1.ts",
+		"http://example.com/hls/1.ts":              "http://example.com/hls/1.ts",
+		"video/index.m3u8":                         "video/index.m3u8",
+	}
+	for uri, expect := range tests {
+		if got := r("Segment", uri); got != expect {
+			t.Errorf("Expected %s relative to the master playlist to be %s, but got %s", uri, expect, got)
+		}
+	}
+	if got := Relative("https://example.com")("Segment", "https://example.com/1.ts"); got != "1.ts" {
+		t.Errorf("Expected 1.ts relative to the host, but got %s", got)
+	}
+}
+
+func TestHost(t *testing.T) {
+	r := Host("origin.example.com", "cdn.example.com:8443")
+	tests := map[string]string{
+		"https://origin.example.com/dash/$Number$.m4s": "https://cdn.example.com:8443/dash/$Number$.m4s",
+		"https://other.example.com/1.ts":               "https://other.example.com/1.ts",
+		"origin.example.com/1.ts":                      "origin.example.com/1.ts",
+	}
+	for uri, expect := range tests {
+		if got := r("Segment", uri); got != expect {
+			t.Errorf("Expected %s to be rewritten to %s, but got %s", uri, expect, got)
+		}
+	}
+}
+
+func TestAkamaiToken(t *testing.T) {
+	key := []byte("secret")
+	r := AkamaiToken("hdnts", key, "/live/*", time.Unix(1600000000, 0))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("exp=1600000000~acl=/live/*"))
+	token := "hdnts=exp=1600000000~acl=/live/*~hmac=" + hex.EncodeToString(mac.Sum(nil))
+	tests := map[string]string{
+		"1.ts":                "1.ts?" + token,
+		"1.ts?a=1#t=2":        "1.ts?a=1&" + token + "#t=2",
+		"$Number$.m4s":        "$Number$.m4s?" + token,
+		"skd://asset":         "skd://asset",
+		"data:text/plain,key": "data:text/plain,key",
+	}
+	for uri, expect := range tests {
+		if got := r("Segment", uri); got != expect {
+			t.Errorf("Expected %s to be signed as %s, but got %s", uri, expect, got)
+		}
+	}
+}
+
+func TestCloudFrontToken(t *testing.T) {
+	key := []byte("secret")
+	got := CloudFrontToken("APKA123", key, "https://cdn.example.com/live/*", time.Unix(1600000000, 0))("Segment", "https://cdn.example.com/live/1.ts")
+
+	policy := `{"Statement":[{"Resource":"https://cdn.example.com/live/*","Condition":{"DateLessThan":{"AWS:EpochTime":1600000000}}}]}`
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(policy))
+	replacer := strings.NewReplacer("+", "-", "=", "_", "/", "~")
+	expect := "https://cdn.example.com/live/1.ts?Policy=" + replacer.Replace(base64.StdEncoding.EncodeToString([]byte(policy))) +
+		"&Signature=" + replacer.Replace(base64.StdEncoding.EncodeToString(mac.Sum(nil))) + "&Key-Pair-Id=APKA123"
+	if got != expect {
+		t.Errorf("Expected %s, but got %s", expect, got)
+	}
+	//the resource is escaped in the JSON policy
+	resource := `https://cdn.example.com/live/"a"\*`
+	got = CloudFrontToken("APKA123", key, resource, time.Unix(1600000000, 0))("Segment", "https://cdn.example.com/live/1.ts")
+	encoded := got[strings.Index(got, "Policy=")+len("Policy=") : strings.Index(got, "&Signature")]
+	decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Statement []struct {
+			Resource string
+		}
+	}
+	if err := json.Unmarshal(decoded, &parsed); err != nil || len(parsed.Statement) != 1 || parsed.Statement[0].Resource != resource {
+		t.Errorf("Expected a policy for %s, but got %s", resource, decoded)
+	}
+}
+
+func TestChainKinds(t *testing.T) {
+	r := Chain(Kinds(Host("a.example.com", "b.example.com"), "Segment"), Absolute("https://a.example.com/hls/"))
+	if got := r("Segment", "https://a.example.com/1.ts"); got != "https://b.example.com/1.ts" {
+		t.Errorf("Expected segment host to be rewritten, but got %s", got)
+	}
+	if got := r("Key", "key.bin"); got != "https://a.example.com/hls/key.bin" {
+		t.Errorf("Expected key to be made absolute only, but got %s", got)
+	}
+}
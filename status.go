@@ -0,0 +1,24 @@
+package manifest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+//StatusError is returned by the HTTP sources of the hls and dash packages when the server doesn't respond
+//with a 2xx status code, rather than handing back the body of an error page.
+type StatusError struct {
+	URI        string
+	StatusCode int
+	Status     string //e.g. "404 Not Found"
+	Header     http.Header
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URI, e.Status)
+}
+
+//Temporary returns true for status codes worth retrying: 5xx server errors and 429 Too Many Requests.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}